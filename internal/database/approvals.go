@@ -0,0 +1,170 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// UpdateStatusAwaitingApproval is the status of a run held until enough
+// approvers approve it.
+const UpdateStatusAwaitingApproval = "awaiting_approval"
+
+// ErrAlreadyDecided is returned when an approver decides on a run twice.
+var ErrAlreadyDecided = errors.New("approver already decided on this run")
+
+func (s *service) ListPipelineApprovers(pipeline_id int64) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT user_id FROM pipeline_approvers WHERE pipeline_id = $1 ORDER BY user_id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in pipeline approvers query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	approvers := make([]int64, 0)
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			slog.Error("error scanning pipeline approvers rows", "error", err)
+			return nil, err
+		}
+		approvers = append(approvers, id)
+	}
+
+	return approvers, rows.Err()
+}
+
+// SetPipelineApprovers replaces the users that can approve the runs of a
+// pipeline.
+func (s *service) SetPipelineApprovers(pipeline_id int64, user_ids []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pipeline_approvers WHERE pipeline_id = $1`, pipeline_id); err != nil {
+		slog.Error("error clearing pipeline approvers", "error", err)
+		return err
+	}
+
+	for _, user_id := range user_ids {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO pipeline_approvers (pipeline_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, pipeline_id, user_id); err != nil {
+			slog.Error("error inserting pipeline approver", "error", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreateRunApproval records a decision, ErrAlreadyDecided when the user
+// already decided on the run.
+func (s *service) CreateRunApproval(approval *models.RunApproval) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO run_approvals (update_id, user_id, decision, comment) VALUES ($1, $2, $3, $4) RETURNING id`,
+		approval.UpdateID, approval.UserID, approval.Decision, approval.Comment).Scan(&approval.ID)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrAlreadyDecided
+	}
+
+	if err != nil {
+		slog.Error("error inserting run approval", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ListRunApprovals(update_id int64) ([]models.RunApproval, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM run_approvals WHERE update_id = $1 ORDER BY id`, update_id)
+
+	if err != nil {
+		slog.Error("error in run approvals query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	approvals, err := ScanRows(rows, models.ScanRunApproval)
+
+	if err != nil {
+		slog.Error("error scanning run approvals rows", "error", err)
+		return nil, err
+	}
+
+	return approvals, nil
+}
+
+func (s *service) ListExpiredApprovalUpdates() ([]Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE status = $1 AND approval_deadline < CURRENT_TIMESTAMP ORDER BY id`, UpdateStatusAwaitingApproval)
+
+	if err != nil {
+		slog.Error("error in expired approval updates query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	updates, err := ScanRows(rows, scanUpdate)
+
+	if err != nil {
+		slog.Error("error scanning updates rows", "error", err)
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// ReleaseApprovalUpdate moves an update out of awaiting_approval, reporting
+// false when it was no longer awaiting so a run is never started twice.
+func (s *service) ReleaseApprovalUpdate(id int64, status string, message string) (bool, error) {
+	return s.transitionUpdate(id, UpdateStatusAwaitingApproval, status, message)
+}
+
+// AwaitApprovalUpdate holds an update in the from status for approval until
+// deadline, reporting false when it was no longer in that status.
+func (s *service) AwaitApprovalUpdate(id int64, from string, message string, deadline time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE updates SET status = $1, message = $2, approval_deadline = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4 AND status = $5`, UpdateStatusAwaitingApproval, message, deadline, id, from)
+
+	if err != nil {
+		slog.Error("error holding update for approval", "from", from, "error", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
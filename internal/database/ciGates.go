@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// UpdateStatusWaitingCI is the status of an update that is held until the CI
+// run for its commit completes.
+const UpdateStatusWaitingCI = "waiting_ci"
+
+func (s *service) ListWaitingCIUpdates(repository string, sha string) ([]Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE status = $1 AND repository = $2 AND sha = $3 ORDER BY id`, UpdateStatusWaitingCI, repository, sha)
+
+	if err != nil {
+		slog.Error("error in waiting ci updates query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	updates, err := ScanRows(rows, scanUpdate)
+
+	if err != nil {
+		slog.Error("error scanning updates rows", "error", err)
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+func (s *service) ListExpiredCIUpdates() ([]Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE status = $1 AND ci_deadline < CURRENT_TIMESTAMP ORDER BY id`, UpdateStatusWaitingCI)
+
+	if err != nil {
+		slog.Error("error in expired ci updates query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	updates, err := ScanRows(rows, scanUpdate)
+
+	if err != nil {
+		slog.Error("error scanning updates rows", "error", err)
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// ReleaseCIUpdate moves an update out of waiting_ci. It reports false when
+// the update was no longer waiting, so a CI result or timeout handled twice
+// never enqueues the same update twice.
+func (s *service) ReleaseCIUpdate(id int64, status string, message string) (bool, error) {
+	return s.transitionUpdate(id, UpdateStatusWaitingCI, status, message)
+}
+
+// transitionUpdate sets the status of an update only when it still has the
+// from status, reporting whether it did.
+func (s *service) transitionUpdate(id int64, from string, status string, message string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE updates SET status = $1, message = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND status = $4`, status, message, id, from)
+
+	if err != nil {
+		slog.Error("error in update status transition", "from", from, "status", status, "error", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
@@ -18,10 +18,14 @@ import (
 type Service interface {
 	Health() map[string]string
 	CreateUpdate(pusher_name string, branch string, status string, message string) (int64, error)
+	CreatePipelineUpdate(update *Update) (int64, error)
 	UpdateStatusAndMessage(id int64, status string, message string) error
 	GetUpdates(limit int, offset int) ([]Update, error)
-	CreateServer(host string, password string, script string, pipeline_id int64, label string) (int64, error)
+	CreateServer(host string, password string, script string, pipeline_id int64, label string, stage_id int64, canary bool, rollback_script string) (int64, error)
 	UpdateServer(opts *models.UpdateServer) error
+	SetServerCanary(server_id int64, canary bool) error
+	SetServerTimeout(server_id int64, seconds int) error
+	SetServerRetry(server_id int64, maxAttempts, backoffSeconds int, on string) error
 	GetServer(id int64) (*models.UpdateServer, error)
 	DeleteServer(id int64) error
 	ListServers(pipeline_id int64) ([]models.UpdateServer, error)
@@ -30,6 +34,7 @@ type Service interface {
 	DeletePipeline(id int64, user_id int64) error
 	ListPipelines(user_id int64) ([]models.Pipeline, error)
 	GetUserPipelineById(pipeline_id int64, user_id int64) (models.Pipeline, error)
+	GetPipeline(pipeline_id int64) (models.Pipeline, error)
 	CreateUser(name string, email string, password string) (int64, error)
 	UpdateUser(opts *models.User) error
 	DeleteUser(id int64) error
@@ -42,6 +47,88 @@ type Service interface {
 	GetUserNotificationConfig(id int64, userId int64) (models.NotificationConfig, error)
 	GetUserNotificationByType(userId int64, notificationType string) ([]models.NotificationConfig, error)
 	UpdateServersPasswords() error
+	CreateWebhookTrigger(trigger *models.WebhookTrigger) (int64, error)
+	UpdateWebhookTrigger(opts *models.UpdateWebhookTrigger) error
+	DeleteWebhookTrigger(id int64) error
+	GetWebhookTrigger(id int64) (models.WebhookTrigger, error)
+	ListWebhookTriggers(pipeline_id int64) ([]models.WebhookTrigger, error)
+	ListActiveWebhookTriggers(repository string) ([]models.WebhookTrigger, error)
+	CreateWebhookDelivery(delivery *models.WebhookDelivery) (int64, error)
+	UpdateWebhookDeliveryDecision(id int64, decision string, updateIds []int64) error
+	GetWebhookDelivery(id int64) (models.WebhookDelivery, error)
+	ReleaseWebhookDelivery(id int64) error
+	ListWebhookDeliveries(limit int, offset int) ([]models.WebhookDelivery, error)
+	ListUserWebhookDeliveries(user_id int64, limit int, offset int) ([]models.WebhookDelivery, error)
+	ListWaitingCIUpdates(repository string, sha string) ([]Update, error)
+	ListExpiredCIUpdates() ([]Update, error)
+	ReleaseCIUpdate(id int64, status string, message string) (bool, error)
+	GetPipelineWebhook(pipeline_id int64) (models.PipelineWebhook, error)
+	GetPipelineWebhookByToken(token string) (models.PipelineWebhook, error)
+	SavePipelineWebhook(webhook *models.PipelineWebhook) error
+	CreateStage(stage *models.Stage) (int64, error)
+	UpdateStage(opts *models.UpdateStage) error
+	DeleteStage(id int64) error
+	GetStage(id int64) (models.Stage, error)
+	ListStages(pipeline_id int64) ([]models.Stage, error)
+	SetServerStage(server_id int64, stage_id int64) error
+	CreateServerResult(result *models.ServerResult) error
+	ListServerResults(update_id int64) ([]models.ServerResult, error)
+	ListServerAttempts(server_id int64, limit int) ([]models.ServerResult, error)
+	GetUpdate(id int64) (Update, error)
+	GetPipelineGithub(pipeline_id int64) (models.PipelineGithub, error)
+	SavePipelineGithub(integration *models.PipelineGithub) error
+	DeletePipelineGithub(pipeline_id int64) error
+	CreateHook(hook *models.Hook) (int64, error)
+	UpdateHook(hook *models.Hook) error
+	DeleteHook(id int64) error
+	GetHook(id int64) (models.Hook, error)
+	ListHooks(pipeline_id int64) ([]models.Hook, error)
+	CreateHookResult(result *models.HookResult) error
+	ListHookResults(update_id int64) ([]models.HookResult, error)
+	ListVariables(pipeline_id int64) ([]models.Variable, error)
+	SetVariables(pipeline_id int64, server_id int64, variables map[string]string) error
+	CreateScript(script *models.Script) (int64, error)
+	UpdateScript(opts *models.UpdateScript) error
+	DeleteScript(id int64) error
+	GetScript(id int64) (models.Script, error)
+	GetScripts(ids []int64) (map[int64]models.Script, error)
+	ListScripts(user_id int64) ([]models.Script, error)
+	ListScriptVersions(script_id int64) ([]models.ScriptVersion, error)
+	ListScriptServers(script_id int64) ([]models.UpdateServer, error)
+	SetServerScript(server_id int64, script_id int64, params map[string]string) error
+	ListPipelineLinks(pipeline_id int64) ([]models.PipelineLink, error)
+	ListUserPipelineLinks(user_id int64) ([]models.PipelineLink, error)
+	SetPipelineLinks(pipeline_id int64, links []models.PipelineLink) error
+	ListChildUpdates(parent_id int64) ([]Update, error)
+	ListPipelineApprovers(pipeline_id int64) ([]int64, error)
+	SetPipelineApprovers(pipeline_id int64, user_ids []int64) error
+	CreateRunApproval(approval *models.RunApproval) error
+	ListRunApprovals(update_id int64) ([]models.RunApproval, error)
+	ListExpiredApprovalUpdates() ([]Update, error)
+	ReleaseApprovalUpdate(id int64, status string, message string) (bool, error)
+	AwaitApprovalUpdate(id int64, from string, message string, deadline time.Time) (bool, error)
+	CreateSchedule(schedule *models.Schedule) (int64, error)
+	UpdateSchedule(schedule *models.Schedule) error
+	DeleteSchedule(id int64) error
+	GetSchedule(id int64) (models.Schedule, error)
+	ListSchedules(pipeline_id int64) ([]models.Schedule, error)
+	ListDueSchedules() ([]models.Schedule, error)
+	ClaimSchedule(id int64, at time.Time, next *time.Time) (bool, error)
+	CreateFreezeWindow(window *models.FreezeWindow) (int64, error)
+	UpdateFreezeWindow(window *models.FreezeWindow) error
+	DeleteFreezeWindow(id int64) error
+	GetFreezeWindow(id int64) (models.FreezeWindow, error)
+	ListUserFreezeWindows(user_id int64, company_id int64) ([]models.FreezeWindow, error)
+	ListPipelineFreezeWindows(pipeline_id int64, company_id int64) ([]models.FreezeWindow, error)
+	CreateFreezeOverride(override *models.FreezeOverride) error
+	ListFreezeOverrides(freeze_window_id int64) ([]models.FreezeOverride, error)
+	ListRunFreezeOverrides(update_id int64) ([]models.FreezeOverride, error)
+	HasPermission(user_id int64, permission string) (bool, error)
+	ListFrozenUpdates() ([]Update, error)
+	ReleaseFrozenUpdate(id int64, status string, message string) (bool, error)
+	StartUpdate(id int64, message string) (bool, error)
+	CancelUpdate(id int64, from string, user_id int64, message string) (bool, error)
+	GetCommitUpdate(pipeline_id int64, repository string, sha string) (Update, error)
 }
 
 type ScanFunc[T any] func(*sql.Rows) (T, error)
@@ -58,6 +145,36 @@ type Update struct {
 	Message    string    `json:"message"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	PipelineID int64     `json:"pipeline_id"`
+	Tag        string    `json:"tag"`
+	Directive  string    `json:"directive"`
+	Repository string    `json:"repository"`
+	SHA        string    `json:"sha"`
+	// CIDeadline is set while the update waits for CI to pass.
+	CIDeadline *time.Time `json:"ci_deadline"`
+	// ParentID is the run whose pipeline chained this one.
+	ParentID int64 `json:"parent_id"`
+	// RequestedBy is the user that started a manual run, ApprovalDeadline is
+	// set while it awaits approval.
+	RequestedBy      int64      `json:"requested_by"`
+	ApprovalDeadline *time.Time `json:"approval_deadline"`
+	// ScheduleID is the schedule that started the run.
+	ScheduleID int64 `json:"schedule_id"`
+	// CancelledBy is the user that cancelled the run.
+	CancelledBy int64 `json:"cancelled_by"`
+}
+
+func scanUpdate(rows *sql.Rows) (Update, error) {
+	var update Update
+	var ciDeadline, approvalDeadline sql.NullTime
+	err := rows.Scan(&update.ID, &update.PusherName, &update.Branch, &update.Status, &update.Message, &update.CreatedAt, &update.UpdatedAt, &update.PipelineID, &update.Tag, &update.Directive, &update.Repository, &update.SHA, &ciDeadline, &update.ParentID, &update.RequestedBy, &approvalDeadline, &update.ScheduleID, &update.CancelledBy)
+	if ciDeadline.Valid {
+		update.CIDeadline = &ciDeadline.Time
+	}
+	if approvalDeadline.Valid {
+		update.ApprovalDeadline = &approvalDeadline.Time
+	}
+	return update, err
 }
 
 var (
@@ -136,6 +253,20 @@ func (s *service) CreateUpdate(pusher_name string, branch string, status string,
 	return id, nil
 }
 
+func (s *service) CreatePipelineUpdate(update *Update) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO updates (pusher_name, branch, status, message, pipeline_id, tag, directive, repository, sha, ci_deadline, parent_id, requested_by, approval_deadline, schedule_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id`, update.PusherName, update.Branch, update.Status, update.Message, update.PipelineID, update.Tag, update.Directive, update.Repository, update.SHA, update.CIDeadline, update.ParentID, update.RequestedBy, update.ApprovalDeadline, update.ScheduleID).Scan(&id)
+	if err != nil {
+		slog.Error("error inserting pipeline update", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
 func (s *service) UpdateStatusAndMessage(id int64, status string, message string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -164,8 +295,7 @@ func (s *service) GetUpdates(limit int, offset int) ([]Update, error) {
 
 	var updates []Update
 	for rows.Next() {
-		var update Update
-		err := rows.Scan(&update.ID, &update.PusherName, &update.Branch, &update.Status, &update.Message, &update.CreatedAt, &update.UpdatedAt)
+		update, err := scanUpdate(rows)
 		if err != nil {
 			fmt.Println("error", err)
 			return nil, err
@@ -177,12 +307,12 @@ func (s *service) GetUpdates(limit int, offset int) ([]Update, error) {
 	return updates, nil
 }
 
-func (s *service) CreateServer(host string, password string, script string, pipeline_id int64, label string) (int64, error) {
+func (s *service) CreateServer(host string, password string, script string, pipeline_id int64, label string, stage_id int64, canary bool, rollback_script string) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	var id int64
-	err := s.db.QueryRowContext(ctx, `INSERT INTO servers (host, password, script, pipeline_id, label) VALUES ($1, $2, $3, $4, $5) RETURNING id`, host, password, script, pipeline_id, label).Scan(&id)
+	err := s.db.QueryRowContext(ctx, `INSERT INTO servers (host, password, script, pipeline_id, label, stage_id, canary, rollback_script) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`, host, password, script, pipeline_id, label, stage_id, canary, rollback_script).Scan(&id)
 	if err != nil {
 		fmt.Println("error in insert", err)
 		return 0, err
@@ -235,6 +365,61 @@ func (s *service) UpdateServer(opts *models.UpdateServer) error {
 		}
 	}
 
+	if opts.RollbackScript != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE servers SET rollback_script = $1 WHERE id = $2`, opts.RollbackScript, opts.ID)
+		if err != nil {
+			slog.Error("error in update rollback script", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetServerCanary marks or unmarks a server as a canary of its pipeline.
+func (s *service) SetServerCanary(server_id int64, canary bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE servers SET canary = $1 WHERE id = $2`, canary, server_id)
+
+	if err != nil {
+		slog.Error("error in update server canary", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetServerTimeout sets how long the script of a server may run, 0 using the
+// timeout of its pipeline.
+func (s *service) SetServerTimeout(server_id int64, seconds int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE servers SET timeout_seconds = $1 WHERE id = $2`, seconds, server_id)
+
+	if err != nil {
+		slog.Error("error in update server timeout", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// SetServerRetry sets the retry policy of a server, 0 attempts using the
+// policy of its pipeline.
+func (s *service) SetServerRetry(server_id int64, maxAttempts, backoffSeconds int, on string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE servers SET retry_max_attempts = $1, retry_backoff_seconds = $2, retry_on = $3 WHERE id = $4`, maxAttempts, backoffSeconds, on, server_id)
+
+	if err != nil {
+		slog.Error("error in update server retry", "error", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -317,6 +502,102 @@ func (s *service) UpdatePipeline(opts *models.UpdatePipeline, user_id int64) err
 		}
 	}
 
+	if opts.Strategy != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET strategy = $1 WHERE id = $2 and user_id = $3`, opts.Strategy, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update strategy", "error", err)
+			return err
+		}
+	}
+
+	if opts.BatchSize != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET batch_size = $1 WHERE id = $2 and user_id = $3`, opts.BatchSize, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update batch size", "error", err)
+			return err
+		}
+	}
+
+	if opts.BakeMinutes != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET bake_minutes = $1 WHERE id = $2 and user_id = $3`, *opts.BakeMinutes, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update bake minutes", "error", err)
+			return err
+		}
+	}
+
+	if opts.TimeoutSeconds != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET timeout_seconds = $1 WHERE id = $2 and user_id = $3`, *opts.TimeoutSeconds, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update timeout", "error", err)
+			return err
+		}
+	}
+
+	if opts.RetryMaxAttempts != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET retry_max_attempts = $1 WHERE id = $2 and user_id = $3`, *opts.RetryMaxAttempts, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update retry max attempts", "error", err)
+			return err
+		}
+	}
+
+	if opts.RetryBackoffSeconds != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET retry_backoff_seconds = $1 WHERE id = $2 and user_id = $3`, *opts.RetryBackoffSeconds, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update retry backoff", "error", err)
+			return err
+		}
+	}
+
+	if opts.RetryOn != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET retry_on = $1 WHERE id = $2 and user_id = $3`, opts.RetryOn, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update retry on", "error", err)
+			return err
+		}
+	}
+
+	if opts.HealthCheck != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET health_check = $1 WHERE id = $2 and user_id = $3`, opts.HealthCheck, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update health check", "error", err)
+			return err
+		}
+	}
+
+	if opts.RollbackScript != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET rollback_script = $1 WHERE id = $2 and user_id = $3`, opts.RollbackScript, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update rollback script", "error", err)
+			return err
+		}
+	}
+
+	if opts.RequiredApprovals != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET required_approvals = $1 WHERE id = $2 and user_id = $3`, *opts.RequiredApprovals, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update required approvals", "error", err)
+			return err
+		}
+	}
+
+	if opts.ApprovalTimeoutMinutes != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET approval_timeout_minutes = $1 WHERE id = $2 and user_id = $3`, *opts.ApprovalTimeoutMinutes, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update approval timeout", "error", err)
+			return err
+		}
+	}
+
+	if opts.MaxFailures != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE pipelines SET max_failures = $1 WHERE id = $2 and user_id = $3`, *opts.MaxFailures, opts.ID, user_id)
+		if err != nil {
+			slog.Error("error in update max failures", "error", err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -371,6 +652,23 @@ func (s *service) GetUserPipelineById(pipeline_id int64, user_id int64) (models.
 
 	return pipeline, nil
 }
+
+func (s *service) GetPipeline(pipeline_id int64) (models.Pipeline, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipelines WHERE id = $1`, pipeline_id)
+
+	pipeline, err := models.ScanRowPipeline(row)
+
+	if err != nil {
+		slog.Error("error in pipeline query", "error", err)
+		return models.Pipeline{}, err
+	}
+
+	return pipeline, nil
+}
+
 func (s *service) CreateUser(name string, email string, password string) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
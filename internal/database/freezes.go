@@ -0,0 +1,217 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// UpdateStatusFrozen is the status of an update held until the deployment
+// freeze blocking it ends.
+const UpdateStatusFrozen = "frozen"
+
+func (s *service) CreateFreezeWindow(window *models.FreezeWindow) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO freeze_windows (scope, scope_id, name, action, starts_at, ends_at, cron, duration_minutes, timezone, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		window.Scope, window.ScopeID, window.Name, window.Action, window.StartsAt, window.EndsAt, window.Cron, window.DurationMinutes, window.Timezone, window.CreatedBy).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting freeze window", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateFreezeWindow saves every field of a freeze window, the handler merges
+// the changes so the window can be validated as a whole.
+func (s *service) UpdateFreezeWindow(window *models.FreezeWindow) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE freeze_windows SET scope = $1, scope_id = $2, name = $3, action = $4, starts_at = $5, ends_at = $6, cron = $7, duration_minutes = $8, timezone = $9, updated_at = CURRENT_TIMESTAMP WHERE id = $10`,
+		window.Scope, window.ScopeID, window.Name, window.Action, window.StartsAt, window.EndsAt, window.Cron, window.DurationMinutes, window.Timezone, window.ID)
+
+	if err != nil {
+		slog.Error("error updating freeze window", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) DeleteFreezeWindow(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM freeze_windows WHERE id = $1`, id); err != nil {
+		slog.Error("error deleting freeze window", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetFreezeWindow(id int64) (models.FreezeWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM freeze_windows WHERE id = $1`, id)
+
+	window, err := models.ScanRowFreezeWindow(row)
+
+	if err != nil {
+		slog.Error("error in freeze window query", "error", err)
+		return models.FreezeWindow{}, err
+	}
+
+	return window, nil
+}
+
+// ListUserFreezeWindows returns the global windows, the windows of the
+// company of a user and the windows of their pipelines.
+func (s *service) ListUserFreezeWindows(user_id int64, company_id int64) ([]models.FreezeWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM freeze_windows WHERE scope = $1 OR (scope = $2 AND scope_id = $3 AND $3 <> 0) OR (scope = $4 AND scope_id IN (SELECT id FROM pipelines WHERE user_id = $5)) ORDER BY id`,
+		models.FreezeGlobal, models.FreezeCompany, company_id, models.FreezePipeline, user_id)
+
+	if err != nil {
+		slog.Error("error in freeze windows query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	windows, err := ScanRows(rows, models.ScanFreezeWindow)
+
+	if err != nil {
+		slog.Error("error scanning freeze windows rows", "error", err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// ListPipelineFreezeWindows returns the windows that apply to a pipeline of a
+// company.
+func (s *service) ListPipelineFreezeWindows(pipeline_id int64, company_id int64) ([]models.FreezeWindow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM freeze_windows WHERE scope = $1 OR (scope = $2 AND scope_id = $3 AND $3 <> 0) OR (scope = $4 AND scope_id = $5) ORDER BY id`,
+		models.FreezeGlobal, models.FreezeCompany, company_id, models.FreezePipeline, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in freeze windows query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	windows, err := ScanRows(rows, models.ScanFreezeWindow)
+
+	if err != nil {
+		slog.Error("error scanning freeze windows rows", "error", err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+func (s *service) CreateFreezeOverride(override *models.FreezeOverride) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO freeze_overrides (update_id, freeze_window_id, user_id, reason) VALUES ($1, $2, $3, $4) RETURNING id`,
+		override.UpdateID, override.FreezeWindowID, override.UserID, override.Reason).Scan(&override.ID)
+
+	if err != nil {
+		slog.Error("error inserting freeze override", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ListFreezeOverrides(freeze_window_id int64) ([]models.FreezeOverride, error) {
+	return s.listFreezeOverrides(`SELECT * FROM freeze_overrides WHERE freeze_window_id = $1 ORDER BY id`, freeze_window_id)
+}
+
+func (s *service) ListRunFreezeOverrides(update_id int64) ([]models.FreezeOverride, error) {
+	return s.listFreezeOverrides(`SELECT * FROM freeze_overrides WHERE update_id = $1 ORDER BY id`, update_id)
+}
+
+func (s *service) listFreezeOverrides(query string, id int64) ([]models.FreezeOverride, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+
+	if err != nil {
+		slog.Error("error in freeze overrides query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	overrides, err := ScanRows(rows, models.ScanFreezeOverride)
+
+	if err != nil {
+		slog.Error("error scanning freeze overrides rows", "error", err)
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// HasPermission reports whether a user was granted a permission. Permissions
+// are granted by inserting into user_permissions.
+func (s *service) HasPermission(user_id int64, permission string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var granted bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM user_permissions WHERE user_id = $1 AND permission = $2)`, user_id, permission).Scan(&granted)
+
+	if err != nil {
+		slog.Error("error in user permission query", "error", err)
+		return false, err
+	}
+
+	return granted, nil
+}
+
+func (s *service) ListFrozenUpdates() ([]Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE status = $1 ORDER BY id`, UpdateStatusFrozen)
+
+	if err != nil {
+		slog.Error("error in frozen updates query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	updates, err := ScanRows(rows, scanUpdate)
+
+	if err != nil {
+		slog.Error("error scanning updates rows", "error", err)
+		return nil, err
+	}
+
+	return updates, nil
+}
+
+// ReleaseFrozenUpdate moves an update out of frozen, reporting false when it
+// was no longer frozen so a held run is never started twice.
+func (s *service) ReleaseFrozenUpdate(id int64, status string, message string) (bool, error) {
+	return s.transitionUpdate(id, UpdateStatusFrozen, status, message)
+}
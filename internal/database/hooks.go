@@ -0,0 +1,131 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) CreateHook(hook *models.Hook) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO pipeline_hooks (pipeline_id, phase, position, kind, server_id, command, method, url, body) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		hook.PipelineID, hook.Phase, hook.Position, hook.Kind, hook.ServerID, hook.Command, hook.Method, hook.URL, hook.Body).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting hook", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateHook saves every field of a hook, the handler merges the changes so
+// the hook can be validated as a whole.
+func (s *service) UpdateHook(hook *models.Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE pipeline_hooks SET phase = $1, position = $2, kind = $3, server_id = $4, command = $5, method = $6, url = $7, body = $8, updated_at = CURRENT_TIMESTAMP WHERE id = $9`,
+		hook.Phase, hook.Position, hook.Kind, hook.ServerID, hook.Command, hook.Method, hook.URL, hook.Body, hook.ID)
+
+	if err != nil {
+		slog.Error("error updating hook", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) DeleteHook(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM pipeline_hooks WHERE id = $1`, id); err != nil {
+		slog.Error("error deleting hook", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetHook(id int64) (models.Hook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipeline_hooks WHERE id = $1`, id)
+
+	hook, err := models.ScanRowHook(row)
+
+	if err != nil {
+		slog.Error("error in hook query", "error", err)
+		return models.Hook{}, err
+	}
+
+	return hook, nil
+}
+
+// ListHooks returns the hooks of a pipeline in the order they run.
+func (s *service) ListHooks(pipeline_id int64) ([]models.Hook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM pipeline_hooks WHERE pipeline_id = $1 ORDER BY phase DESC, position, id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in hooks query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	hooks, err := ScanRows(rows, models.ScanHook)
+
+	if err != nil {
+		slog.Error("error scanning hooks rows", "error", err)
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+func (s *service) CreateHookResult(result *models.HookResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO update_hook_results (update_id, hook_id, phase, kind, status, output, started_at, finished_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`,
+		result.UpdateID, result.HookID, result.Phase, result.Kind, result.Status, result.Output, nullTime(result.StartedAt), nullTime(result.FinishedAt)).Scan(&result.ID)
+
+	if err != nil {
+		slog.Error("error inserting hook result", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ListHookResults(update_id int64) ([]models.HookResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM update_hook_results WHERE update_id = $1 ORDER BY id`, update_id)
+
+	if err != nil {
+		slog.Error("error in hook results query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results, err := ScanRows(rows, models.ScanHookResult)
+
+	if err != nil {
+		slog.Error("error scanning hook results rows", "error", err)
+		return nil, err
+	}
+
+	return results, nil
+}
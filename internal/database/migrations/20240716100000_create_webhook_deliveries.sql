@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id SERIAL PRIMARY KEY,
+    delivery_id VARCHAR(255),
+    provider VARCHAR(255),
+    event_type VARCHAR(255),
+    headers TEXT,
+    body TEXT,
+    signature_valid BOOLEAN DEFAULT FALSE,
+    decision TEXT,
+    update_ids TEXT DEFAULT '',
+    redelivery_of INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, delivery_id)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS webhook_deliveries;
+-- +goose StatementEnd
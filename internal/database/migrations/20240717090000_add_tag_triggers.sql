@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers ADD COLUMN tag_pattern VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN tag VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN tag;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers DROP COLUMN tag_pattern;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers ADD COLUMN include_paths TEXT DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers ADD COLUMN exclude_paths TEXT DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers DROP COLUMN exclude_paths;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers DROP COLUMN include_paths;
+-- +goose StatementEnd
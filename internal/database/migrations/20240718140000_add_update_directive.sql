@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN directive VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN directive;
+-- +goose StatementEnd
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_webhooks (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL UNIQUE,
+    token VARCHAR(255) NOT NULL UNIQUE,
+    secret TEXT NOT NULL,
+    previous_secret TEXT DEFAULT '',
+    previous_expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries ADD COLUMN pipeline_id INTEGER DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP COLUMN pipeline_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_webhooks;
+-- +goose StatementEnd
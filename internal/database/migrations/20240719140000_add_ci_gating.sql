@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers ADD COLUMN wait_for_ci BOOLEAN DEFAULT FALSE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers ADD COLUMN ci_timeout_minutes INTEGER DEFAULT 60;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN repository VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN sha VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN ci_deadline TIMESTAMPTZ;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS updates_waiting_ci_idx ON updates (repository, sha) WHERE status = 'waiting_ci';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS updates_waiting_ci_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN ci_deadline;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN sha;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN repository;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers DROP COLUMN ci_timeout_minutes;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_triggers DROP COLUMN wait_for_ci;
+-- +goose StatementEnd
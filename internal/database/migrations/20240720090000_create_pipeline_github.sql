@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_github (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL UNIQUE,
+    repository VARCHAR(255) NOT NULL,
+    token TEXT NOT NULL,
+    environment VARCHAR(255) DEFAULT 'production',
+    base_url VARCHAR(255) DEFAULT '',
+    active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_github;
+-- +goose StatementEnd
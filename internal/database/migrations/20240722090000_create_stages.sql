@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS stages (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    position INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN stage_id INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS update_server_results (
+    id SERIAL PRIMARY KEY,
+    update_id INTEGER NOT NULL,
+    server_id INTEGER NOT NULL,
+    label VARCHAR(255),
+    stage_id INTEGER DEFAULT 0,
+    stage VARCHAR(255) DEFAULT '',
+    status VARCHAR(255),
+    output TEXT DEFAULT '',
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (update_id) REFERENCES updates (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS update_server_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN stage_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS stages;
+-- +goose StatementEnd
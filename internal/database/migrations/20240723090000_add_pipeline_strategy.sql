@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN strategy VARCHAR(255) DEFAULT 'all';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN batch_size VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN max_failures INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE update_server_results ADD COLUMN batch INTEGER DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE update_server_results DROP COLUMN batch;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN max_failures;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN batch_size;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN strategy;
+-- +goose StatementEnd
@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN bake_minutes INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN health_check TEXT DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN canary BOOLEAN DEFAULT false;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN rollback_script TEXT DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE update_server_results ADD COLUMN phase VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE update_server_results DROP COLUMN phase;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN rollback_script;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN canary;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN health_check;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN bake_minutes;
+-- +goose StatementEnd
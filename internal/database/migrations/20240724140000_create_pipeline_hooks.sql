@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_hooks (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    phase VARCHAR(255) NOT NULL,
+    position INTEGER DEFAULT 0,
+    kind VARCHAR(255) NOT NULL,
+    server_id INTEGER DEFAULT 0,
+    command TEXT DEFAULT '',
+    method VARCHAR(255) DEFAULT '',
+    url TEXT DEFAULT '',
+    body TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS update_hook_results (
+    id SERIAL PRIMARY KEY,
+    update_id INTEGER NOT NULL,
+    hook_id INTEGER NOT NULL,
+    phase VARCHAR(255),
+    kind VARCHAR(255),
+    status VARCHAR(255),
+    output TEXT DEFAULT '',
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (update_id) REFERENCES updates (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS update_hook_results;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_hooks;
+-- +goose StatementEnd
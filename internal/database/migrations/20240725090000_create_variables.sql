@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS variables (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    server_id INTEGER DEFAULT 0,
+    name VARCHAR(255) NOT NULL,
+    value TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipeline_id, server_id, name),
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS variables;
+-- +goose StatementEnd
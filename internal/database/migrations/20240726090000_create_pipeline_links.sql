@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_links (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    downstream_id INTEGER NOT NULL,
+    condition VARCHAR(255) DEFAULT 'success',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipeline_id, downstream_id),
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE,
+    FOREIGN KEY (downstream_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN parent_id INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS updates_parent_id_idx ON updates (parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS updates_parent_id_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN parent_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_links;
+-- +goose StatementEnd
@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN required_approvals INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN approval_timeout_minutes INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_approvers (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (pipeline_id, user_id),
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN requested_by INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN approval_deadline TIMESTAMPTZ;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS run_approvals (
+    id SERIAL PRIMARY KEY,
+    update_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    decision VARCHAR(255) NOT NULL,
+    comment TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (update_id, user_id),
+    FOREIGN KEY (update_id) REFERENCES updates (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS run_approvals;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN approval_deadline;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN requested_by;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_approvers;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN approval_timeout_minutes;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN required_approvals;
+-- +goose StatementEnd
@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS pipeline_schedules (
+    id SERIAL PRIMARY KEY,
+    pipeline_id INTEGER NOT NULL,
+    cron VARCHAR(255) NOT NULL,
+    timezone VARCHAR(255) DEFAULT 'UTC',
+    tag VARCHAR(255) DEFAULT '',
+    branch VARCHAR(255) DEFAULT '',
+    sha VARCHAR(255) DEFAULT '',
+    catch_up VARCHAR(255) DEFAULT 'skip',
+    paused BOOLEAN DEFAULT FALSE,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (pipeline_id) REFERENCES pipelines (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS pipeline_schedules_next_run_at_idx ON pipeline_schedules (next_run_at) WHERE NOT paused;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE updates ADD COLUMN schedule_id INTEGER DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE updates DROP COLUMN schedule_id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX IF EXISTS pipeline_schedules_next_run_at_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS pipeline_schedules;
+-- +goose StatementEnd
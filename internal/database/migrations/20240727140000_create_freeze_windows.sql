@@ -0,0 +1,55 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS freeze_windows (
+    id SERIAL PRIMARY KEY,
+    scope VARCHAR(255) NOT NULL,
+    scope_id INTEGER DEFAULT 0,
+    name VARCHAR(255) DEFAULT '',
+    action VARCHAR(255) DEFAULT 'reject',
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    cron VARCHAR(255) DEFAULT '',
+    duration_minutes INTEGER DEFAULT 0,
+    timezone VARCHAR(255) DEFAULT 'UTC',
+    created_by INTEGER DEFAULT 0,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE INDEX IF NOT EXISTS freeze_windows_scope_idx ON freeze_windows (scope, scope_id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS freeze_overrides (
+    id SERIAL PRIMARY KEY,
+    update_id INTEGER NOT NULL,
+    freeze_window_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    reason TEXT DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (update_id) REFERENCES updates (id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_permissions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    permission VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, permission)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_permissions;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS freeze_overrides;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP INDEX IF EXISTS freeze_windows_scope_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DROP TABLE IF EXISTS freeze_windows;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN timeout_seconds INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN timeout_seconds INTEGER DEFAULT 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN timeout_seconds;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN timeout_seconds;
+-- +goose StatementEnd
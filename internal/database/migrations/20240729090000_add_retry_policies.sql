@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN retry_max_attempts INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN retry_backoff_seconds INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines ADD COLUMN retry_on VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN retry_max_attempts INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN retry_backoff_seconds INTEGER DEFAULT 0;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers ADD COLUMN retry_on VARCHAR(255) DEFAULT '';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE update_server_results ADD COLUMN attempt INTEGER DEFAULT 1;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE update_server_results ADD COLUMN failure_class VARCHAR(20) DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE update_server_results DROP COLUMN failure_class;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE update_server_results DROP COLUMN attempt;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN retry_on;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN retry_backoff_seconds;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE servers DROP COLUMN retry_max_attempts;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN retry_on;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN retry_backoff_seconds;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE pipelines DROP COLUMN retry_max_attempts;
+-- +goose StatementEnd
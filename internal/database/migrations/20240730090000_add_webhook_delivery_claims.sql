@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries ADD COLUMN claimed BOOLEAN DEFAULT FALSE;
+-- +goose StatementEnd
+-- +goose StatementBegin
+UPDATE webhook_deliveries SET claimed = TRUE WHERE signature_valid AND decision <> 'received' AND decision NOT LIKE 'rejected%' AND decision NOT LIKE 'error%';
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP CONSTRAINT IF EXISTS webhook_deliveries_provider_delivery_id_key;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE UNIQUE INDEX webhook_deliveries_claimed_idx ON webhook_deliveries (provider, delivery_id) WHERE claimed;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS webhook_deliveries_claimed_idx;
+-- +goose StatementEnd
+-- +goose StatementBegin
+DELETE FROM webhook_deliveries d USING webhook_deliveries o WHERE d.provider = o.provider AND d.delivery_id = o.delivery_id AND d.id > o.id;
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries ADD CONSTRAINT webhook_deliveries_provider_delivery_id_key UNIQUE (provider, delivery_id);
+-- +goose StatementEnd
+-- +goose StatementBegin
+ALTER TABLE webhook_deliveries DROP COLUMN claimed;
+-- +goose StatementEnd
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Freeze window scopes. ScopeID is the company or pipeline id, 0 for global
+// windows.
+const (
+	FreezeGlobal   = "global"
+	FreezeCompany  = "company"
+	FreezePipeline = "pipeline"
+)
+
+// What happens to a run started during a freeze.
+const (
+	// FreezeReject refuses the run.
+	FreezeReject = "reject"
+	// FreezeHold records the run and starts it once the freeze ends.
+	FreezeHold = "hold"
+)
+
+// Permissions granted to users in user_permissions.
+const (
+	// PermissionFreezeOverride lets a user force a manual run during a freeze.
+	PermissionFreezeOverride = "freeze_override"
+	// PermissionFreezeAdmin lets a user manage global freeze windows.
+	PermissionFreezeAdmin = "freeze_admin"
+)
+
+// FreezeWindow blocks deployments either once, from StartsAt to EndsAt, or
+// every time Cron matches in Timezone, for DurationMinutes.
+type FreezeWindow struct {
+	ID              int64      `json:"id"`
+	Scope           string     `json:"scope"`
+	ScopeID         int64      `json:"scope_id"`
+	Name            string     `json:"name"`
+	Action          string     `json:"action"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	Cron            string     `json:"cron"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Timezone        string     `json:"timezone"`
+	CreatedBy       int64      `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// FreezeOverride audits a run forced during a freeze.
+type FreezeOverride struct {
+	ID             int64     `json:"id"`
+	UpdateID       int64     `json:"update_id"`
+	FreezeWindowID int64     `json:"freeze_window_id"`
+	UserID         int64     `json:"user_id"`
+	Reason         string    `json:"reason"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func scanFreezeWindow(scan func(dest ...any) error) (FreezeWindow, error) {
+	var n FreezeWindow
+	var startsAt, endsAt sql.NullTime
+	err := scan(&n.ID, &n.Scope, &n.ScopeID, &n.Name, &n.Action, &startsAt, &endsAt, &n.Cron, &n.DurationMinutes, &n.Timezone, &n.CreatedBy, &n.CreatedAt, &n.UpdatedAt)
+	if startsAt.Valid {
+		n.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		n.EndsAt = &endsAt.Time
+	}
+	return n, err
+}
+
+func ScanFreezeWindow(rows *sql.Rows) (FreezeWindow, error) {
+	return scanFreezeWindow(rows.Scan)
+}
+
+func ScanRowFreezeWindow(row *sql.Row) (FreezeWindow, error) {
+	return scanFreezeWindow(row.Scan)
+}
+
+func ScanFreezeOverride(rows *sql.Rows) (FreezeOverride, error) {
+	var n FreezeOverride
+	err := rows.Scan(&n.ID, &n.UpdateID, &n.FreezeWindowID, &n.UserID, &n.Reason, &n.CreatedAt)
+	return n, err
+}
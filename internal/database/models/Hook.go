@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Hook phases, pre hooks run before any server of the run and post hooks
+// after all of them.
+const (
+	HookPre  = "pre"
+	HookPost = "post"
+)
+
+// Hook kinds.
+const (
+	// HookCommand runs Command on the server ServerID of the pipeline.
+	HookCommand = "command"
+	// HookHTTP sends Body to URL with Method.
+	HookHTTP = "http"
+)
+
+// Hook is a step that runs once per pipeline run.
+type Hook struct {
+	ID         int64     `json:"id"`
+	PipelineID int64     `json:"pipeline_id"`
+	Phase      string    `json:"phase"`
+	Position   int       `json:"position"`
+	Kind       string    `json:"kind"`
+	ServerID   int64     `json:"server_id"`
+	Command    string    `json:"command"`
+	Method     string    `json:"method"`
+	URL        string    `json:"url"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func ScanHook(rows *sql.Rows) (Hook, error) {
+	var n Hook
+	err := rows.Scan(&n.ID, &n.PipelineID, &n.Phase, &n.Position, &n.Kind, &n.ServerID, &n.Command, &n.Method, &n.URL, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func ScanRowHook(row *sql.Row) (Hook, error) {
+	var n Hook
+	err := row.Scan(&n.ID, &n.PipelineID, &n.Phase, &n.Position, &n.Kind, &n.ServerID, &n.Command, &n.Method, &n.URL, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+// HookResult is the outcome of a hook in a run.
+type HookResult struct {
+	ID         int64     `json:"id"`
+	UpdateID   int64     `json:"update_id"`
+	HookID     int64     `json:"hook_id"`
+	Phase      string    `json:"phase"`
+	Kind       string    `json:"kind"`
+	Status     string    `json:"status"`
+	Output     string    `json:"output"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (r HookResult) Failed() bool {
+	return r.Status != ServerSuccess && r.Status != ServerSkipped
+}
+
+func ScanHookResult(rows *sql.Rows) (HookResult, error) {
+	var n HookResult
+	var startedAt, finishedAt sql.NullTime
+	err := rows.Scan(&n.ID, &n.UpdateID, &n.HookID, &n.Phase, &n.Kind, &n.Status, &n.Output, &startedAt, &finishedAt, &n.CreatedAt)
+	n.StartedAt = startedAt.Time
+	n.FinishedAt = finishedAt.Time
+	return n, err
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PipelineGithub configures reporting a pipeline's runs to GitHub as
+// deployments and commit statuses. Token is stored encrypted.
+type PipelineGithub struct {
+	ID          int64     `json:"id"`
+	PipelineID  int64     `json:"pipeline_id"`
+	Repository  string    `json:"repository"`
+	Token       string    `json:"-"`
+	Environment string    `json:"environment"`
+	BaseURL     string    `json:"base_url"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func ScanRowPipelineGithub(row *sql.Row) (PipelineGithub, error) {
+	var n PipelineGithub
+	err := row.Scan(&n.ID, &n.PipelineID, &n.Repository, &n.Token, &n.Environment, &n.BaseURL, &n.Active, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
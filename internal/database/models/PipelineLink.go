@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Conditions on which a downstream pipeline runs.
+const (
+	LinkOnSuccess = "success"
+	LinkOnFailure = "failure"
+	LinkAlways    = "always"
+)
+
+// PipelineLink enqueues DownstreamID when a run of PipelineID finishes
+// matching Condition.
+type PipelineLink struct {
+	ID           int64     `json:"id"`
+	PipelineID   int64     `json:"pipeline_id"`
+	DownstreamID int64     `json:"downstream_id"`
+	Condition    string    `json:"condition"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func ScanPipelineLink(rows *sql.Rows) (PipelineLink, error) {
+	var n PipelineLink
+	err := rows.Scan(&n.ID, &n.PipelineID, &n.DownstreamID, &n.Condition, &n.CreatedAt)
+	return n, err
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PipelineWebhook is the dedicated webhook endpoint of a pipeline. Secrets are
+// stored encrypted. While a secret is being rotated the previous one is still
+// accepted until PreviousExpiresAt.
+type PipelineWebhook struct {
+	ID                int64      `json:"id"`
+	PipelineID        int64      `json:"pipeline_id"`
+	Token             string     `json:"token"`
+	Secret            string     `json:"-"`
+	PreviousSecret    string     `json:"-"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// PreviousActive reports whether the previous secret is still accepted.
+func (w PipelineWebhook) PreviousActive(now time.Time) bool {
+	return w.PreviousSecret != "" && w.PreviousExpiresAt != nil && now.Before(*w.PreviousExpiresAt)
+}
+
+func ScanRowPipelineWebhook(row *sql.Row) (PipelineWebhook, error) {
+	var n PipelineWebhook
+	var expiresAt sql.NullTime
+	err := row.Scan(&n.ID, &n.PipelineID, &n.Token, &n.Secret, &n.PreviousSecret, &expiresAt, &n.CreatedAt, &n.UpdatedAt)
+	if expiresAt.Valid {
+		n.PreviousExpiresAt = &expiresAt.Time
+	}
+	return n, err
+}
@@ -11,21 +11,58 @@ type Pipeline struct {
 	UserID    int64     `json:"user_id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// Strategy is how the servers of a stage are deployed, see the deploy
+	// package. BatchSize and MaxFailures configure rolling deploys.
+	Strategy    string `json:"strategy"`
+	BatchSize   string `json:"batch_size"`
+	MaxFailures int    `json:"max_failures"`
+	// BakeMinutes and HealthCheck configure canary deploys.
+	BakeMinutes int    `json:"bake_minutes"`
+	HealthCheck string `json:"health_check"`
+	// RollbackScript runs on the servers without one of their own when their
+	// deploy fails.
+	RollbackScript string `json:"rollback_script"`
+	// RequiredApprovals is how many distinct approvers must approve a manual
+	// run before it starts, 0 starting it right away.
+	RequiredApprovals      int `json:"required_approvals"`
+	ApprovalTimeoutMinutes int `json:"approval_timeout_minutes"`
+	// TimeoutSeconds is how long the script of a server without a timeout of
+	// its own may run, 0 using the default.
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// RetryMaxAttempts, RetryBackoffSeconds and RetryOn are the retry policy
+	// of the servers without one of their own, see deploy.RetryPolicy.
+	RetryMaxAttempts    int    `json:"retry_max_attempts"`
+	RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+	RetryOn             string `json:"retry_on"`
 }
 
 type UpdatePipeline struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID             int64  `json:"id"`
+	Name           string `json:"name"`
+	Strategy       string `json:"strategy"`
+	BatchSize      string `json:"batch_size"`
+	MaxFailures    *int   `json:"max_failures"`
+	BakeMinutes    *int   `json:"bake_minutes"`
+	HealthCheck    string `json:"health_check"`
+	RollbackScript string `json:"rollback_script"`
+
+	RequiredApprovals      *int `json:"required_approvals"`
+	ApprovalTimeoutMinutes *int `json:"approval_timeout_minutes"`
+	TimeoutSeconds         *int `json:"timeout_seconds"`
+
+	RetryMaxAttempts    *int   `json:"retry_max_attempts"`
+	RetryBackoffSeconds *int   `json:"retry_backoff_seconds"`
+	RetryOn             string `json:"retry_on"`
 }
 
 func ScanPipeline(rows *sql.Rows) (Pipeline, error) {
 	var n Pipeline
-	err := rows.Scan(&n.ID, &n.Name, &n.CreatedAt, &n.UpdatedAt, &n.UserID)
+	err := rows.Scan(&n.ID, &n.Name, &n.CreatedAt, &n.UpdatedAt, &n.UserID, &n.Strategy, &n.BatchSize, &n.MaxFailures, &n.BakeMinutes, &n.HealthCheck, &n.RollbackScript, &n.RequiredApprovals, &n.ApprovalTimeoutMinutes, &n.TimeoutSeconds, &n.RetryMaxAttempts, &n.RetryBackoffSeconds, &n.RetryOn)
 	return n, err
 }
 
 func ScanRowPipeline(row *sql.Row) (Pipeline, error) {
 	var n Pipeline
-	err := row.Scan(&n.ID, &n.Name, &n.CreatedAt, &n.UpdatedAt, &n.UserID)
+	err := row.Scan(&n.ID, &n.Name, &n.CreatedAt, &n.UpdatedAt, &n.UserID, &n.Strategy, &n.BatchSize, &n.MaxFailures, &n.BakeMinutes, &n.HealthCheck, &n.RollbackScript, &n.RequiredApprovals, &n.ApprovalTimeoutMinutes, &n.TimeoutSeconds, &n.RetryMaxAttempts, &n.RetryBackoffSeconds, &n.RetryOn)
 	return n, err
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Approval decisions.
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// RunApproval is the decision of an approver on a run awaiting approval.
+type RunApproval struct {
+	ID        int64     `json:"id"`
+	UpdateID  int64     `json:"update_id"`
+	UserID    int64     `json:"user_id"`
+	Decision  string    `json:"decision"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ScanRunApproval(rows *sql.Rows) (RunApproval, error) {
+	var n RunApproval
+	err := rows.Scan(&n.ID, &n.UpdateID, &n.UserID, &n.Decision, &n.Comment, &n.CreatedAt)
+	return n, err
+}
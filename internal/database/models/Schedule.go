@@ -0,0 +1,45 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Schedule runs a pipeline whenever Cron matches in Timezone, with the same
+// parameters as a manual run. NextRunAt is nil while the schedule is paused.
+type Schedule struct {
+	ID         int64      `json:"id"`
+	PipelineID int64      `json:"pipeline_id"`
+	Cron       string     `json:"cron"`
+	Timezone   string     `json:"timezone"`
+	Tag        string     `json:"tag"`
+	Branch     string     `json:"branch"`
+	SHA        string     `json:"sha"`
+	CatchUp    string     `json:"catch_up"`
+	Paused     bool       `json:"paused"`
+	NextRunAt  *time.Time `json:"next_run_at"`
+	LastRunAt  *time.Time `json:"last_run_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func scanSchedule(scan func(dest ...any) error) (Schedule, error) {
+	var n Schedule
+	var nextRunAt, lastRunAt sql.NullTime
+	err := scan(&n.ID, &n.PipelineID, &n.Cron, &n.Timezone, &n.Tag, &n.Branch, &n.SHA, &n.CatchUp, &n.Paused, &nextRunAt, &lastRunAt, &n.CreatedAt, &n.UpdatedAt)
+	if nextRunAt.Valid {
+		n.NextRunAt = &nextRunAt.Time
+	}
+	if lastRunAt.Valid {
+		n.LastRunAt = &lastRunAt.Time
+	}
+	return n, err
+}
+
+func ScanSchedule(rows *sql.Rows) (Schedule, error) {
+	return scanSchedule(rows.Scan)
+}
+
+func ScanRowSchedule(row *sql.Row) (Schedule, error) {
+	return scanSchedule(row.Scan)
+}
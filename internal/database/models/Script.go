@@ -0,0 +1,51 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Script is a named script of the library that servers can reference. Every
+// change to its body is kept as a new version.
+type Script struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpdateScript struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// ScriptVersion is the body a script had at a version.
+type ScriptVersion struct {
+	ID        int64     `json:"id"`
+	ScriptID  int64     `json:"script_id"`
+	Version   int       `json:"version"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func ScanScript(rows *sql.Rows) (Script, error) {
+	var n Script
+	err := rows.Scan(&n.ID, &n.UserID, &n.Name, &n.Version, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func ScanRowScript(row *sql.Row) (Script, error) {
+	var n Script
+	err := row.Scan(&n.ID, &n.UserID, &n.Name, &n.Version, &n.Body, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func ScanScriptVersion(rows *sql.Rows) (ScriptVersion, error) {
+	var n ScriptVersion
+	err := rows.Scan(&n.ID, &n.ScriptID, &n.Version, &n.Body, &n.CreatedAt)
+	return n, err
+}
@@ -2,9 +2,12 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 )
 
+// UpdateServer is a server of a pipeline. Its Script is rendered as a
+// text/template with deploy.ScriptData before it runs.
 type UpdateServer struct {
 	ID         int64     `json:"id"`
 	Host       string    `json:"host"`
@@ -15,16 +18,58 @@ type UpdateServer struct {
 	Active     bool      `json:"active"`
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
+	StageID    int64     `json:"stage_id"`
+	// Canary servers are deployed first by the canary strategy.
+	Canary bool `json:"canary"`
+	// RollbackScript restores the server when its deploy fails.
+	RollbackScript string `json:"rollback_script"`
+	// ScriptID is the library script the server runs instead of Script, with
+	// ScriptParams available to it as {{.Params.NAME}}.
+	ScriptID     int64             `json:"script_id"`
+	ScriptParams map[string]string `json:"script_params"`
+	// TimeoutSeconds is how long the script may run before it is stopped, 0
+	// using the timeout of the pipeline.
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// RetryMaxAttempts, RetryBackoffSeconds and RetryOn are the retry policy
+	// of the server, 0 attempts using the policy of the pipeline.
+	RetryMaxAttempts    int    `json:"retry_max_attempts"`
+	RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+	RetryOn             string `json:"retry_on"`
+}
+
+// JoinScriptParams is the text representation stored in servers.script_params.
+func JoinScriptParams(params map[string]string) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	value, _ := json.Marshal(params)
+
+	return string(value)
+}
+
+func parseScriptParams(value string) map[string]string {
+	params := make(map[string]string)
+
+	if value != "" {
+		_ = json.Unmarshal([]byte(value), &params)
+	}
+
+	return params
 }
 
 func ScanUpdateServer(rows *sql.Rows) (UpdateServer, error) {
 	var n UpdateServer
-	err := rows.Scan(&n.ID, &n.Host, &n.Password, &n.Script, &n.PipelineID, &n.Label, &n.Active, &n.CreatedAt, &n.UpdatedAt)
+	var params string
+	err := rows.Scan(&n.ID, &n.Host, &n.Password, &n.Script, &n.PipelineID, &n.Label, &n.Active, &n.CreatedAt, &n.UpdatedAt, &n.StageID, &n.Canary, &n.RollbackScript, &n.ScriptID, &params, &n.TimeoutSeconds, &n.RetryMaxAttempts, &n.RetryBackoffSeconds, &n.RetryOn)
+	n.ScriptParams = parseScriptParams(params)
 	return n, err
 }
 
 func ScanRowUpdateServer(row *sql.Row) (UpdateServer, error) {
 	var n UpdateServer
-	err := row.Scan(&n.ID, &n.Host, &n.Password, &n.Script, &n.PipelineID, &n.Label, &n.Active, &n.CreatedAt, &n.UpdatedAt)
+	var params string
+	err := row.Scan(&n.ID, &n.Host, &n.Password, &n.Script, &n.PipelineID, &n.Label, &n.Active, &n.CreatedAt, &n.UpdatedAt, &n.StageID, &n.Canary, &n.RollbackScript, &n.ScriptID, &params, &n.TimeoutSeconds, &n.RetryMaxAttempts, &n.RetryBackoffSeconds, &n.RetryOn)
+	n.ScriptParams = parseScriptParams(params)
 	return n, err
 }
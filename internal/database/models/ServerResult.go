@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Server result statuses.
+const (
+	ServerSuccess = "success"
+	ServerError   = "error"
+	// ServerSkipped is a server that did not run because an earlier stage failed.
+	ServerSkipped = "skipped"
+	// ServerTimedOut is a server whose script was stopped after its timeout.
+	ServerTimedOut = "timed_out"
+	// ServerCancelled is a server whose script was stopped because the run
+	// was cancelled.
+	ServerCancelled = "cancelled"
+)
+
+// Server result phases. Servers deployed in stages have no phase.
+const (
+	// PhaseCanary is a canary server deployed before the rest of the pipeline.
+	PhaseCanary = "canary"
+	// PhaseRollback is the rollback script of a server whose deploy failed.
+	PhaseRollback = "rollback"
+	// PhaseRetry is an attempt of a server deploy that failed and was retried.
+	PhaseRetry = "retry"
+)
+
+// ServerResult is the outcome of one server in a run.
+type ServerResult struct {
+	ID         int64     `json:"id"`
+	UpdateID   int64     `json:"update_id"`
+	ServerID   int64     `json:"server_id"`
+	Label      string    `json:"label"`
+	StageID    int64     `json:"stage_id"`
+	Stage      string    `json:"stage"`
+	Status     string    `json:"status"`
+	Output     string    `json:"output"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Batch is the 1-based rolling batch of the server within its stage.
+	Batch int    `json:"batch"`
+	Phase string `json:"phase"`
+	// Attempt is the 1-based attempt of the deploy, 0 for skipped servers and
+	// rollbacks. FailureClass is how a failed attempt failed, see
+	// deploy.RetryPolicy.
+	Attempt      int    `json:"attempt"`
+	FailureClass string `json:"failure_class"`
+	// Rollback is the rollback run after the deploy failed, recorded as a
+	// result of its own.
+	Rollback *ServerResult `json:"rollback,omitempty"`
+	// Attempts are the earlier attempts that failed and were retried, each
+	// recorded as a result of its own.
+	Attempts []ServerResult `json:"attempts,omitempty"`
+}
+
+func (r ServerResult) Failed() bool {
+	return r.Status != ServerSuccess && r.Status != ServerSkipped
+}
+
+func ScanServerResult(rows *sql.Rows) (ServerResult, error) {
+	var n ServerResult
+	var startedAt, finishedAt sql.NullTime
+	err := rows.Scan(&n.ID, &n.UpdateID, &n.ServerID, &n.Label, &n.StageID, &n.Stage, &n.Status, &n.Output, &startedAt, &finishedAt, &n.CreatedAt, &n.Batch, &n.Phase, &n.Attempt, &n.FailureClass)
+	n.StartedAt = startedAt.Time
+	n.FinishedAt = finishedAt.Time
+	return n, err
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Stage groups the servers of a pipeline. Stages run one after another by
+// position, the servers of a stage run in parallel.
+type Stage struct {
+	ID         int64     `json:"id"`
+	PipelineID int64     `json:"pipeline_id"`
+	Name       string    `json:"name"`
+	Position   int       `json:"position"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type UpdateStage struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position *int   `json:"position"`
+}
+
+func ScanStage(rows *sql.Rows) (Stage, error) {
+	var n Stage
+	err := rows.Scan(&n.ID, &n.PipelineID, &n.Name, &n.Position, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+func ScanRowStage(row *sql.Row) (Stage, error) {
+	var n Stage
+	err := row.Scan(&n.ID, &n.PipelineID, &n.Name, &n.Position, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Variable is a value available to the scripts of a pipeline as
+// {{.Vars.NAME}}. ServerID 0 is a pipeline variable, otherwise it overrides
+// the pipeline one for that server.
+type Variable struct {
+	ID         int64     `json:"id"`
+	PipelineID int64     `json:"pipeline_id"`
+	ServerID   int64     `json:"server_id"`
+	Name       string    `json:"name"`
+	Value      string    `json:"value"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func ScanVariable(rows *sql.Rows) (Variable, error) {
+	var n Variable
+	err := rows.Scan(&n.ID, &n.PipelineID, &n.ServerID, &n.Name, &n.Value, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
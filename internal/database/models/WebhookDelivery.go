@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PermissionWebhookAdmin lets a user read and redeliver every webhook
+// delivery, including the ones sent to the shared provider endpoints that
+// belong to no pipeline.
+const PermissionWebhookAdmin = "webhook_admin"
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	DeliveryID     string          `json:"delivery_id"`
+	Provider       string          `json:"provider"`
+	EventType      string          `json:"event_type"`
+	Headers        json.RawMessage `json:"headers"`
+	Body           string          `json:"body"`
+	SignatureValid bool            `json:"signature_valid"`
+	Decision       string          `json:"decision"`
+	UpdateIDs      []int64         `json:"update_ids"`
+	RedeliveryOf   int64           `json:"redelivery_of"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	PipelineID     int64           `json:"pipeline_id"`
+	// Claimed deliveries were signed and routed, a later delivery with the
+	// same id is a duplicate.
+	Claimed bool `json:"claimed"`
+}
+
+// JoinUpdateIDs is the text representation stored in webhook_deliveries.update_ids.
+func JoinUpdateIDs(ids []int64) string {
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.FormatInt(id, 10))
+	}
+
+	return strings.Join(values, ",")
+}
+
+func splitUpdateIDs(value string) []int64 {
+	ids := make([]int64, 0)
+	for _, v := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+func ScanWebhookDelivery(rows *sql.Rows) (WebhookDelivery, error) {
+	var n WebhookDelivery
+	var headers, updateIds string
+	err := rows.Scan(&n.ID, &n.DeliveryID, &n.Provider, &n.EventType, &headers, &n.Body, &n.SignatureValid, &n.Decision, &updateIds, &n.RedeliveryOf, &n.CreatedAt, &n.UpdatedAt, &n.PipelineID, &n.Claimed)
+	n.Headers = json.RawMessage(headers)
+	n.UpdateIDs = splitUpdateIDs(updateIds)
+	return n, err
+}
+
+func ScanRowWebhookDelivery(row *sql.Row) (WebhookDelivery, error) {
+	var n WebhookDelivery
+	var headers, updateIds string
+	err := row.Scan(&n.ID, &n.DeliveryID, &n.Provider, &n.EventType, &headers, &n.Body, &n.SignatureValid, &n.Decision, &updateIds, &n.RedeliveryOf, &n.CreatedAt, &n.UpdatedAt, &n.PipelineID, &n.Claimed)
+	n.Headers = json.RawMessage(headers)
+	n.UpdateIDs = splitUpdateIDs(updateIds)
+	return n, err
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type WebhookTrigger struct {
+	ID            int64     `json:"id"`
+	PipelineID    int64     `json:"pipeline_id"`
+	Repository    string    `json:"repository"`
+	BranchPattern string    `json:"branch_pattern"`
+	EventType     string    `json:"event_type"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	TagPattern    string    `json:"tag_pattern"`
+	IncludePaths  []string  `json:"include_paths"`
+	ExcludePaths  []string  `json:"exclude_paths"`
+	// WaitForCI holds the deploy until the CI run of the commit succeeds, for
+	// at most CITimeoutMinutes.
+	WaitForCI        bool `json:"wait_for_ci"`
+	CITimeoutMinutes int  `json:"ci_timeout_minutes"`
+}
+
+type UpdateWebhookTrigger struct {
+	ID               int64    `json:"id"`
+	Repository       string   `json:"repository"`
+	BranchPattern    string   `json:"branch_pattern"`
+	EventType        string   `json:"event_type"`
+	TagPattern       string   `json:"tag_pattern"`
+	Active           *bool    `json:"active"`
+	IncludePaths     []string `json:"include_paths"`
+	ExcludePaths     []string `json:"exclude_paths"`
+	WaitForCI        *bool    `json:"wait_for_ci"`
+	CITimeoutMinutes int      `json:"ci_timeout_minutes"`
+}
+
+// JoinPaths stores a path filter as one glob per line. Splitting an empty
+// value gives an empty filter.
+func JoinPaths(paths []string) string {
+	return strings.Join(paths, "\n")
+}
+
+func splitPaths(value string) []string {
+	paths := make([]string, 0)
+	for _, p := range strings.Split(value, "\n") {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+func ScanWebhookTrigger(rows *sql.Rows) (WebhookTrigger, error) {
+	var n WebhookTrigger
+	var includePaths, excludePaths string
+	err := rows.Scan(&n.ID, &n.PipelineID, &n.Repository, &n.BranchPattern, &n.EventType, &n.Active, &n.CreatedAt, &n.UpdatedAt, &n.TagPattern, &includePaths, &excludePaths, &n.WaitForCI, &n.CITimeoutMinutes)
+	n.IncludePaths = splitPaths(includePaths)
+	n.ExcludePaths = splitPaths(excludePaths)
+	return n, err
+}
+
+func ScanRowWebhookTrigger(row *sql.Row) (WebhookTrigger, error) {
+	var n WebhookTrigger
+	var includePaths, excludePaths string
+	err := row.Scan(&n.ID, &n.PipelineID, &n.Repository, &n.BranchPattern, &n.EventType, &n.Active, &n.CreatedAt, &n.UpdatedAt, &n.TagPattern, &includePaths, &excludePaths, &n.WaitForCI, &n.CITimeoutMinutes)
+	n.IncludePaths = splitPaths(includePaths)
+	n.ExcludePaths = splitPaths(excludePaths)
+	return n, err
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) GetPipelineGithub(pipeline_id int64) (models.PipelineGithub, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipeline_github WHERE pipeline_id = $1`, pipeline_id)
+
+	return models.ScanRowPipelineGithub(row)
+}
+
+// SavePipelineGithub creates or replaces the GitHub settings of a pipeline.
+func (s *service) SavePipelineGithub(integration *models.PipelineGithub) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO pipeline_github (pipeline_id, repository, token, environment, base_url, active) VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (pipeline_id) DO UPDATE SET repository = EXCLUDED.repository, token = EXCLUDED.token, environment = EXCLUDED.environment, base_url = EXCLUDED.base_url, active = EXCLUDED.active, updated_at = CURRENT_TIMESTAMP`,
+		integration.PipelineID, integration.Repository, integration.Token, integration.Environment, integration.BaseURL, integration.Active)
+
+	if err != nil {
+		slog.Error("error saving pipeline github", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) DeletePipelineGithub(pipeline_id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM pipeline_github WHERE pipeline_id = $1`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error deleting pipeline github", "error", err)
+		return err
+	}
+
+	return nil
+}
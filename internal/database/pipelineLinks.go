@@ -0,0 +1,111 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ListPipelineLinks returns the downstream pipelines of a pipeline.
+func (s *service) ListPipelineLinks(pipeline_id int64) ([]models.PipelineLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM pipeline_links WHERE pipeline_id = $1 ORDER BY id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in pipeline links query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	links, err := ScanRows(rows, models.ScanPipelineLink)
+
+	if err != nil {
+		slog.Error("error scanning pipeline links rows", "error", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListUserPipelineLinks returns the links between every pipeline of a user.
+func (s *service) ListUserPipelineLinks(user_id int64) ([]models.PipelineLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT l.* FROM pipeline_links l JOIN pipelines p ON p.id = l.pipeline_id WHERE p.user_id = $1 ORDER BY l.id`, user_id)
+
+	if err != nil {
+		slog.Error("error in pipeline links query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	links, err := ScanRows(rows, models.ScanPipelineLink)
+
+	if err != nil {
+		slog.Error("error scanning pipeline links rows", "error", err)
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// SetPipelineLinks replaces the downstream pipelines of a pipeline.
+func (s *service) SetPipelineLinks(pipeline_id int64, links []models.PipelineLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM pipeline_links WHERE pipeline_id = $1`, pipeline_id); err != nil {
+		slog.Error("error clearing pipeline links", "error", err)
+		return err
+	}
+
+	for _, link := range links {
+		_, err := tx.ExecContext(ctx, `INSERT INTO pipeline_links (pipeline_id, downstream_id, condition) VALUES ($1, $2, $3)`, pipeline_id, link.DownstreamID, link.Condition)
+
+		if err != nil {
+			slog.Error("error inserting pipeline link", "error", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListChildUpdates returns the runs a run enqueued on its downstream
+// pipelines.
+func (s *service) ListChildUpdates(parent_id int64) ([]Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE parent_id = $1 ORDER BY id`, parent_id)
+
+	if err != nil {
+		slog.Error("error in child updates query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	updates, err := ScanRows(rows, scanUpdate)
+
+	if err != nil {
+		slog.Error("error scanning child updates rows", "error", err)
+		return nil, err
+	}
+
+	return updates, nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) GetPipelineWebhook(pipeline_id int64) (models.PipelineWebhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipeline_webhooks WHERE pipeline_id = $1`, pipeline_id)
+
+	return models.ScanRowPipelineWebhook(row)
+}
+
+func (s *service) GetPipelineWebhookByToken(token string) (models.PipelineWebhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipeline_webhooks WHERE token = $1`, token)
+
+	return models.ScanRowPipelineWebhook(row)
+}
+
+// SavePipelineWebhook creates the webhook of a pipeline or replaces its
+// secrets. The token of an existing webhook is kept so its URL does not change.
+func (s *service) SavePipelineWebhook(webhook *models.PipelineWebhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `INSERT INTO pipeline_webhooks (pipeline_id, token, secret, previous_secret, previous_expires_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (pipeline_id) DO UPDATE SET secret = EXCLUDED.secret, previous_secret = EXCLUDED.previous_secret, previous_expires_at = EXCLUDED.previous_expires_at, updated_at = CURRENT_TIMESTAMP`,
+		webhook.PipelineID, webhook.Token, webhook.Secret, webhook.PreviousSecret, webhook.PreviousExpiresAt)
+
+	if err != nil {
+		slog.Error("error saving pipeline webhook", "error", err)
+		return err
+	}
+
+	return nil
+}
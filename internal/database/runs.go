@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// StartUpdate moves a pending update to running, reporting false when it was
+// no longer pending, like a run cancelled while it was queued.
+func (s *service) StartUpdate(id int64, message string) (bool, error) {
+	return s.transitionUpdate(id, "pending", "running", message)
+}
+
+// CancelUpdate marks an update in the from status as cancelled by a user,
+// reporting false when it was no longer in that status.
+func (s *service) CancelUpdate(id int64, from string, user_id int64, message string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE updates SET status = 'cancelled', message = $1, cancelled_by = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3 AND status = $4`, message, user_id, id, from)
+
+	if err != nil {
+		slog.Error("error cancelling update", "from", from, "error", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
+
+// GetCommitUpdate returns the latest update of a pipeline for a commit of a
+// repository that is still due or deployed it: updates that failed, were
+// cancelled or were left out do not count.
+func (s *service) GetCommitUpdate(pipeline_id int64, repository string, sha string) (Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE pipeline_id = $1 AND repository = $2 AND sha = $3 AND status NOT IN ('error', 'cancelled', 'skipped', 'rejected') ORDER BY id DESC LIMIT 1`, pipeline_id, repository, sha)
+
+	if err != nil {
+		slog.Error("error in commit update query", "error", err)
+		return Update{}, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Update{}, err
+		}
+
+		return Update{}, sql.ErrNoRows
+	}
+
+	return scanUpdate(rows)
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) CreateSchedule(schedule *models.Schedule) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO pipeline_schedules (pipeline_id, cron, timezone, tag, branch, sha, catch_up, paused, next_run_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`,
+		schedule.PipelineID, schedule.Cron, schedule.Timezone, schedule.Tag, schedule.Branch, schedule.SHA, schedule.CatchUp, schedule.Paused, schedule.NextRunAt).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting schedule", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// UpdateSchedule saves every field of a schedule, the handler merges the
+// changes and computes the next run.
+func (s *service) UpdateSchedule(schedule *models.Schedule) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE pipeline_schedules SET cron = $1, timezone = $2, tag = $3, branch = $4, sha = $5, catch_up = $6, paused = $7, next_run_at = $8, updated_at = CURRENT_TIMESTAMP WHERE id = $9`,
+		schedule.Cron, schedule.Timezone, schedule.Tag, schedule.Branch, schedule.SHA, schedule.CatchUp, schedule.Paused, schedule.NextRunAt, schedule.ID)
+
+	if err != nil {
+		slog.Error("error updating schedule", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) DeleteSchedule(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM pipeline_schedules WHERE id = $1`, id); err != nil {
+		slog.Error("error deleting schedule", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetSchedule(id int64) (models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM pipeline_schedules WHERE id = $1`, id)
+
+	schedule, err := models.ScanRowSchedule(row)
+
+	if err != nil {
+		slog.Error("error in schedule query", "error", err)
+		return models.Schedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func (s *service) ListSchedules(pipeline_id int64) ([]models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM pipeline_schedules WHERE pipeline_id = $1 ORDER BY id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in schedules query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schedules, err := ScanRows(rows, models.ScanSchedule)
+
+	if err != nil {
+		slog.Error("error scanning schedules rows", "error", err)
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// ListDueSchedules returns the active schedules whose next run is due.
+func (s *service) ListDueSchedules() ([]models.Schedule, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM pipeline_schedules WHERE NOT paused AND next_run_at <= CURRENT_TIMESTAMP ORDER BY next_run_at, id`)
+
+	if err != nil {
+		slog.Error("error in due schedules query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	schedules, err := ScanRows(rows, models.ScanSchedule)
+
+	if err != nil {
+		slog.Error("error scanning schedules rows", "error", err)
+		return nil, err
+	}
+
+	return schedules, nil
+}
+
+// ClaimSchedule moves a schedule from its due run at to its following run
+// next, reporting false when another sweep already claimed it so the same
+// occurrence never starts twice.
+func (s *service) ClaimSchedule(id int64, at time.Time, next *time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `UPDATE pipeline_schedules SET next_run_at = $1, last_run_at = CURRENT_TIMESTAMP WHERE id = $2 AND next_run_at = $3 AND NOT paused`, next, id, at)
+
+	if err != nil {
+		slog.Error("error claiming schedule", "error", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
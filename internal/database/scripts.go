@@ -0,0 +1,231 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// CreateScript adds a script to the library as its version 1.
+func (s *service) CreateScript(script *models.Script) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO scripts (user_id, name, version, body) VALUES ($1, $2, 1, $3) RETURNING id`, script.UserID, script.Name, script.Body).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting script", "error", err)
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO script_versions (script_id, version, body) VALUES ($1, 1, $2)`, id, script.Body); err != nil {
+		slog.Error("error inserting script version", "error", err)
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// UpdateScript renames a script and, when the body changed, saves it as a new
+// version that every server using the script runs from then on.
+func (s *service) UpdateScript(opts *models.UpdateScript) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if opts.Name != "" {
+		_, err := tx.ExecContext(ctx, `UPDATE scripts SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.Name, opts.ID)
+		if err != nil {
+			slog.Error("error in update script name", "error", err)
+			return err
+		}
+	}
+
+	if opts.Body != "" {
+		var version int
+		err := tx.QueryRowContext(ctx, `UPDATE scripts SET version = version + 1, body = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND body <> $1 RETURNING version`, opts.Body, opts.ID).Scan(&version)
+
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `INSERT INTO script_versions (script_id, version, body) VALUES ($1, $2, $3)`, opts.ID, version, opts.Body)
+		} else if errors.Is(err, sql.ErrNoRows) {
+			// Same body, nothing to version.
+			err = nil
+		}
+
+		if err != nil {
+			slog.Error("error in update script body", "error", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) DeleteScript(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM scripts WHERE id = $1`, id); err != nil {
+		slog.Error("error deleting script", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetScript(id int64) (models.Script, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM scripts WHERE id = $1`, id)
+
+	script, err := models.ScanRowScript(row)
+
+	if err != nil {
+		slog.Error("error in script query", "error", err)
+		return models.Script{}, err
+	}
+
+	return script, nil
+}
+
+// GetScripts returns the scripts with the given IDs by ID.
+func (s *service) GetScripts(ids []int64) (map[int64]models.Script, error) {
+	scripts := make(map[int64]models.Script)
+
+	if len(ids) == 0 {
+		return scripts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM scripts WHERE id = ANY($1)`, pq.Array(ids))
+
+	if err != nil {
+		slog.Error("error in scripts query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	list, err := ScanRows(rows, models.ScanScript)
+
+	if err != nil {
+		slog.Error("error scanning scripts rows", "error", err)
+		return nil, err
+	}
+
+	for _, script := range list {
+		scripts[script.ID] = script
+	}
+
+	return scripts, nil
+}
+
+func (s *service) ListScripts(user_id int64) ([]models.Script, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM scripts WHERE user_id = $1 ORDER BY name, id`, user_id)
+
+	if err != nil {
+		slog.Error("error in scripts query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	scripts, err := ScanRows(rows, models.ScanScript)
+
+	if err != nil {
+		slog.Error("error scanning scripts rows", "error", err)
+		return nil, err
+	}
+
+	return scripts, nil
+}
+
+func (s *service) ListScriptVersions(script_id int64) ([]models.ScriptVersion, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM script_versions WHERE script_id = $1 ORDER BY version DESC`, script_id)
+
+	if err != nil {
+		slog.Error("error in script versions query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	versions, err := ScanRows(rows, models.ScanScriptVersion)
+
+	if err != nil {
+		slog.Error("error scanning script versions rows", "error", err)
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// ListScriptServers returns every server using a script, active or not.
+func (s *service) ListScriptServers(script_id int64) ([]models.UpdateServer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM servers WHERE script_id = $1 ORDER BY pipeline_id, id`, script_id)
+
+	if err != nil {
+		slog.Error("error in script servers query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	servers, err := ScanRows(rows, models.ScanUpdateServer)
+
+	if err != nil {
+		slog.Error("error scanning servers rows", "error", err)
+		return nil, err
+	}
+
+	return servers, nil
+}
+
+// SetServerScript makes a server run a library script with its params, 0
+// going back to the server's own script.
+func (s *service) SetServerScript(server_id int64, script_id int64, params map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE servers SET script_id = $1, script_params = $2 WHERE id = $3`, script_id, models.JoinScriptParams(params), server_id)
+
+	if err != nil {
+		slog.Error("error in update server script", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+func (s *service) CreateServerResult(result *models.ServerResult) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `INSERT INTO update_server_results (update_id, server_id, label, stage_id, stage, status, output, started_at, finished_at, batch, phase, attempt, failure_class) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id`,
+		result.UpdateID, result.ServerID, result.Label, result.StageID, result.Stage, result.Status, result.Output, nullTime(result.StartedAt), nullTime(result.FinishedAt), result.Batch, result.Phase, result.Attempt, result.FailureClass).Scan(&result.ID)
+
+	if err != nil {
+		slog.Error("error inserting server result", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) ListServerResults(update_id int64) ([]models.ServerResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM update_server_results WHERE update_id = $1 ORDER BY id`, update_id)
+
+	if err != nil {
+		slog.Error("error in server results query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results, err := ScanRows(rows, models.ScanServerResult)
+
+	if err != nil {
+		slog.Error("error scanning server results rows", "error", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ListServerAttempts lists the latest deploy attempts of a server across
+// runs, newest first, retried attempts included.
+func (s *service) ListServerAttempts(server_id int64, limit int) ([]models.ServerResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM update_server_results WHERE server_id = $1 AND attempt > 0 AND phase <> $2 ORDER BY id DESC LIMIT $3`, server_id, models.PhaseRollback, limit)
+
+	if err != nil {
+		slog.Error("error in server attempts query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	results, err := ScanRows(rows, models.ScanServerResult)
+
+	if err != nil {
+		slog.Error("error scanning server attempts rows", "error", err)
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (s *service) GetUpdate(id int64) (Update, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM updates WHERE id = $1`, id)
+
+	if err != nil {
+		slog.Error("error in update query", "error", err)
+		return Update{}, err
+	}
+
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return Update{}, err
+		}
+
+		return Update{}, sql.ErrNoRows
+	}
+
+	return scanUpdate(rows)
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
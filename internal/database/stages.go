@@ -0,0 +1,126 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) CreateStage(stage *models.Stage) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO stages (pipeline_id, name, position) VALUES ($1, $2, $3) RETURNING id`, stage.PipelineID, stage.Name, stage.Position).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting stage", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *service) UpdateStage(opts *models.UpdateStage) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if opts.Name != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE stages SET name = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.Name, opts.ID)
+		if err != nil {
+			slog.Error("error in update stage name", "error", err)
+			return err
+		}
+	}
+
+	if opts.Position != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE stages SET position = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, *opts.Position, opts.ID)
+		if err != nil {
+			slog.Error("error in update stage position", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeleteStage removes a stage, its servers go back to the default stage.
+func (s *service) DeleteStage(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE servers SET stage_id = 0 WHERE stage_id = $1`, id); err != nil {
+		slog.Error("error clearing servers stage", "error", err)
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM stages WHERE id = $1`, id); err != nil {
+		slog.Error("error deleting stage", "error", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *service) GetStage(id int64) (models.Stage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM stages WHERE id = $1`, id)
+
+	stage, err := models.ScanRowStage(row)
+
+	if err != nil {
+		slog.Error("error in stage query", "error", err)
+		return models.Stage{}, err
+	}
+
+	return stage, nil
+}
+
+func (s *service) ListStages(pipeline_id int64) ([]models.Stage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM stages WHERE pipeline_id = $1 ORDER BY position, id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in stages query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	stages, err := ScanRows(rows, models.ScanStage)
+
+	if err != nil {
+		slog.Error("error scanning stages rows", "error", err)
+		return nil, err
+	}
+
+	return stages, nil
+}
+
+// SetServerStage moves a server to a stage, 0 being the default stage.
+func (s *service) SetServerStage(server_id int64, stage_id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE servers SET stage_id = $1 WHERE id = $2`, stage_id, server_id)
+
+	if err != nil {
+		slog.Error("error in update server stage", "error", err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ListVariables returns the variables of a pipeline and the overrides of its
+// servers.
+func (s *service) ListVariables(pipeline_id int64) ([]models.Variable, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM variables WHERE pipeline_id = $1 ORDER BY server_id, name`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in variables query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	variables, err := ScanRows(rows, models.ScanVariable)
+
+	if err != nil {
+		slog.Error("error scanning variables rows", "error", err)
+		return nil, err
+	}
+
+	return variables, nil
+}
+
+// SetVariables replaces the variables of a pipeline, or the overrides of one
+// of its servers when server_id is not 0.
+func (s *service) SetVariables(pipeline_id int64, server_id int64, variables map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM variables WHERE pipeline_id = $1 AND server_id = $2`, pipeline_id, server_id); err != nil {
+		slog.Error("error clearing variables", "error", err)
+		return err
+	}
+
+	for name, value := range variables {
+		_, err := tx.ExecContext(ctx, `INSERT INTO variables (pipeline_id, server_id, name, value) VALUES ($1, $2, $3, $4)`, pipeline_id, server_id, name, value)
+
+		if err != nil {
+			slog.Error("error inserting variable", "error", err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// ErrDuplicateDelivery is returned when a claimed delivery was already
+// claimed, git hosts retry deliveries with the same id.
+var ErrDuplicateDelivery = errors.New("duplicate webhook delivery")
+
+// CreateWebhookDelivery records a delivery. A Claimed delivery claims its id
+// in the same statement so concurrent copies of it are recorded only once.
+func (s *service) CreateWebhookDelivery(delivery *models.WebhookDelivery) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO webhook_deliveries (delivery_id, provider, event_type, headers, body, signature_valid, decision, redelivery_of, pipeline_id, claimed) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (provider, delivery_id) WHERE claimed DO NOTHING RETURNING id`, delivery.DeliveryID, delivery.Provider, delivery.EventType, string(delivery.Headers), delivery.Body, delivery.SignatureValid, delivery.Decision, delivery.RedeliveryOf, delivery.PipelineID, delivery.Claimed).Scan(&id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDuplicateDelivery
+	}
+
+	if err != nil {
+		slog.Error("error inserting webhook delivery", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// ReleaseWebhookDelivery gives up the claim of a delivery that could not be
+// routed, so the provider can deliver it again.
+func (s *service) ReleaseWebhookDelivery(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET claimed = FALSE, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+
+	if err != nil {
+		slog.Error("error releasing webhook delivery", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) UpdateWebhookDeliveryDecision(id int64, decision string, updateIds []int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries SET decision = $1, update_ids = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`, decision, models.JoinUpdateIDs(updateIds), id)
+
+	if err != nil {
+		slog.Error("error updating webhook delivery decision", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetWebhookDelivery(id int64) (models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM webhook_deliveries WHERE id = $1`, id)
+
+	delivery, err := models.ScanRowWebhookDelivery(row)
+
+	if err != nil {
+		slog.Error("error in webhook delivery query", "error", err)
+		return models.WebhookDelivery{}, err
+	}
+
+	return delivery, nil
+}
+
+func (s *service) ListWebhookDeliveries(limit int, offset int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM webhook_deliveries ORDER BY id DESC LIMIT $1 OFFSET $2`, limit, offset)
+
+	if err != nil {
+		slog.Error("error in webhook deliveries query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries, err := ScanRows(rows, models.ScanWebhookDelivery)
+
+	if err != nil {
+		slog.Error("error scanning webhook deliveries rows", "error", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ListUserWebhookDeliveries lists the deliveries sent to the webhooks of the
+// pipelines of a user.
+func (s *service) ListUserWebhookDeliveries(user_id int64, limit int, offset int) ([]models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT d.* FROM webhook_deliveries d JOIN pipelines p ON p.id = d.pipeline_id WHERE p.user_id = $1 ORDER BY d.id DESC LIMIT $2 OFFSET $3`, user_id, limit, offset)
+
+	if err != nil {
+		slog.Error("error in user webhook deliveries query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	deliveries, err := ScanRows(rows, models.ScanWebhookDelivery)
+
+	if err != nil {
+		slog.Error("error scanning webhook deliveries rows", "error", err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
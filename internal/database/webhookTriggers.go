@@ -0,0 +1,180 @@
+package database
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"log/slog"
+	"time"
+)
+
+func (s *service) CreateWebhookTrigger(trigger *models.WebhookTrigger) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO webhook_triggers (pipeline_id, repository, branch_pattern, event_type, active, tag_pattern, include_paths, exclude_paths, wait_for_ci, ci_timeout_minutes) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`, trigger.PipelineID, trigger.Repository, trigger.BranchPattern, trigger.EventType, trigger.Active, trigger.TagPattern, models.JoinPaths(trigger.IncludePaths), models.JoinPaths(trigger.ExcludePaths), trigger.WaitForCI, trigger.CITimeoutMinutes).Scan(&id)
+
+	if err != nil {
+		slog.Error("error inserting webhook trigger", "error", err)
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *service) UpdateWebhookTrigger(opts *models.UpdateWebhookTrigger) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if opts.Repository != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET repository = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.Repository, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger repository", "error", err)
+			return err
+		}
+	}
+
+	if opts.BranchPattern != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET branch_pattern = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.BranchPattern, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger branch pattern", "error", err)
+			return err
+		}
+	}
+
+	if opts.EventType != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET event_type = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.EventType, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger event type", "error", err)
+			return err
+		}
+	}
+
+	if opts.TagPattern != "" {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET tag_pattern = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.TagPattern, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger tag pattern", "error", err)
+			return err
+		}
+	}
+
+	// A nil filter is left unchanged, an empty one clears it.
+	if opts.IncludePaths != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET include_paths = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, models.JoinPaths(opts.IncludePaths), opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger include paths", "error", err)
+			return err
+		}
+	}
+
+	if opts.ExcludePaths != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET exclude_paths = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, models.JoinPaths(opts.ExcludePaths), opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger exclude paths", "error", err)
+			return err
+		}
+	}
+
+	if opts.WaitForCI != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET wait_for_ci = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, *opts.WaitForCI, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger wait for ci", "error", err)
+			return err
+		}
+	}
+
+	if opts.CITimeoutMinutes != 0 {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET ci_timeout_minutes = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, opts.CITimeoutMinutes, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger ci timeout", "error", err)
+			return err
+		}
+	}
+
+	if opts.Active != nil {
+		_, err := s.db.ExecContext(ctx, `UPDATE webhook_triggers SET active = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, *opts.Active, opts.ID)
+		if err != nil {
+			slog.Error("error in update webhook trigger active", "error", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *service) DeleteWebhookTrigger(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM webhook_triggers WHERE id = $1`, id)
+	if err != nil {
+		slog.Error("error deleting webhook trigger", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *service) GetWebhookTrigger(id int64) (models.WebhookTrigger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, `SELECT * FROM webhook_triggers WHERE id = $1`, id)
+
+	trigger, err := models.ScanRowWebhookTrigger(row)
+
+	if err != nil {
+		slog.Error("error in webhook trigger query", "error", err)
+		return models.WebhookTrigger{}, err
+	}
+
+	return trigger, nil
+}
+
+func (s *service) ListWebhookTriggers(pipeline_id int64) ([]models.WebhookTrigger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM webhook_triggers WHERE pipeline_id = $1 ORDER BY id`, pipeline_id)
+
+	if err != nil {
+		slog.Error("error in webhook triggers query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	triggers, err := ScanRows(rows, models.ScanWebhookTrigger)
+
+	if err != nil {
+		slog.Error("error scanning webhook triggers rows", "error", err)
+		return nil, err
+	}
+
+	return triggers, nil
+}
+
+// ListActiveWebhookTriggers returns the active rules registered for a repository.
+// Branch and event matching is left to the caller since patterns are globs.
+func (s *service) ListActiveWebhookTriggers(repository string) ([]models.WebhookTrigger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT * FROM webhook_triggers WHERE repository = $1 AND active = true ORDER BY id`, repository)
+
+	if err != nil {
+		slog.Error("error in active webhook triggers query", "error", err)
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	triggers, err := ScanRows(rows, models.ScanWebhookTrigger)
+
+	if err != nil {
+		slog.Error("error scanning webhook triggers rows", "error", err)
+		return nil, err
+	}
+
+	return triggers, nil
+}
@@ -0,0 +1,67 @@
+package deploy
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"auto-update/internal/database/models"
+)
+
+// DefaultApprovalTimeout is how long a run awaits approval when the pipeline
+// does not set it.
+const DefaultApprovalTimeout = 24 * time.Hour
+
+var (
+	ErrNotApprover   = errors.New("user is not an approver of the pipeline")
+	ErrSelfApproval  = errors.New("the user that requested the run cannot approve it")
+	ErrNotAwaiting   = errors.New("run is not awaiting approval")
+	ErrApprovalEnded = errors.New("approval expired")
+)
+
+// ApprovalDeadline is when a run requested at now stops awaiting approval.
+func ApprovalDeadline(timeoutMinutes int, now time.Time) time.Time {
+	timeout := DefaultApprovalTimeout
+
+	if timeoutMinutes > 0 {
+		timeout = time.Duration(timeoutMinutes) * time.Minute
+	}
+
+	return now.Add(timeout)
+}
+
+// CheckApprover validates that userID can decide on a run awaiting approval
+// until deadline, requested by requestedBy.
+func CheckApprover(approvers []int64, requestedBy int64, userID int64, deadline *time.Time, now time.Time) error {
+	if deadline != nil && now.After(*deadline) {
+		return ErrApprovalEnded
+	}
+
+	if !slices.Contains(approvers, userID) {
+		return ErrNotApprover
+	}
+
+	if requestedBy != 0 && requestedBy == userID {
+		return ErrSelfApproval
+	}
+
+	return nil
+}
+
+// CountApprovals returns how many distinct users approved and whether any
+// rejected.
+func CountApprovals(approvals []models.RunApproval) (int, bool) {
+	approved := make(map[int64]bool)
+	rejected := false
+
+	for _, approval := range approvals {
+		switch approval.Decision {
+		case models.ApprovalApproved:
+			approved[approval.UserID] = true
+		case models.ApprovalRejected:
+			rejected = true
+		}
+	}
+
+	return len(approved), rejected
+}
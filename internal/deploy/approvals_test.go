@@ -0,0 +1,41 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalDeadline(t *testing.T) {
+	now := time.Date(2024, 7, 26, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, now.Add(DefaultApprovalTimeout), ApprovalDeadline(0, now))
+	assert.Equal(t, now.Add(30*time.Minute), ApprovalDeadline(30, now))
+}
+
+func TestCheckApprover(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	approvers := []int64{2, 3}
+
+	assert.NoError(t, CheckApprover(approvers, 1, 2, &later, now))
+	assert.ErrorIs(t, CheckApprover(approvers, 1, 4, &later, now), ErrNotApprover)
+	assert.ErrorIs(t, CheckApprover(approvers, 2, 2, &later, now), ErrSelfApproval)
+	assert.ErrorIs(t, CheckApprover(approvers, 1, 2, &earlier, now), ErrApprovalEnded)
+}
+
+func TestCountApprovals(t *testing.T) {
+	approved, rejected := CountApprovals([]models.RunApproval{
+		{UserID: 2, Decision: models.ApprovalApproved},
+		{UserID: 2, Decision: models.ApprovalApproved},
+		{UserID: 3, Decision: models.ApprovalApproved},
+	})
+	assert.Equal(t, 2, approved)
+	assert.False(t, rejected)
+
+	_, rejected = CountApprovals([]models.RunApproval{{UserID: 2, Decision: models.ApprovalRejected}})
+	assert.True(t, rejected)
+}
@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+
+	"auto-update/internal/database/models"
+)
+
+var ErrInvalidLink = errors.New("invalid pipeline link")
+
+// ValidLinkCondition reports whether a downstream pipeline can run on the
+// condition.
+func ValidLinkCondition(condition string) bool {
+	switch condition {
+	case models.LinkOnSuccess, models.LinkOnFailure, models.LinkAlways:
+		return true
+	}
+
+	return false
+}
+
+// LinkTriggered reports whether a run that finished with status enqueues the
+// downstream pipeline of the link. Runs that did not deploy, like skipped
+// ones, trigger nothing.
+func LinkTriggered(link models.PipelineLink, status string) bool {
+	switch status {
+	case "success":
+		return link.Condition == models.LinkOnSuccess || link.Condition == models.LinkAlways
+	case "error":
+		return link.Condition == models.LinkOnFailure || link.Condition == models.LinkAlways
+	}
+
+	return false
+}
+
+// CheckLinks validates replacing the downstream pipelines of pipelineID in
+// links, the existing links of the user, with downstream. It fails when the
+// new links would chain a pipeline back to itself.
+func CheckLinks(links []models.PipelineLink, pipelineID int64, downstream []models.PipelineLink) error {
+	graph := make(map[int64][]int64)
+
+	for _, link := range links {
+		if link.PipelineID != pipelineID {
+			graph[link.PipelineID] = append(graph[link.PipelineID], link.DownstreamID)
+		}
+	}
+
+	seen := make(map[int64]bool)
+
+	for _, link := range downstream {
+		if !ValidLinkCondition(link.Condition) {
+			return fmt.Errorf("%w: condition %q", ErrInvalidLink, link.Condition)
+		}
+
+		if seen[link.DownstreamID] {
+			return fmt.Errorf("%w: pipeline %d linked twice", ErrInvalidLink, link.DownstreamID)
+		}
+
+		seen[link.DownstreamID] = true
+		graph[pipelineID] = append(graph[pipelineID], link.DownstreamID)
+	}
+
+	// A cycle through the new links has to come back to pipelineID.
+	visited := make(map[int64]bool)
+	stack := append([]int64{}, graph[pipelineID]...)
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if current == pipelineID {
+			return fmt.Errorf("%w: cycle through pipeline %d", ErrInvalidLink, pipelineID)
+		}
+
+		if visited[current] {
+			continue
+		}
+
+		visited[current] = true
+		stack = append(stack, graph[current]...)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkTriggered(t *testing.T) {
+	success := models.PipelineLink{Condition: models.LinkOnSuccess}
+	failure := models.PipelineLink{Condition: models.LinkOnFailure}
+	always := models.PipelineLink{Condition: models.LinkAlways}
+
+	assert.True(t, LinkTriggered(success, "success"))
+	assert.False(t, LinkTriggered(success, "error"))
+	assert.True(t, LinkTriggered(failure, "error"))
+	assert.False(t, LinkTriggered(failure, "success"))
+	assert.True(t, LinkTriggered(always, "success"))
+	assert.True(t, LinkTriggered(always, "error"))
+	assert.False(t, LinkTriggered(always, "skipped"))
+}
+
+func TestCheckLinks(t *testing.T) {
+	// 1 -> 2 -> 3
+	links := []models.PipelineLink{
+		{PipelineID: 1, DownstreamID: 2, Condition: models.LinkOnSuccess},
+		{PipelineID: 2, DownstreamID: 3, Condition: models.LinkOnSuccess},
+	}
+
+	assert.NoError(t, CheckLinks(links, 3, []models.PipelineLink{{DownstreamID: 4, Condition: models.LinkAlways}}))
+	assert.ErrorIs(t, CheckLinks(links, 3, []models.PipelineLink{{DownstreamID: 1, Condition: models.LinkOnSuccess}}), ErrInvalidLink)
+	assert.ErrorIs(t, CheckLinks(links, 4, []models.PipelineLink{{DownstreamID: 4, Condition: models.LinkOnSuccess}}), ErrInvalidLink)
+	assert.ErrorIs(t, CheckLinks(links, 3, []models.PipelineLink{{DownstreamID: 4, Condition: "sometimes"}}), ErrInvalidLink)
+	assert.ErrorIs(t, CheckLinks(links, 3, []models.PipelineLink{{DownstreamID: 4, Condition: models.LinkAlways}, {DownstreamID: 4, Condition: models.LinkOnFailure}}), ErrInvalidLink)
+
+	// Replacing 1's links drops 1 -> 2, so 2 -> ... -> 1 is no longer a cycle.
+	assert.NoError(t, CheckLinks(append(links, models.PipelineLink{PipelineID: 3, DownstreamID: 1}), 1, nil))
+}
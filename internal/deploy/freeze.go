@@ -0,0 +1,140 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"auto-update/internal/database/models"
+	"auto-update/internal/schedule"
+)
+
+var ErrInvalidFreeze = errors.New("invalid freeze window")
+
+// NormalizeFreeze validates a freeze window, defaulting it to reject runs in
+// UTC and clearing the fields of the kind of window it is not.
+func NormalizeFreeze(window models.FreezeWindow) (models.FreezeWindow, error) {
+	switch window.Scope {
+	case models.FreezeGlobal:
+		window.ScopeID = 0
+	case models.FreezeCompany, models.FreezePipeline:
+		if window.ScopeID == 0 {
+			return window, fmt.Errorf("%w: %s windows need a scope_id", ErrInvalidFreeze, window.Scope)
+		}
+	default:
+		return window, fmt.Errorf("%w: scope must be %q, %q or %q", ErrInvalidFreeze, models.FreezeGlobal, models.FreezeCompany, models.FreezePipeline)
+	}
+
+	if window.Action == "" {
+		window.Action = models.FreezeReject
+	}
+
+	if window.Action != models.FreezeReject && window.Action != models.FreezeHold {
+		return window, fmt.Errorf("%w: action must be %q or %q", ErrInvalidFreeze, models.FreezeReject, models.FreezeHold)
+	}
+
+	if window.Timezone == "" {
+		window.Timezone = "UTC"
+	}
+
+	if _, err := time.LoadLocation(window.Timezone); err != nil {
+		return window, fmt.Errorf("%w: timezone %q", ErrInvalidFreeze, window.Timezone)
+	}
+
+	if strings.TrimSpace(window.Cron) != "" {
+		if _, err := schedule.Parse(window.Cron); err != nil {
+			return window, fmt.Errorf("%w: %w", ErrInvalidFreeze, err)
+		}
+
+		if window.DurationMinutes <= 0 {
+			return window, fmt.Errorf("%w: recurring windows need a duration", ErrInvalidFreeze)
+		}
+
+		window.StartsAt, window.EndsAt = nil, nil
+		return window, nil
+	}
+
+	if window.StartsAt == nil || window.EndsAt == nil || !window.EndsAt.After(*window.StartsAt) {
+		return window, fmt.Errorf("%w: one-off windows need starts_at before ends_at", ErrInvalidFreeze)
+	}
+
+	window.Cron, window.DurationMinutes = "", 0
+	return window, nil
+}
+
+// FreezeEnd reports whether window is in effect at now and when the current
+// freeze ends.
+func FreezeEnd(window models.FreezeWindow, now time.Time) (time.Time, bool) {
+	if window.Cron == "" {
+		if window.StartsAt == nil || window.EndsAt == nil || now.Before(*window.StartsAt) || !now.Before(*window.EndsAt) {
+			return time.Time{}, false
+		}
+
+		return *window.EndsAt, true
+	}
+
+	cron, err := schedule.Parse(window.Cron)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+
+	// The latest start within the duration, Next is strictly after its
+	// argument so start one minute earlier to include a freeze starting
+	// exactly duration ago.
+	var end time.Time
+	for start := cron.Next(now.In(loc).Add(-duration - time.Minute)); !start.IsZero() && !start.After(now); start = cron.Next(start) {
+		if start.Add(duration).After(now) {
+			end = start.Add(duration)
+		}
+	}
+
+	return end, !end.IsZero()
+}
+
+// ActiveFreeze returns the window blocking a run at now, with when it ends.
+// Rejecting windows win over holding ones, then the one ending last.
+func ActiveFreeze(windows []models.FreezeWindow, now time.Time) (models.FreezeWindow, time.Time, bool) {
+	var active models.FreezeWindow
+	var until time.Time
+	found := false
+
+	for _, window := range windows {
+		end, ok := FreezeEnd(window, now)
+
+		if !ok {
+			continue
+		}
+
+		stricter := window.Action == models.FreezeReject && active.Action != models.FreezeReject
+		laxer := window.Action != models.FreezeReject && active.Action == models.FreezeReject
+
+		if !found || stricter || (!laxer && end.After(until)) {
+			active, until, found = window, end, true
+		}
+	}
+
+	return active, until, found
+}
+
+// FreezeReason explains why a run is blocked by window until the freeze ends.
+func FreezeReason(window models.FreezeWindow, until time.Time) string {
+	name := window.Name
+	if name == "" {
+		name = fmt.Sprintf("%d", window.ID)
+	}
+
+	verb := "rejected"
+	if window.Action == models.FreezeHold {
+		verb = "held"
+	}
+
+	return fmt.Sprintf("%s: deployment freeze %q (%s) until %s", verb, name, window.Scope, until.Format(time.RFC3339))
+}
@@ -0,0 +1,122 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func oneOff(action string, start time.Time, end time.Time) models.FreezeWindow {
+	return models.FreezeWindow{Scope: models.FreezeGlobal, Action: action, StartsAt: &start, EndsAt: &end, Timezone: "UTC"}
+}
+
+func TestNormalizeFreeze(t *testing.T) {
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+
+	window, err := NormalizeFreeze(models.FreezeWindow{Scope: models.FreezeGlobal, ScopeID: 5, StartsAt: &start, EndsAt: &end})
+	assert.NoError(t, err)
+	assert.Equal(t, models.FreezeReject, window.Action)
+	assert.Equal(t, "UTC", window.Timezone)
+	assert.Zero(t, window.ScopeID)
+
+	window, err = NormalizeFreeze(models.FreezeWindow{Scope: models.FreezePipeline, ScopeID: 1, Action: models.FreezeHold, Cron: "0 18 * * 5", DurationMinutes: 360, StartsAt: &start})
+	assert.NoError(t, err)
+	assert.Nil(t, window.StartsAt)
+
+	invalid := []models.FreezeWindow{
+		{Scope: "team", StartsAt: &start, EndsAt: &end},
+		{Scope: models.FreezeCompany, StartsAt: &start, EndsAt: &end},
+		{Scope: models.FreezeGlobal, Action: "pause", StartsAt: &start, EndsAt: &end},
+		{Scope: models.FreezeGlobal, Timezone: "Mars/Olympus", StartsAt: &start, EndsAt: &end},
+		{Scope: models.FreezeGlobal, StartsAt: &end, EndsAt: &start},
+		{Scope: models.FreezeGlobal, Cron: "0 18 * * 5"},
+		{Scope: models.FreezeGlobal, Cron: "0 18 * *", DurationMinutes: 60},
+	}
+
+	for _, window := range invalid {
+		_, err := NormalizeFreeze(window)
+		assert.ErrorIs(t, err, ErrInvalidFreeze)
+	}
+}
+
+func TestFreezeEndOneOff(t *testing.T) {
+	start := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	end := start.Add(72 * time.Hour)
+	window := oneOff(models.FreezeReject, start, end)
+
+	_, ok := FreezeEnd(window, start.Add(-time.Second))
+	assert.False(t, ok)
+
+	until, ok := FreezeEnd(window, start)
+	assert.True(t, ok)
+	assert.Equal(t, end, until)
+
+	_, ok = FreezeEnd(window, end)
+	assert.False(t, ok)
+}
+
+func TestFreezeEndRecurring(t *testing.T) {
+	// Fridays from 18:00 to midnight.
+	window := models.FreezeWindow{Cron: "0 18 * * 5", DurationMinutes: 360, Timezone: "UTC"}
+	friday := time.Date(2024, 7, 26, 0, 0, 0, 0, time.UTC)
+
+	_, ok := FreezeEnd(window, friday.Add(17*time.Hour+59*time.Minute))
+	assert.False(t, ok)
+
+	until, ok := FreezeEnd(window, friday.Add(18*time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, friday.Add(24*time.Hour), until)
+
+	_, ok = FreezeEnd(window, friday.Add(23*time.Hour+59*time.Minute))
+	assert.True(t, ok)
+
+	_, ok = FreezeEnd(window, friday.Add(24*time.Hour))
+	assert.False(t, ok)
+}
+
+func TestFreezeEndTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	window := models.FreezeWindow{Cron: "0 18 * * 5", DurationMinutes: 60, Timezone: loc.String()}
+
+	// 18:30 in Sao Paulo is 21:30 UTC.
+	_, ok := FreezeEnd(window, time.Date(2024, 7, 26, 21, 30, 0, 0, time.UTC))
+	assert.True(t, ok)
+
+	_, ok = FreezeEnd(window, time.Date(2024, 7, 26, 18, 30, 0, 0, time.UTC))
+	assert.False(t, ok)
+}
+
+func TestActiveFreeze(t *testing.T) {
+	now := time.Date(2024, 11, 29, 12, 0, 0, 0, time.UTC)
+
+	_, _, ok := ActiveFreeze(nil, now)
+	assert.False(t, ok)
+
+	past := oneOff(models.FreezeReject, now.Add(-3*time.Hour), now.Add(-time.Hour))
+	hold := oneOff(models.FreezeHold, now.Add(-time.Hour), now.Add(5*time.Hour))
+	reject := oneOff(models.FreezeReject, now.Add(-time.Hour), now.Add(time.Hour))
+	rejectLater := oneOff(models.FreezeReject, now.Add(-time.Hour), now.Add(2*time.Hour))
+
+	window, until, ok := ActiveFreeze([]models.FreezeWindow{past, hold}, now)
+	assert.True(t, ok)
+	assert.Equal(t, models.FreezeHold, window.Action)
+	assert.Equal(t, now.Add(5*time.Hour), until)
+
+	window, until, _ = ActiveFreeze([]models.FreezeWindow{hold, reject, rejectLater}, now)
+	assert.Equal(t, models.FreezeReject, window.Action)
+	assert.Equal(t, now.Add(2*time.Hour), until)
+}
+
+func TestFreezeReason(t *testing.T) {
+	until := time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC)
+	window := models.FreezeWindow{Name: "Black Friday", Scope: models.FreezeCompany, Action: models.FreezeHold}
+
+	assert.Equal(t, `held: deployment freeze "Black Friday" (company) until 2024-12-02T00:00:00Z`, FreezeReason(window, until))
+}
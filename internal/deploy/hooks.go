@@ -0,0 +1,68 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"auto-update/internal/database/models"
+)
+
+var ErrInvalidHook = errors.New("invalid hook")
+
+// NormalizeHook validates a hook, defaulting HTTP hooks to POST and clearing
+// the fields its kind does not use.
+func NormalizeHook(hook models.Hook) (models.Hook, error) {
+	if hook.Phase != models.HookPre && hook.Phase != models.HookPost {
+		return hook, fmt.Errorf("%w: phase must be %q or %q", ErrInvalidHook, models.HookPre, models.HookPost)
+	}
+
+	switch hook.Kind {
+	case models.HookCommand:
+		if hook.ServerID == 0 || strings.TrimSpace(hook.Command) == "" {
+			return hook, fmt.Errorf("%w: command hooks need a server and a command", ErrInvalidHook)
+		}
+
+		hook.Method, hook.URL, hook.Body = "", "", ""
+
+	case models.HookHTTP:
+		target, err := url.Parse(hook.URL)
+
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return hook, fmt.Errorf("%w: url %q", ErrInvalidHook, hook.URL)
+		}
+
+		hook.Method = strings.ToUpper(strings.TrimSpace(hook.Method))
+		if hook.Method == "" {
+			hook.Method = http.MethodPost
+		}
+
+		switch hook.Method {
+		case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		default:
+			return hook, fmt.Errorf("%w: method %q", ErrInvalidHook, hook.Method)
+		}
+
+		hook.ServerID, hook.Command = 0, ""
+
+	default:
+		return hook, fmt.Errorf("%w: kind must be %q or %q", ErrInvalidHook, models.HookCommand, models.HookHTTP)
+	}
+
+	return hook, nil
+}
+
+// HooksOf returns the hooks of a phase, keeping their order.
+func HooksOf(hooks []models.Hook, phase string) []models.Hook {
+	result := make([]models.Hook, 0, len(hooks))
+
+	for _, hook := range hooks {
+		if hook.Phase == phase {
+			result = append(result, hook)
+		}
+	}
+
+	return result
+}
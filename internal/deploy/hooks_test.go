@@ -0,0 +1,43 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeHook(t *testing.T) {
+	hook, err := NormalizeHook(models.Hook{Phase: models.HookPre, Kind: models.HookHTTP, URL: "https://lb.example.com/maintenance", Command: "ls", ServerID: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", hook.Method)
+	assert.Empty(t, hook.Command)
+	assert.Zero(t, hook.ServerID)
+
+	hook, err = NormalizeHook(models.Hook{Phase: models.HookPost, Kind: models.HookCommand, ServerID: 3, Command: "purge", URL: "http://x"})
+	assert.NoError(t, err)
+	assert.Empty(t, hook.URL)
+
+	invalid := []models.Hook{
+		{Phase: "during", Kind: models.HookHTTP, URL: "https://x"},
+		{Phase: models.HookPre, Kind: "email"},
+		{Phase: models.HookPre, Kind: models.HookCommand, Command: "ls"},
+		{Phase: models.HookPre, Kind: models.HookCommand, ServerID: 1},
+		{Phase: models.HookPre, Kind: models.HookHTTP, URL: "ftp://x"},
+		{Phase: models.HookPre, Kind: models.HookHTTP, URL: "https://x", Method: "TRACE"},
+	}
+
+	for _, hook := range invalid {
+		_, err := NormalizeHook(hook)
+		assert.ErrorIs(t, err, ErrInvalidHook, hook)
+	}
+}
+
+func TestHooksOf(t *testing.T) {
+	hooks := []models.Hook{{ID: 1, Phase: models.HookPre}, {ID: 2, Phase: models.HookPost}, {ID: 3, Phase: models.HookPre}}
+
+	pre := HooksOf(hooks, models.HookPre)
+	assert.Len(t, pre, 2)
+	assert.Equal(t, int64(3), pre[1].ID)
+	assert.Len(t, HooksOf(hooks, models.HookPost), 1)
+}
@@ -0,0 +1,99 @@
+package deploy
+
+import "auto-update/internal/database/models"
+
+// Readiness checks of a dry run, in the order they run.
+const (
+	CheckScript         = "script"
+	CheckCredentials    = "credentials"
+	CheckConnection     = "connection"
+	CheckHostKey        = "host_key"
+	CheckAuthentication = "authentication"
+)
+
+// Readiness check statuses.
+const (
+	CheckOK     = "ok"
+	CheckFailed = "failed"
+	// CheckWarning passed but needs attention, such as a host key that is
+	// not known yet.
+	CheckWarning = "warning"
+	// CheckSkipped did not run because an earlier check failed.
+	CheckSkipped = "skipped"
+)
+
+// ReadinessCheck is the outcome of one check of a server.
+type ReadinessCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Readiness reports whether a server is ready to be deployed, with what the
+// run would execute on it. A server is ready when none of its checks failed.
+type Readiness struct {
+	ServerID       int64            `json:"server_id"`
+	Label          string           `json:"label"`
+	Host           string           `json:"host"`
+	StageID        int64            `json:"stage_id"`
+	Canary         bool             `json:"canary"`
+	Script         string           `json:"script"`
+	RollbackScript string           `json:"rollback_script"`
+	TimeoutSeconds int              `json:"timeout_seconds"`
+	Ready          bool             `json:"ready"`
+	Checks         []ReadinessCheck `json:"checks"`
+}
+
+// NewReadiness starts the readiness report of a server.
+func NewReadiness(server models.UpdateServer) Readiness {
+	return Readiness{
+		ServerID:       server.ID,
+		Label:          server.Label,
+		Host:           server.Host,
+		StageID:        server.StageID,
+		Canary:         server.Canary,
+		Script:         server.Script,
+		RollbackScript: server.RollbackScript,
+		TimeoutSeconds: int(ServerTimeout(server).Seconds()),
+		Ready:          true,
+	}
+}
+
+// Check records a check, failed when err is not nil. It reports whether the
+// check passed.
+func (r *Readiness) Check(name string, err error) bool {
+	if err != nil {
+		r.Ready = false
+		r.Checks = append(r.Checks, ReadinessCheck{Name: name, Status: CheckFailed, Message: err.Error()})
+		return false
+	}
+
+	r.Checks = append(r.Checks, ReadinessCheck{Name: name, Status: CheckOK})
+
+	return true
+}
+
+// Warn records a check that passed with a warning.
+func (r *Readiness) Warn(name, message string) {
+	r.Checks = append(r.Checks, ReadinessCheck{Name: name, Status: CheckWarning, Message: message})
+}
+
+// Skip records checks that did not run.
+func (r *Readiness) Skip(names ...string) {
+	for _, name := range names {
+		r.Checks = append(r.Checks, ReadinessCheck{Name: name, Status: CheckSkipped})
+	}
+}
+
+// ReadyServers counts the ready servers of a dry run.
+func ReadyServers(reports []Readiness) int {
+	ready := 0
+
+	for _, report := range reports {
+		if report.Ready {
+			ready++
+		}
+	}
+
+	return ready
+}
@@ -0,0 +1,49 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReadiness(t *testing.T) {
+	report := NewReadiness(models.UpdateServer{ID: 7, Label: "web-1", Host: "10.0.0.1", StageID: 2, Canary: true, Script: "make deploy", TimeoutSeconds: 60})
+
+	assert.Equal(t, int64(7), report.ServerID)
+	assert.Equal(t, "web-1", report.Label)
+	assert.Equal(t, "10.0.0.1", report.Host)
+	assert.Equal(t, int64(2), report.StageID)
+	assert.True(t, report.Canary)
+	assert.Equal(t, "make deploy", report.Script)
+	assert.Equal(t, 60, report.TimeoutSeconds)
+	assert.True(t, report.Ready)
+	assert.Empty(t, report.Checks)
+
+	assert.Equal(t, int(DefaultServerTimeout.Seconds()), NewReadiness(models.UpdateServer{}).TimeoutSeconds)
+}
+
+func TestReadinessChecks(t *testing.T) {
+	report := NewReadiness(models.UpdateServer{})
+
+	assert.True(t, report.Check(CheckScript, nil))
+	report.Warn(CheckHostKey, "unknown host")
+	assert.True(t, report.Ready)
+
+	assert.False(t, report.Check(CheckConnection, errors.New("connection refused")))
+	report.Skip(CheckAuthentication)
+
+	assert.False(t, report.Ready)
+	assert.Equal(t, []ReadinessCheck{
+		{Name: CheckScript, Status: CheckOK},
+		{Name: CheckHostKey, Status: CheckWarning, Message: "unknown host"},
+		{Name: CheckConnection, Status: CheckFailed, Message: "connection refused"},
+		{Name: CheckAuthentication, Status: CheckSkipped},
+	}, report.Checks)
+}
+
+func TestReadyServers(t *testing.T) {
+	assert.Equal(t, 0, ReadyServers(nil))
+	assert.Equal(t, 2, ReadyServers([]Readiness{{Ready: true}, {Ready: false}, {Ready: true}}))
+}
@@ -0,0 +1,163 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Failure classes a retry policy may retry.
+const (
+	// FailureConnection is a server that could not be reached, authenticated
+	// or lost its connection before the script finished.
+	FailureConnection = "connection"
+	// FailureExit is a script that exited non-zero.
+	FailureExit = "exit"
+	// FailureTimeout is a script stopped after its timeout.
+	FailureTimeout = "timeout"
+)
+
+const (
+	// MaxAttempts caps the attempts of a retry policy.
+	MaxAttempts = 10
+	// MaxBackoff caps the wait between two attempts.
+	MaxBackoff = 5 * time.Minute
+)
+
+var ErrInvalidRetry = errors.New("invalid retry policy")
+
+var failureClasses = []string{FailureConnection, FailureExit, FailureTimeout}
+
+// RetryPolicy is how many times the deploy of a server is attempted, how
+// long to wait between attempts and which failures are retried. The wait
+// doubles after every attempt, starting at Backoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	On          []string
+}
+
+// ParseRetryOn parses a comma separated list of failure classes. An empty
+// list retries connection failures only.
+func ParseRetryOn(value string) ([]string, error) {
+	var classes []string
+
+	for _, class := range strings.Split(value, ",") {
+		class = strings.TrimSpace(class)
+
+		if class == "" || slices.Contains(classes, class) {
+			continue
+		}
+
+		if !slices.Contains(failureClasses, class) {
+			return nil, fmt.Errorf("%w: unknown failure class %q", ErrInvalidRetry, class)
+		}
+
+		classes = append(classes, class)
+	}
+
+	if len(classes) == 0 {
+		return []string{FailureConnection}, nil
+	}
+
+	return classes, nil
+}
+
+// ValidRetry checks the retry settings of a server or pipeline.
+func ValidRetry(maxAttempts, backoffSeconds int, on string) error {
+	if maxAttempts < 0 || maxAttempts > MaxAttempts {
+		return fmt.Errorf("%w: max attempts must be between 0 and %d", ErrInvalidRetry, MaxAttempts)
+	}
+
+	if backoffSeconds < 0 {
+		return fmt.Errorf("%w: backoff cannot be negative", ErrInvalidRetry)
+	}
+
+	_, err := ParseRetryOn(on)
+
+	return err
+}
+
+// WithRetry gives the pipeline retry policy to the servers that do not
+// override it.
+func WithRetry(servers []models.UpdateServer, pipeline models.Pipeline) []models.UpdateServer {
+	for i := range servers {
+		if servers[i].RetryMaxAttempts <= 0 {
+			servers[i].RetryMaxAttempts = pipeline.RetryMaxAttempts
+			servers[i].RetryBackoffSeconds = pipeline.RetryBackoffSeconds
+			servers[i].RetryOn = pipeline.RetryOn
+		}
+	}
+
+	return servers
+}
+
+// ServerRetry is the retry policy of a server. Servers without a valid
+// policy are attempted once.
+func ServerRetry(server models.UpdateServer) RetryPolicy {
+	on, err := ParseRetryOn(server.RetryOn)
+
+	if err != nil || server.RetryMaxAttempts <= 1 {
+		return RetryPolicy{MaxAttempts: 1}
+	}
+
+	return RetryPolicy{
+		MaxAttempts: min(server.RetryMaxAttempts, MaxAttempts),
+		Backoff:     time.Duration(max(server.RetryBackoffSeconds, 0)) * time.Second,
+		On:          on,
+	}
+}
+
+// Retries reports whether an attempt that failed with class is attempted
+// again.
+func (p RetryPolicy) Retries(class string, attempt int) bool {
+	return attempt < p.MaxAttempts && slices.Contains(p.On, class)
+}
+
+// Delay is how long to wait after the 1-based attempt before the next one.
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+
+	for i := 1; i < attempt && delay < MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, MaxBackoff)
+}
+
+// FlakinessReport summarizes the deploy attempts of a server.
+type FlakinessReport struct {
+	// Deploys is how many deploys the attempts belong to and Retried how many
+	// of them needed more than one attempt.
+	Deploys  int `json:"deploys"`
+	Retried  int `json:"retried"`
+	Attempts int `json:"attempts"`
+	// Failures counts the failed attempts by failure class.
+	Failures map[string]int `json:"failures"`
+}
+
+// Flakiness summarizes deploy attempts, see FlakinessReport.
+func Flakiness(attempts []models.ServerResult) FlakinessReport {
+	report := FlakinessReport{Attempts: len(attempts), Failures: make(map[string]int)}
+
+	for _, attempt := range attempts {
+		if attempt.FailureClass != "" {
+			report.Failures[attempt.FailureClass]++
+		}
+
+		if attempt.Phase == models.PhaseRetry {
+			continue
+		}
+
+		report.Deploys++
+
+		if attempt.Attempt > 1 {
+			report.Retried++
+		}
+	}
+
+	return report
+}
@@ -0,0 +1,94 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryOn(t *testing.T) {
+	on, err := ParseRetryOn("")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{FailureConnection}, on)
+
+	on, err = ParseRetryOn(" exit, timeout,exit ")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{FailureExit, FailureTimeout}, on)
+
+	_, err = ParseRetryOn("connection,reboot")
+	assert.ErrorIs(t, err, ErrInvalidRetry)
+}
+
+func TestValidRetry(t *testing.T) {
+	assert.NoError(t, ValidRetry(3, 10, "connection,exit"))
+	assert.NoError(t, ValidRetry(0, 0, ""))
+	assert.ErrorIs(t, ValidRetry(-1, 0, ""), ErrInvalidRetry)
+	assert.ErrorIs(t, ValidRetry(MaxAttempts+1, 0, ""), ErrInvalidRetry)
+	assert.ErrorIs(t, ValidRetry(3, -5, ""), ErrInvalidRetry)
+	assert.ErrorIs(t, ValidRetry(3, 5, "disk"), ErrInvalidRetry)
+}
+
+func TestWithRetry(t *testing.T) {
+	all := servers(2)
+	all[1].RetryMaxAttempts = 2
+	all[1].RetryOn = "exit"
+
+	all = WithRetry(all, models.Pipeline{RetryMaxAttempts: 4, RetryBackoffSeconds: 15, RetryOn: "timeout"})
+
+	assert.Equal(t, 4, all[0].RetryMaxAttempts)
+	assert.Equal(t, 15, all[0].RetryBackoffSeconds)
+	assert.Equal(t, "timeout", all[0].RetryOn)
+	assert.Equal(t, 2, all[1].RetryMaxAttempts)
+	assert.Equal(t, 0, all[1].RetryBackoffSeconds)
+	assert.Equal(t, "exit", all[1].RetryOn)
+}
+
+func TestServerRetry(t *testing.T) {
+	assert.Equal(t, RetryPolicy{MaxAttempts: 1}, ServerRetry(models.UpdateServer{}))
+	assert.Equal(t, RetryPolicy{MaxAttempts: 1}, ServerRetry(models.UpdateServer{RetryMaxAttempts: 3, RetryOn: "disk"}))
+
+	policy := ServerRetry(models.UpdateServer{RetryMaxAttempts: 3, RetryBackoffSeconds: 5})
+
+	assert.Equal(t, 3, policy.MaxAttempts)
+	assert.Equal(t, 5*time.Second, policy.Backoff)
+	assert.Equal(t, []string{FailureConnection}, policy.On)
+}
+
+func TestRetries(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, On: []string{FailureConnection, FailureTimeout}}
+
+	assert.True(t, policy.Retries(FailureConnection, 1))
+	assert.True(t, policy.Retries(FailureTimeout, 2))
+	assert.False(t, policy.Retries(FailureConnection, 3))
+	assert.False(t, policy.Retries(FailureExit, 1))
+	assert.False(t, policy.Retries("", 1))
+	assert.False(t, RetryPolicy{MaxAttempts: 1}.Retries(FailureConnection, 1))
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, Backoff: 10 * time.Second}
+
+	assert.Equal(t, 10*time.Second, policy.Delay(1))
+	assert.Equal(t, 20*time.Second, policy.Delay(2))
+	assert.Equal(t, 40*time.Second, policy.Delay(3))
+	assert.Equal(t, MaxBackoff, policy.Delay(10))
+	assert.Equal(t, time.Duration(0), RetryPolicy{MaxAttempts: 3}.Delay(2))
+}
+
+func TestFlakiness(t *testing.T) {
+	report := Flakiness([]models.ServerResult{
+		{Attempt: 2, Status: models.ServerSuccess},
+		{Attempt: 1, Phase: models.PhaseRetry, Status: models.ServerError, FailureClass: FailureConnection},
+		{Attempt: 1, Status: models.ServerSuccess},
+		{Attempt: 3, Status: models.ServerTimedOut, FailureClass: FailureTimeout},
+		{Attempt: 2, Phase: models.PhaseRetry, Status: models.ServerError, FailureClass: FailureConnection},
+		{Attempt: 1, Phase: models.PhaseRetry, Status: models.ServerError, FailureClass: FailureExit},
+	})
+
+	assert.Equal(t, 3, report.Deploys)
+	assert.Equal(t, 2, report.Retried)
+	assert.Equal(t, 6, report.Attempts)
+	assert.Equal(t, map[string]int{FailureConnection: 2, FailureTimeout: 1, FailureExit: 1}, report.Failures)
+}
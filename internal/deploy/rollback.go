@@ -0,0 +1,18 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"strings"
+)
+
+// WithRollbackScript gives the pipeline rollback script to the servers that
+// do not override it.
+func WithRollbackScript(servers []models.UpdateServer, pipeline models.Pipeline) []models.UpdateServer {
+	for i := range servers {
+		if strings.TrimSpace(servers[i].RollbackScript) == "" {
+			servers[i].RollbackScript = pipeline.RollbackScript
+		}
+	}
+
+	return servers
+}
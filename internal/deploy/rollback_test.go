@@ -0,0 +1,18 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRollbackScript(t *testing.T) {
+	all := servers(2)
+	all[1].RollbackScript = "git checkout -"
+
+	all = WithRollbackScript(all, models.Pipeline{RollbackScript: "./rollback.sh"})
+
+	assert.Equal(t, "./rollback.sh", all[0].RollbackScript)
+	assert.Equal(t, "git checkout -", all[1].RollbackScript)
+}
@@ -0,0 +1,44 @@
+package deploy
+
+import (
+	"fmt"
+
+	"auto-update/internal/database/models"
+)
+
+// ResolveScripts gives the servers that reference a library script its
+// current body. scripts holds the library scripts by ID.
+func ResolveScripts(servers []models.UpdateServer, scripts map[int64]models.Script) ([]models.UpdateServer, error) {
+	resolved := make([]models.UpdateServer, len(servers))
+
+	for i, server := range servers {
+		if server.ScriptID != 0 {
+			script, ok := scripts[server.ScriptID]
+
+			if !ok {
+				return nil, fmt.Errorf("script %d do servidor %s não encontrado", server.ScriptID, server.Label)
+			}
+
+			server.Script = script.Body
+		}
+
+		resolved[i] = server
+	}
+
+	return resolved, nil
+}
+
+// ScriptIDs lists the library scripts the servers reference, once each.
+func ScriptIDs(servers []models.UpdateServer) []int64 {
+	ids := make([]int64, 0)
+	seen := make(map[int64]bool)
+
+	for _, server := range servers {
+		if server.ScriptID != 0 && !seen[server.ScriptID] {
+			seen[server.ScriptID] = true
+			ids = append(ids, server.ScriptID)
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,35 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveScripts(t *testing.T) {
+	all := servers(3)
+	all[0].Script = "own script"
+	all[1].ScriptID = 10
+	all[1].ScriptParams = map[string]string{"PORT": "8080"}
+	all[2].ScriptID = 10
+
+	assert.Equal(t, []int64{10}, ScriptIDs(all))
+
+	scripts := map[int64]models.Script{10: {ID: 10, Body: "serve --port {{.Params.PORT}}"}}
+
+	resolved, err := ResolveScripts(all, scripts)
+	assert.NoError(t, err)
+	assert.Equal(t, "own script", resolved[0].Script)
+	assert.Equal(t, "serve --port {{.Params.PORT}}", resolved[1].Script)
+
+	rendered, err := RenderServers(resolved[:2], ScriptData{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "serve --port 8080", rendered[1].Script)
+
+	_, err = RenderServers(resolved[2:], ScriptData{}, nil)
+	assert.Error(t, err, "missing params must not render as empty")
+
+	_, err = ResolveScripts(all, map[int64]models.Script{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+// Package deploy holds the planning rules of a pipeline run: in which order
+// and groups its servers are deployed.
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"sort"
+)
+
+// DefaultStageName is the stage of the servers that are not in any stage.
+const DefaultStageName = "default"
+
+// Stage is a group of servers deployed in parallel.
+type Stage struct {
+	ID      int64
+	Name    string
+	Servers []models.UpdateServer
+}
+
+// PlanStages orders the servers of a run into stages by position. Servers
+// without a stage, or whose stage no longer exists, run last in the default
+// stage. Stages without servers are left out.
+func PlanStages(stages []models.Stage, servers []models.UpdateServer) []Stage {
+	ordered := make([]models.Stage, len(stages))
+	copy(ordered, stages)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Position != ordered[j].Position {
+			return ordered[i].Position < ordered[j].Position
+		}
+		return ordered[i].ID < ordered[j].ID
+	})
+
+	byStage := make(map[int64][]models.UpdateServer)
+	known := make(map[int64]bool)
+
+	for _, stage := range ordered {
+		known[stage.ID] = true
+	}
+
+	for _, server := range servers {
+		stageId := server.StageID
+		if !known[stageId] {
+			stageId = 0
+		}
+		byStage[stageId] = append(byStage[stageId], server)
+	}
+
+	plan := make([]Stage, 0, len(ordered)+1)
+
+	for _, stage := range ordered {
+		if len(byStage[stage.ID]) == 0 {
+			continue
+		}
+
+		plan = append(plan, Stage{ID: stage.ID, Name: stage.Name, Servers: byStage[stage.ID]})
+	}
+
+	if len(byStage[0]) > 0 {
+		plan = append(plan, Stage{ID: 0, Name: DefaultStageName, Servers: byStage[0]})
+	}
+
+	return plan
+}
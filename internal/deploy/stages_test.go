@@ -0,0 +1,53 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func labels(stage Stage) []string {
+	result := make([]string, 0, len(stage.Servers))
+	for _, server := range stage.Servers {
+		result = append(result, server.Label)
+	}
+	return result
+}
+
+func TestPlanStages(t *testing.T) {
+	stages := []models.Stage{
+		{ID: 3, Name: "workers", Position: 2},
+		{ID: 1, Name: "migrator", Position: 0},
+		{ID: 2, Name: "api", Position: 1},
+		{ID: 4, Name: "empty", Position: 3},
+	}
+
+	servers := []models.UpdateServer{
+		{ID: 1, Label: "api-1", StageID: 2},
+		{ID: 2, Label: "worker-1", StageID: 3},
+		{ID: 3, Label: "db", StageID: 1},
+		{ID: 4, Label: "api-2", StageID: 2},
+		{ID: 5, Label: "cron"},
+		{ID: 6, Label: "orphan", StageID: 99},
+	}
+
+	plan := PlanStages(stages, servers)
+
+	assert.Len(t, plan, 4)
+	assert.Equal(t, "migrator", plan[0].Name)
+	assert.Equal(t, []string{"db"}, labels(plan[0]))
+	assert.Equal(t, []string{"api-1", "api-2"}, labels(plan[1]))
+	assert.Equal(t, []string{"worker-1"}, labels(plan[2]))
+	assert.Equal(t, DefaultStageName, plan[3].Name)
+	assert.Equal(t, []string{"cron", "orphan"}, labels(plan[3]))
+}
+
+func TestPlanStagesWithoutStages(t *testing.T) {
+	plan := PlanStages(nil, []models.UpdateServer{{Label: "a"}, {Label: "b"}})
+
+	assert.Len(t, plan, 1)
+	assert.Equal(t, []string{"a", "b"}, labels(plan[0]))
+
+	assert.Empty(t, PlanStages(nil, nil))
+}
@@ -0,0 +1,171 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"auto-update/internal/database/models"
+)
+
+// Deployment strategies of a pipeline.
+const (
+	// StrategyAll deploys every server of a stage at once.
+	StrategyAll = "all"
+	// StrategyRolling deploys the servers of a stage in batches.
+	StrategyRolling = "rolling"
+	// StrategyCanary deploys the canary servers first and only promotes the
+	// rollout to the other servers once they stay healthy for the bake period.
+	StrategyCanary = "canary"
+)
+
+// DefaultBakePeriod is how long canaries bake when the pipeline does not set
+// it.
+const DefaultBakePeriod = 5 * time.Minute
+
+var ErrInvalidStrategy = errors.New("invalid deployment strategy")
+
+// BatchSize is how many servers a rolling batch updates, either a count or a
+// percentage of the servers of the stage.
+type BatchSize struct {
+	Count   int
+	Percent int
+}
+
+// ParseBatchSize reads "2" or "25%".
+func ParseBatchSize(value string) (BatchSize, error) {
+	value = strings.TrimSpace(value)
+
+	if percent, ok := strings.CutSuffix(value, "%"); ok {
+		n, err := strconv.Atoi(percent)
+
+		if err != nil || n <= 0 || n > 100 {
+			return BatchSize{}, fmt.Errorf("%w: batch size %q", ErrInvalidStrategy, value)
+		}
+
+		return BatchSize{Percent: n}, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n <= 0 {
+		return BatchSize{}, fmt.Errorf("%w: batch size %q", ErrInvalidStrategy, value)
+	}
+
+	return BatchSize{Count: n}, nil
+}
+
+// Of is the number of servers per batch for a stage of total servers, at
+// least one.
+func (b BatchSize) Of(total int) int {
+	size := b.Count
+
+	if b.Percent > 0 {
+		size = int(math.Ceil(float64(total) * float64(b.Percent) / 100))
+	}
+
+	if size < 1 {
+		size = 1
+	}
+
+	return size
+}
+
+// Strategy decides how the servers of a stage are split into batches and
+// when a rollout is aborted.
+type Strategy struct {
+	Name      string
+	BatchSize BatchSize
+	// MaxFailures is how many servers may fail before a rolling deploy is
+	// aborted.
+	MaxFailures int
+	// BakePeriod is how long canaries must stay healthy, HealthCheck the
+	// command run on them meanwhile.
+	BakePeriod  time.Duration
+	HealthCheck string
+}
+
+// PipelineStrategy reads the strategy configured on a pipeline. Pipelines
+// without one deploy all servers of a stage at once.
+func PipelineStrategy(pipeline models.Pipeline) (Strategy, error) {
+	switch pipeline.Strategy {
+	case "", StrategyAll:
+		return Strategy{Name: StrategyAll}, nil
+
+	case StrategyRolling:
+		size, err := ParseBatchSize(pipeline.BatchSize)
+
+		if err != nil {
+			return Strategy{}, err
+		}
+
+		if pipeline.MaxFailures < 0 {
+			return Strategy{}, fmt.Errorf("%w: max failures %d", ErrInvalidStrategy, pipeline.MaxFailures)
+		}
+
+		return Strategy{Name: StrategyRolling, BatchSize: size, MaxFailures: pipeline.MaxFailures}, nil
+
+	case StrategyCanary:
+		if pipeline.BakeMinutes < 0 {
+			return Strategy{}, fmt.Errorf("%w: bake minutes %d", ErrInvalidStrategy, pipeline.BakeMinutes)
+		}
+
+		bake := time.Duration(pipeline.BakeMinutes) * time.Minute
+		if bake == 0 {
+			bake = DefaultBakePeriod
+		}
+
+		return Strategy{Name: StrategyCanary, BakePeriod: bake, HealthCheck: strings.TrimSpace(pipeline.HealthCheck)}, nil
+	}
+
+	return Strategy{}, fmt.Errorf("%w: %q", ErrInvalidStrategy, pipeline.Strategy)
+}
+
+// Batches splits the servers of a stage in the order they are updated.
+func (s Strategy) Batches(servers []models.UpdateServer) [][]models.UpdateServer {
+	if len(servers) == 0 {
+		return nil
+	}
+
+	if s.Name != StrategyRolling {
+		return [][]models.UpdateServer{servers}
+	}
+
+	size := s.BatchSize.Of(len(servers))
+	batches := make([][]models.UpdateServer, 0, (len(servers)+size-1)/size)
+
+	for start := 0; start < len(servers); start += size {
+		end := min(start+size, len(servers))
+		batches = append(batches, servers[start:end])
+	}
+
+	return batches
+}
+
+// SplitCanaries separates the canary servers from the ones promoted after the
+// bake period, keeping their order.
+func SplitCanaries(servers []models.UpdateServer) (canaries []models.UpdateServer, rest []models.UpdateServer) {
+	for _, server := range servers {
+		if server.Canary {
+			canaries = append(canaries, server)
+		} else {
+			rest = append(rest, server)
+		}
+	}
+
+	return canaries, rest
+}
+
+// ShouldAbort reports whether the run stops after a batch, given the number
+// of servers that failed so far. Without a rolling strategy any failure stops
+// the stages that follow.
+func (s Strategy) ShouldAbort(failures int) bool {
+	if s.Name != StrategyRolling {
+		return failures > 0
+	}
+
+	return failures > s.MaxFailures
+}
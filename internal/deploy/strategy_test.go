@@ -0,0 +1,72 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func servers(n int) []models.UpdateServer {
+	result := make([]models.UpdateServer, n)
+	for i := range result {
+		result[i] = models.UpdateServer{ID: int64(i + 1)}
+	}
+	return result
+}
+
+func TestParseBatchSize(t *testing.T) {
+	size, err := ParseBatchSize("2")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, size.Of(10))
+
+	size, err = ParseBatchSize("25%")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, size.Of(10))
+	assert.Equal(t, 1, size.Of(2))
+
+	for _, value := range []string{"", "0", "-1", "abc", "0%", "150%"} {
+		_, err := ParseBatchSize(value)
+		assert.ErrorIs(t, err, ErrInvalidStrategy, value)
+	}
+}
+
+func TestPipelineStrategy(t *testing.T) {
+	strategy, err := PipelineStrategy(models.Pipeline{})
+	assert.NoError(t, err)
+	assert.Equal(t, StrategyAll, strategy.Name)
+	assert.Len(t, strategy.Batches(servers(5)), 1)
+	assert.True(t, strategy.ShouldAbort(1))
+
+	strategy, err = PipelineStrategy(models.Pipeline{Strategy: StrategyRolling, BatchSize: "2", MaxFailures: 1})
+	assert.NoError(t, err)
+
+	batches := strategy.Batches(servers(5))
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[2], 1)
+	assert.Equal(t, int64(5), batches[2][0].ID)
+	assert.False(t, strategy.ShouldAbort(1))
+	assert.True(t, strategy.ShouldAbort(2))
+
+	strategy, err = PipelineStrategy(models.Pipeline{Strategy: StrategyCanary, HealthCheck: " curl -f localhost "})
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultBakePeriod, strategy.BakePeriod)
+	assert.Equal(t, "curl -f localhost", strategy.HealthCheck)
+	assert.Len(t, strategy.Batches(servers(3)), 1)
+
+	_, err = PipelineStrategy(models.Pipeline{Strategy: StrategyRolling})
+	assert.ErrorIs(t, err, ErrInvalidStrategy)
+
+	_, err = PipelineStrategy(models.Pipeline{Strategy: "blue-green"})
+	assert.ErrorIs(t, err, ErrInvalidStrategy)
+}
+
+func TestSplitCanaries(t *testing.T) {
+	all := servers(4)
+	all[1].Canary = true
+	all[3].Canary = true
+
+	canaries, rest := SplitCanaries(all)
+	assert.Equal(t, []int64{2, 4}, []int64{canaries[0].ID, canaries[1].ID})
+	assert.Equal(t, []int64{1, 3}, []int64{rest[0].ID, rest[1].ID})
+}
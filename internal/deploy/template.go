@@ -0,0 +1,122 @@
+package deploy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"auto-update/internal/database/models"
+)
+
+// ScriptData is what server scripts can use when rendered, e.g.
+// {{.Branch}}, {{.Vars.IMAGE}} or {{.Params.PORT}} for library scripts.
+type ScriptData struct {
+	SHA      string
+	Branch   string
+	Tag      string
+	Pusher   string
+	Pipeline string
+	Server   string
+	RunID    int64
+	Vars     map[string]string
+	Params   map[string]string
+}
+
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidVariableName reports whether a variable can be used as {{.Vars.NAME}}.
+func ValidVariableName(name string) bool {
+	return variableName.MatchString(name)
+}
+
+// Variables splits the variables of a pipeline into its own and the
+// overrides of each server.
+func Variables(variables []models.Variable) (map[string]string, map[int64]map[string]string) {
+	pipeline := make(map[string]string)
+	servers := make(map[int64]map[string]string)
+
+	for _, variable := range variables {
+		if variable.ServerID == 0 {
+			pipeline[variable.Name] = variable.Value
+			continue
+		}
+
+		if servers[variable.ServerID] == nil {
+			servers[variable.ServerID] = make(map[string]string)
+		}
+		servers[variable.ServerID][variable.Name] = variable.Value
+	}
+
+	return pipeline, servers
+}
+
+// MergeVariables returns the pipeline variables with the server overrides
+// applied.
+func MergeVariables(pipeline map[string]string, server map[string]string) map[string]string {
+	merged := make(map[string]string, len(pipeline)+len(server))
+
+	for name, value := range pipeline {
+		merged[name] = value
+	}
+	for name, value := range server {
+		merged[name] = value
+	}
+
+	return merged
+}
+
+// RenderScript executes a script as a text/template. Unknown variables are an
+// error rather than an empty string, so a typo never reaches a server.
+func RenderScript(script string, data ScriptData) (string, error) {
+	if !strings.Contains(script, "{{") {
+		return script, nil
+	}
+
+	tmpl, err := template.New("script").Option("missingkey=error").Parse(script)
+
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+// RenderServers renders the deploy and rollback scripts of every server with
+// its own label and variables, failing on the first server that does not
+// render.
+func RenderServers(servers []models.UpdateServer, data ScriptData, variables []models.Variable) ([]models.UpdateServer, error) {
+	pipelineVars, serverVars := Variables(variables)
+	rendered := make([]models.UpdateServer, len(servers))
+
+	for i, server := range servers {
+		serverData := data
+		serverData.Server = server.Label
+		serverData.Vars = MergeVariables(pipelineVars, serverVars[server.ID])
+		serverData.Params = server.ScriptParams
+
+		script, err := RenderScript(server.Script, serverData)
+
+		if err != nil {
+			return nil, fmt.Errorf("script do servidor %s: %w", server.Label, err)
+		}
+
+		rollback, err := RenderScript(server.RollbackScript, serverData)
+
+		if err != nil {
+			return nil, fmt.Errorf("rollback do servidor %s: %w", server.Label, err)
+		}
+
+		server.Script = script
+		server.RollbackScript = rollback
+		rendered[i] = server
+	}
+
+	return rendered, nil
+}
@@ -0,0 +1,61 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderScript(t *testing.T) {
+	data := ScriptData{SHA: "abc123", Branch: "main", Tag: "v1.2.0", Pipeline: "api", Server: "web-1", RunID: 7, Vars: map[string]string{"IMAGE": "api"}}
+
+	out, err := RenderScript("deploy {{.Vars.IMAGE}}:{{.Tag}} {{.SHA}} on {{.Server}} ({{.Pipeline}} #{{.RunID}}, {{.Branch}})", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "deploy api:v1.2.0 abc123 on web-1 (api #7, main)", out)
+
+	out, err = RenderScript("echo plain", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "echo plain", out)
+
+	_, err = RenderScript("echo {{.Vars.MISSING}}", data)
+	assert.Error(t, err)
+
+	_, err = RenderScript("echo {{.Nope}}", data)
+	assert.Error(t, err)
+
+	_, err = RenderScript("echo {{", data)
+	assert.Error(t, err)
+}
+
+func TestRenderServers(t *testing.T) {
+	all := servers(2)
+	all[0].Label, all[1].Label = "web-1", "web-2"
+	all[0].Script = "run {{.Vars.ENV}} {{.Server}}"
+	all[1].Script = "run {{.Vars.ENV}} {{.Server}}"
+	all[1].RollbackScript = "undo {{.Vars.ENV}}"
+
+	variables := []models.Variable{
+		{Name: "ENV", Value: "prod"},
+		{ServerID: 2, Name: "ENV", Value: "canary"},
+	}
+
+	rendered, err := RenderServers(all, ScriptData{}, variables)
+	assert.NoError(t, err)
+	assert.Equal(t, "run prod web-1", rendered[0].Script)
+	assert.Equal(t, "run canary web-2", rendered[1].Script)
+	assert.Equal(t, "undo canary", rendered[1].RollbackScript)
+	assert.Equal(t, "run {{.Vars.ENV}} {{.Server}}", all[0].Script)
+
+	all[0].Script = "run {{.Vars.OTHER}}"
+	_, err = RenderServers(all, ScriptData{}, variables)
+	assert.ErrorContains(t, err, "web-1")
+}
+
+func TestValidVariableName(t *testing.T) {
+	assert.True(t, ValidVariableName("IMAGE_TAG"))
+	assert.True(t, ValidVariableName("_x1"))
+	assert.False(t, ValidVariableName("1x"))
+	assert.False(t, ValidVariableName("with-dash"))
+	assert.False(t, ValidVariableName(""))
+}
@@ -0,0 +1,31 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"time"
+)
+
+// DefaultServerTimeout is how long a server script may run when neither the
+// server nor its pipeline set a timeout.
+const DefaultServerTimeout = 500 * time.Second
+
+// WithTimeout gives the pipeline timeout to the servers that do not override
+// it.
+func WithTimeout(servers []models.UpdateServer, pipeline models.Pipeline) []models.UpdateServer {
+	for i := range servers {
+		if servers[i].TimeoutSeconds <= 0 {
+			servers[i].TimeoutSeconds = pipeline.TimeoutSeconds
+		}
+	}
+
+	return servers
+}
+
+// ServerTimeout is how long the script of a server may run.
+func ServerTimeout(server models.UpdateServer) time.Duration {
+	if server.TimeoutSeconds <= 0 {
+		return DefaultServerTimeout
+	}
+
+	return time.Duration(server.TimeoutSeconds) * time.Second
+}
@@ -0,0 +1,24 @@
+package deploy
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeout(t *testing.T) {
+	all := servers(2)
+	all[1].TimeoutSeconds = 30
+
+	all = WithTimeout(all, models.Pipeline{TimeoutSeconds: 120})
+
+	assert.Equal(t, 120, all[0].TimeoutSeconds)
+	assert.Equal(t, 30, all[1].TimeoutSeconds)
+}
+
+func TestServerTimeout(t *testing.T) {
+	assert.Equal(t, DefaultServerTimeout, ServerTimeout(models.UpdateServer{}))
+	assert.Equal(t, 90*time.Second, ServerTimeout(models.UpdateServer{TimeoutSeconds: 90}))
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   map[string]any
+}
+
+// fakeGithub answers like the GitHub API and records what it received.
+func fakeGithub(t *testing.T) (*httptest.Server, *[]recordedRequest) {
+	requests := make([]recordedRequest, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := make(map[string]any)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		requests = append(requests, recordedRequest{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Auth:   r.Header.Get("Authorization"),
+			Body:   body,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/repos/acme/missing/"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "Not Found"}`))
+		case r.URL.Path == "/repos/acme/web/deployments":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 42, "ref": "0d1a26e", "environment": "production"}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func TestDeploymentFlow(t *testing.T) {
+	server, requests := fakeGithub(t)
+
+	client := NewClient("token-123", WithBaseURL(server.URL+"/"), WithHTTPClient(server.Client()))
+	ctx := context.Background()
+
+	deployment, err := client.CreateDeployment(ctx, "acme/web", "0d1a26e", "production", "Deploy pipeline prod")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), deployment.ID)
+
+	assert.NoError(t, client.CreateDeploymentStatus(ctx, "acme/web", deployment.ID, StateInProgress, "https://deploy.example.com/updates?id=7", "Deploying"))
+	assert.NoError(t, client.CreateCommitStatus(ctx, "acme/web", "0d1a26e", StateInProgress, "https://deploy.example.com/updates?id=7", "Deploying", "deploy/prod"))
+
+	assert.Len(t, *requests, 3)
+
+	first := (*requests)[0]
+	assert.Equal(t, http.MethodPost, first.Method)
+	assert.Equal(t, "/repos/acme/web/deployments", first.Path)
+	assert.Equal(t, "Bearer token-123", first.Auth)
+	assert.Equal(t, "0d1a26e", first.Body["ref"])
+	assert.Equal(t, false, first.Body["auto_merge"])
+
+	status := (*requests)[1]
+	assert.Equal(t, "/repos/acme/web/deployments/42/statuses", status.Path)
+	assert.Equal(t, StateInProgress, status.Body["state"])
+	assert.Equal(t, "https://deploy.example.com/updates?id=7", status.Body["log_url"])
+
+	commit := (*requests)[2]
+	assert.Equal(t, "/repos/acme/web/statuses/0d1a26e", commit.Path)
+	assert.Equal(t, StatePending, commit.Body["state"])
+	assert.Equal(t, "deploy/prod", commit.Body["context"])
+}
+
+func TestClientError(t *testing.T) {
+	server, _ := fakeGithub(t)
+
+	client := NewClient("token-123", WithBaseURL(server.URL), WithHTTPClient(server.Client()))
+
+	_, err := client.CreateDeployment(context.Background(), "acme/missing", "master", "production", "")
+
+	var apiError *Error
+	assert.ErrorAs(t, err, &apiError)
+	assert.Equal(t, http.StatusNotFound, apiError.StatusCode)
+	assert.Equal(t, "Not Found", apiError.Message)
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short"))
+	assert.Len(t, []rune(truncate(strings.Repeat("á", 200))), maxDescription)
+}
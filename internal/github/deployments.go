@@ -0,0 +1,96 @@
+package github
+
+import (
+	"context"
+	"fmt"
+)
+
+// Deployment states accepted by the deployment statuses API.
+const (
+	StatePending    = "pending"
+	StateInProgress = "in_progress"
+	StateSuccess    = "success"
+	StateFailure    = "failure"
+	StateError      = "error"
+)
+
+// maxDescription is the longest description GitHub accepts on a status.
+const maxDescription = 140
+
+type DeploymentRequest struct {
+	Ref              string   `json:"ref"`
+	Environment      string   `json:"environment"`
+	Description      string   `json:"description"`
+	AutoMerge        bool     `json:"auto_merge"`
+	RequiredContexts []string `json:"required_contexts"`
+}
+
+type Deployment struct {
+	ID          int64  `json:"id"`
+	Ref         string `json:"ref"`
+	Environment string `json:"environment"`
+}
+
+type DeploymentStatusRequest struct {
+	State       string `json:"state"`
+	LogURL      string `json:"log_url,omitempty"`
+	Description string `json:"description"`
+	Environment string `json:"environment,omitempty"`
+}
+
+type CommitStatusRequest struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description"`
+	Context     string `json:"context"`
+}
+
+// CreateDeployment creates a deployment of ref, a sha, branch or tag, in
+// repository given as "owner/name". Required status checks are not enforced
+// because the deploy has already been decided by the time it is reported.
+func (c *Client) CreateDeployment(ctx context.Context, repository string, ref string, environment string, description string) (Deployment, error) {
+	var deployment Deployment
+
+	err := c.post(ctx, fmt.Sprintf("/repos/%s/deployments", repository), DeploymentRequest{
+		Ref:              ref,
+		Environment:      environment,
+		Description:      truncate(description),
+		AutoMerge:        false,
+		RequiredContexts: []string{},
+	}, &deployment)
+
+	return deployment, err
+}
+
+func (c *Client) CreateDeploymentStatus(ctx context.Context, repository string, deploymentID int64, state string, logURL string, description string) error {
+	return c.post(ctx, fmt.Sprintf("/repos/%s/deployments/%d/statuses", repository, deploymentID), DeploymentStatusRequest{
+		State:       state,
+		LogURL:      logURL,
+		Description: truncate(description),
+	}, nil)
+}
+
+// CreateCommitStatus posts a status on a commit. Commit statuses have no
+// in_progress state, it is reported as pending.
+func (c *Client) CreateCommitStatus(ctx context.Context, repository string, sha string, state string, targetURL string, description string, statusContext string) error {
+	if state == StateInProgress {
+		state = StatePending
+	}
+
+	return c.post(ctx, fmt.Sprintf("/repos/%s/statuses/%s", repository, sha), CommitStatusRequest{
+		State:       state,
+		TargetURL:   targetURL,
+		Description: truncate(description),
+		Context:     statusContext,
+	}, nil)
+}
+
+func truncate(description string) string {
+	runes := []rune(description)
+
+	if len(runes) <= maxDescription {
+		return description
+	}
+
+	return string(runes[:maxDescription-3]) + "..."
+}
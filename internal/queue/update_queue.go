@@ -3,11 +3,21 @@ package queue
 import (
 	"auto-update/internal/sshclient"
 	"fmt"
+	"sync"
 )
 
 type UpdateQueue struct {
 	updateChannel  chan *sshclient.UpdateOptions
 	workingChannel chan bool
+
+	// removed holds the updates dropped from the queue, skipped once the
+	// worker reaches them.
+	removedMu sync.Mutex
+	removed   map[int64]bool
+
+	// finished is called with every pipeline run the worker completes, see
+	// OnFinished.
+	finished func(*sshclient.UpdateOptions)
 }
 
 var sshClientService = sshclient.NewSshClientService()
@@ -19,6 +29,7 @@ func NewUpdateQueue() *UpdateQueue {
 	return &UpdateQueue{
 		updateChannel:  updateChannel,
 		workingChannel: workingChannel,
+		removed:        make(map[int64]bool),
 	}
 }
 
@@ -29,12 +40,26 @@ func (e *UpdateQueue) Work() {
 	for {
 		select {
 		case id := <-e.updateChannel:
+			if e.takeRemoved(id.ID) {
+				fmt.Println("Skipping removed update", id.ID)
+				continue
+			}
+
 			fmt.Println("Starting queue worker updating repository")
 			// Enqueue message to workingChannel to avoid miscalculation in queue size.
 			e.workingChannel <- true
 
 			fmt.Println("testeeeeeee", id)
-			sshClientService.UpdateRepository(id)
+			if id.PipelineID != 0 {
+				sshClientService.RunPipeline(id)
+
+				// The worker is the only consumer, enqueue without blocking it.
+				if e.finished != nil {
+					go e.finished(id)
+				}
+			} else {
+				sshClientService.UpdateRepository(id)
+			}
 			fmt.Println("Finish queue worker updating repository")
 
 			<-e.workingChannel
@@ -52,3 +77,30 @@ func (e *UpdateQueue) Enqueue(options *sshclient.UpdateOptions) {
 	fmt.Println("Enqueue:", options)
 	e.updateChannel <- options
 }
+
+// OnFinished sets what runs once the worker completed a pipeline run, such as
+// starting the pipelines it chains. It may enqueue new runs.
+func (e *UpdateQueue) OnFinished(finished func(*sshclient.UpdateOptions)) {
+	e.finished = finished
+}
+
+// Remove drops the queued run of an update.
+func (e *UpdateQueue) Remove(id int64) {
+	e.removedMu.Lock()
+	defer e.removedMu.Unlock()
+
+	e.removed[id] = true
+}
+
+func (e *UpdateQueue) takeRemoved(id int64) bool {
+	e.removedMu.Lock()
+	defer e.removedMu.Unlock()
+
+	if id == 0 || !e.removed[id] {
+		return false
+	}
+
+	delete(e.removed, id)
+
+	return true
+}
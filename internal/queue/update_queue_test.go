@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"auto-update/internal/sshclient"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemove(t *testing.T) {
+	q := NewUpdateQueue()
+	q.Enqueue(&sshclient.UpdateOptions{ID: 1, PipelineID: 1})
+	q.Enqueue(&sshclient.UpdateOptions{ID: 2, PipelineID: 1})
+
+	q.Remove(1)
+
+	// Removed updates stay in the channel until the worker skips them.
+	assert.Equal(t, 2, q.Size())
+
+	assert.True(t, q.takeRemoved(1))
+	// A removed update is skipped once.
+	assert.False(t, q.takeRemoved(1))
+	assert.False(t, q.takeRemoved(2))
+	// Repository updates have no id and are never removed.
+	q.Remove(0)
+	assert.False(t, q.takeRemoved(0))
+}
@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Catch-up policies for the runs a schedule missed while the server was down.
+const (
+	// CatchUpSkip drops missed runs, only a run due within Grace still starts.
+	CatchUpSkip = "skip"
+	// CatchUpOnce starts a single run for any number of missed ones.
+	CatchUpOnce = "once"
+	// CatchUpAll starts every missed run, up to MaxCatchUp.
+	CatchUpAll = "all"
+)
+
+const (
+	// Grace is how late a run can start and still count as on time.
+	Grace = 5 * time.Minute
+	// MaxCatchUp caps how many missed runs CatchUpAll starts.
+	MaxCatchUp = 24
+)
+
+var ErrInvalidCatchUp = errors.New("invalid catch-up policy")
+
+// ValidCatchUp reports whether policy is a known catch-up policy, "" being
+// CatchUpSkip.
+func ValidCatchUp(policy string) error {
+	switch policy {
+	case "", CatchUpSkip, CatchUpOnce, CatchUpAll:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %q", ErrInvalidCatchUp, policy)
+}
+
+// Due returns how many runs of a schedule whose next run was at next should
+// start at now under the catch-up policy, and when the schedule runs next.
+func Due(cron Cron, next time.Time, now time.Time, policy string) (int, time.Time) {
+	if now.Before(next) {
+		return 0, next
+	}
+
+	missed := make([]time.Time, 0)
+
+	for occurrence := next; !occurrence.IsZero() && !occurrence.After(now); occurrence = cron.Next(occurrence) {
+		missed = append(missed, occurrence)
+	}
+
+	following := cron.Next(now.In(next.Location()))
+
+	switch policy {
+	case CatchUpAll:
+		return min(len(missed), MaxCatchUp), following
+	case CatchUpOnce:
+		return 1, following
+	}
+
+	if now.Sub(missed[len(missed)-1]) <= Grace {
+		return 1, following
+	}
+
+	return 0, following
+}
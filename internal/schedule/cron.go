@@ -0,0 +1,223 @@
+// Package schedule parses cron expressions and decides which scheduled runs
+// are due.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// maxSearch bounds how far Next looks for a matching time, so expressions
+// that never match (like February 30th) do not loop forever.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Cron is a parsed five field expression: minute, hour, day of month, month
+// and day of week (0 or 7 is Sunday).
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAll and dowAll keep the standard rule that when both days are
+	// restricted a time matches either of them.
+	domAll, dowAll bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse reads a cron expression such as "30 2 * * 1-5" or "@daily".
+func Parse(expr string) (Cron, error) {
+	expr = strings.TrimSpace(expr)
+
+	if macro, ok := macros[expr]; ok {
+		expr = macro
+	}
+
+	parts := strings.Fields(expr)
+
+	if len(parts) != len(fields) {
+		return Cron{}, fmt.Errorf("%w: expected %d fields, got %d", ErrInvalidCron, len(fields), len(parts))
+	}
+
+	sets := make([]uint64, len(fields))
+
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+
+		if err != nil {
+			return Cron{}, err
+		}
+
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return Cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAll: parts[2] == "*",
+		dowAll: parts[4] == "*",
+	}, nil
+}
+
+func parseField(value string, f field) (uint64, error) {
+	var set uint64
+
+	for _, item := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: %s step %q", ErrInvalidCron, f.name, stepPart)
+			}
+
+			step = n
+		}
+
+		start, end := f.min, f.max
+
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+
+			var err error
+			if start, err = parseValue(from, f); err != nil {
+				return 0, err
+			}
+
+			end = start
+			if isRange {
+				if end, err = parseValue(to, f); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				end = f.max
+			}
+
+			if end < start {
+				return 0, fmt.Errorf("%w: %s range %q", ErrInvalidCron, f.name, rangePart)
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+
+	return set, nil
+}
+
+func parseValue(value string, f field) (int, error) {
+	n, err := strconv.Atoi(value)
+
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%w: %s %q", ErrInvalidCron, f.name, value)
+	}
+
+	return n, nil
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
+
+func (c Cron) dayMatches(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+
+	if c.domAll || c.dowAll {
+		return dom && dow
+	}
+
+	return dom || dow
+}
+
+// Next returns the first time after t, in t's location, that matches the
+// expression, or the zero time when none does in the next years.
+func (c Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(c.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// NextRun parses expr and returns its first run after t in the timezone, ""
+// being UTC.
+func NextRun(expr string, timezone string, after time.Time) (time.Time, error) {
+	cron, err := Parse(expr)
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	next := cron.Next(after.In(loc))
+
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: %q never runs", ErrInvalidCron, expr)
+	}
+
+	return next, nil
+}
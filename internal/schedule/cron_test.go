@@ -0,0 +1,99 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(t *testing.T, expr string) Cron {
+	cron, err := Parse(expr)
+	assert.NoError(t, err)
+	return cron
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := Parse(expr)
+		assert.ErrorIs(t, err, ErrInvalidCron, expr)
+	}
+}
+
+func TestNext(t *testing.T) {
+	start := time.Date(2024, 7, 26, 10, 17, 30, 0, time.UTC)
+
+	cases := map[string]time.Time{
+		"* * * * *":        time.Date(2024, 7, 26, 10, 18, 0, 0, time.UTC),
+		"*/15 * * * *":     time.Date(2024, 7, 26, 10, 30, 0, 0, time.UTC),
+		"0 2 * * *":        time.Date(2024, 7, 27, 2, 0, 0, 0, time.UTC),
+		"@daily":           time.Date(2024, 7, 27, 0, 0, 0, 0, time.UTC),
+		"30 3 * * 1":       time.Date(2024, 7, 29, 3, 30, 0, 0, time.UTC),
+		"0 9 * * 1-5":      time.Date(2024, 7, 29, 9, 0, 0, 0, time.UTC),
+		"0 0 1 * *":        time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":        time.Date(2024, 7, 28, 0, 0, 0, 0, time.UTC),
+		"0 12 15 * 5":      time.Date(2024, 7, 26, 12, 0, 0, 0, time.UTC),
+		"5,10 10,11 * * *": time.Date(2024, 7, 26, 11, 5, 0, 0, time.UTC),
+	}
+
+	for expr, want := range cases {
+		assert.Equal(t, want, mustParse(t, expr).Next(start), expr)
+	}
+
+	assert.True(t, mustParse(t, "0 0 30 2 *").Next(start).IsZero())
+}
+
+func TestNextTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	next := mustParse(t, "0 2 * * *").Next(time.Date(2024, 7, 26, 12, 0, 0, 0, loc))
+
+	assert.Equal(t, time.Date(2024, 7, 27, 2, 0, 0, 0, loc), next)
+	assert.Equal(t, time.Date(2024, 7, 27, 5, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestDue(t *testing.T) {
+	hourly := mustParse(t, "0 * * * *")
+	next := time.Date(2024, 7, 26, 10, 0, 0, 0, time.UTC)
+
+	runs, following := Due(hourly, next, next.Add(-time.Minute), CatchUpSkip)
+	assert.Equal(t, 0, runs)
+	assert.Equal(t, next, following)
+
+	runs, following = Due(hourly, next, next.Add(time.Minute), CatchUpSkip)
+	assert.Equal(t, 1, runs)
+	assert.Equal(t, next.Add(time.Hour), following)
+
+	// Down from 10:00 to 13:30.
+	now := next.Add(3*time.Hour + 30*time.Minute)
+
+	runs, following = Due(hourly, next, now, CatchUpSkip)
+	assert.Equal(t, 0, runs)
+	assert.Equal(t, time.Date(2024, 7, 26, 14, 0, 0, 0, time.UTC), following)
+
+	runs, _ = Due(hourly, next, now, CatchUpOnce)
+	assert.Equal(t, 1, runs)
+
+	runs, _ = Due(hourly, next, now, CatchUpAll)
+	assert.Equal(t, 4, runs)
+
+	runs, _ = Due(hourly, next, next.Add(100*time.Hour), CatchUpAll)
+	assert.Equal(t, MaxCatchUp, runs)
+}
+
+func TestNextRun(t *testing.T) {
+	after := time.Date(2024, 7, 26, 10, 17, 0, 0, time.UTC)
+
+	next, err := NextRun("0 * * * *", "", after)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 7, 26, 11, 0, 0, 0, time.UTC), next)
+
+	_, err = NextRun("0 * * * *", "Mars/Olympus", after)
+	assert.Error(t, err)
+
+	_, err = NextRun("0 0 31 2 *", "UTC", after)
+	assert.ErrorIs(t, err, ErrInvalidCron)
+}
@@ -0,0 +1,363 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	notification "auto-update/internal/notifications"
+	"auto-update/internal/sshclient"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// approvalSweepPeriod is how often runs awaiting approval are checked for
+// expiry.
+const approvalSweepPeriod = time.Minute
+
+type ApproversInfo struct {
+	UserIDs []int64 `json:"user_ids"`
+}
+
+type ApprovalInfo struct {
+	Comment string `json:"comment"`
+}
+
+// requestApproval records a manual run awaiting approval and notifies the
+// approvers of the pipeline.
+func (s *Server) requestApproval(pipeline models.Pipeline, options *sshclient.UpdateOptions, run database.Update) (int64, error) {
+	deadline := deploy.ApprovalDeadline(pipeline.ApprovalTimeoutMinutes, time.Now())
+
+	run.Status = database.UpdateStatusAwaitingApproval
+	run.Message = approvalMessage(pipeline)
+	run.ApprovalDeadline = &deadline
+
+	id, err := s.createRun(options, run)
+
+	if err != nil {
+		return 0, err
+	}
+
+	s.notifyApprovalRequest(pipeline, id, options.Pusher, deadline)
+
+	return id, nil
+}
+
+// awaitApproval holds an update released by the gate it was in, from, for
+// approval when its pipeline requires it and it was not approved yet. It
+// reports whether the update is held, false also when it left from
+// meanwhile.
+func (s *Server) awaitApproval(pipeline models.Pipeline, update database.Update, from string) (bool, error) {
+	if pipeline.RequiredApprovals <= 0 {
+		return false, nil
+	}
+
+	approvals, err := s.db.ListRunApprovals(update.ID)
+
+	if err != nil {
+		return false, err
+	}
+
+	if approved, rejected := deploy.CountApprovals(approvals); approved >= pipeline.RequiredApprovals && !rejected {
+		return false, nil
+	}
+
+	deadline := deploy.ApprovalDeadline(pipeline.ApprovalTimeoutMinutes, time.Now())
+
+	held, err := s.db.AwaitApprovalUpdate(update.ID, from, approvalMessage(pipeline), deadline)
+
+	if err != nil || !held {
+		return false, err
+	}
+
+	s.notifyApprovalRequest(pipeline, update.ID, update.PusherName, deadline)
+
+	return true, nil
+}
+
+func approvalMessage(pipeline models.Pipeline) string {
+	return fmt.Sprintf("aguardando %d aprovação(ões)", pipeline.RequiredApprovals)
+}
+
+func (s *Server) notifyApprovalRequest(pipeline models.Pipeline, id int64, pusher string, deadline time.Time) {
+	msg := fmt.Sprintf("Aprovação necessária na pipeline: *%s* (execução %d solicitada por %s). São necessárias %d aprovação(ões) até %s.",
+		pipeline.Name, id, pusher, pipeline.RequiredApprovals, deadline.Format(time.RFC3339))
+
+	s.notifyApprovers(pipeline.ID, msg, "yellow")
+}
+
+// notifyApprovers sends a message to every approver of a pipeline.
+func (s *Server) notifyApprovers(pipelineId int64, msg string, color string) {
+	approvers, err := s.db.ListPipelineApprovers(pipelineId)
+
+	if err != nil {
+		return
+	}
+
+	notificationService := notification.NewNotificationService()
+
+	for _, approver := range approvers {
+		if err := notificationService.SendAllNotifications(msg, approver, color); err != nil {
+			slog.Error("error ao enviar notificação", "user_id", approver, "error", err)
+		}
+	}
+}
+
+func (s *Server) GetPipelineApproversHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	approvers, err := s.db.ListPipelineApprovers(id)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting approvers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, approvers)
+}
+
+// SetPipelineApproversHandler replaces the users that can approve the runs of
+// a pipeline.
+func (s *Server) SetPipelineApproversHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	info := new(ApproversInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	for _, userId := range info.UserIDs {
+		if _, err := s.db.GetUserByID(userId); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "user not found: " + strconv.FormatInt(userId, 10),
+			})
+		}
+	}
+
+	if err := s.db.SetPipelineApprovers(id, info.UserIDs); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving approvers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// ApproveRunHandler records the approval of the logged user and starts the
+// run once the pipeline has enough distinct approvals.
+func (s *Server) ApproveRunHandler(c echo.Context) error {
+	return s.decideRun(c, models.ApprovalApproved)
+}
+
+// RejectRunHandler rejects a run awaiting approval, it never starts.
+func (s *Server) RejectRunHandler(c echo.Context) error {
+	return s.decideRun(c, models.ApprovalRejected)
+}
+
+func (s *Server) decideRun(c echo.Context, decision string) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	info := new(ApprovalInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	update, err := s.db.GetUpdate(id)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "run not found",
+		})
+	}
+
+	if update.Status != database.UpdateStatusAwaitingApproval {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": deploy.ErrNotAwaiting.Error(),
+		})
+	}
+
+	approvers, err := s.db.ListPipelineApprovers(update.PipelineID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting approvers",
+		})
+	}
+
+	if err := deploy.CheckApprover(approvers, update.RequestedBy, loggedUserId, update.ApprovalDeadline, time.Now()); err != nil {
+		status := http.StatusForbidden
+		if errors.Is(err, deploy.ErrApprovalEnded) {
+			status = http.StatusConflict
+		}
+
+		return c.JSON(status, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	err = s.db.CreateRunApproval(&models.RunApproval{
+		UpdateID: update.ID,
+		UserID:   loggedUserId,
+		Decision: decision,
+		Comment:  info.Comment,
+	})
+
+	if errors.Is(err, database.ErrAlreadyDecided) {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving approval",
+		})
+	}
+
+	pipeline, err := s.db.GetPipeline(update.PipelineID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting pipeline",
+		})
+	}
+
+	approvals, err := s.db.ListRunApprovals(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting approvals",
+		})
+	}
+
+	approved, rejected := deploy.CountApprovals(approvals)
+	status := database.UpdateStatusAwaitingApproval
+
+	switch {
+	case rejected:
+		user, _ := s.db.GetUserByID(loggedUserId)
+		released, err := s.db.ReleaseApprovalUpdate(update.ID, models.ApprovalRejected, "rejeitada por "+user.Name)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error rejecting run",
+			})
+		}
+
+		if released {
+			status = models.ApprovalRejected
+			msg := fmt.Sprintf("Execução %d da pipeline *%s* rejeitada por %s", update.ID, pipeline.Name, user.Name)
+			s.notifyApprovers(pipeline.ID, msg, "red")
+			s.notifyOwner(pipeline.UserID, msg, "red")
+		}
+
+	case approved >= pipeline.RequiredApprovals:
+		next, message := "pending", "aprovada"
+
+		// Approved runs still wait for a freeze in effect to end.
+		_, freezeStatus, freezeReason, frozen := s.freezeStatus(pipeline)
+		if frozen {
+			next, message = freezeStatus, freezeReason
+		}
+
+		released, err := s.db.ReleaseApprovalUpdate(update.ID, next, message)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error starting run",
+			})
+		}
+
+		// Concurrent approvals only start the run once.
+		if released {
+			status = next
+
+			if !frozen {
+				s.launchRun(ciUpdateOptions(update))
+			}
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"message":   "ok",
+		"status":    status,
+		"approvals": approved,
+		"required":  pipeline.RequiredApprovals,
+	})
+}
+
+func (s *Server) notifyOwner(userId int64, msg string, color string) {
+	if err := notification.NewNotificationService().SendAllNotifications(msg, userId, color); err != nil {
+		slog.Error("error ao enviar notificação", "user_id", userId, "error", err)
+	}
+}
+
+// expireApprovals cancels the runs that were not approved before their
+// deadline. It runs for the lifetime of the server.
+func (s *Server) expireApprovals() {
+	ticker := time.NewTicker(approvalSweepPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		updates, err := s.db.ListExpiredApprovalUpdates()
+
+		if err != nil {
+			continue
+		}
+
+		for _, update := range updates {
+			released, err := s.db.ReleaseApprovalUpdate(update.ID, "cancelled", "cancelled: approval expired")
+
+			if err != nil {
+				slog.Error("Error cancelling update awaiting approval", "update_id", update.ID, "error", err)
+				continue
+			}
+
+			if !released {
+				continue
+			}
+
+			slog.Info("Cancelled update awaiting approval", "update_id", update.ID)
+
+			if pipeline, err := s.db.GetPipeline(update.PipelineID); err == nil {
+				msg := fmt.Sprintf("Execução %d da pipeline *%s* cancelada: aprovação expirou", update.ID, pipeline.Name)
+				s.notifyOwner(pipeline.UserID, msg, "red")
+			}
+		}
+	}
+}
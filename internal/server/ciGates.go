@@ -0,0 +1,174 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/sshclient"
+	"auto-update/internal/webhooks"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	defaultCITimeout  = 60 * time.Minute
+	ciGateSweepPeriod = time.Minute
+)
+
+func ciDeadline(timeoutMinutes int) time.Time {
+	timeout := defaultCITimeout
+
+	if timeoutMinutes > 0 {
+		timeout = time.Duration(timeoutMinutes) * time.Minute
+	}
+
+	return time.Now().Add(timeout)
+}
+
+func ciUpdateOptions(update database.Update) *sshclient.UpdateOptions {
+	return &sshclient.UpdateOptions{
+		ID:         update.ID,
+		PipelineID: update.PipelineID,
+		Tag:        update.Tag,
+		Branch:     update.Branch,
+		SHA:        update.SHA,
+		Pusher:     update.PusherName,
+		Only:       webhooks.ParseDirective(update.Directive).Only,
+	}
+}
+
+// resolveCIGates releases the updates waiting for CI on the event's commit
+// when the run succeeded, through the approval and freeze gates of their
+// pipeline, and cancels them when it failed. pipelineId scopes a
+// delivery to a pipeline's own endpoint like in dispatchWebhookEvent.
+func (s *Server) resolveCIGates(event webhooks.Event, pipelineId int64) ([]TriggeredPipeline, []SkippedPipeline, error) {
+	released := make([]TriggeredPipeline, 0)
+	cancelled := make([]SkippedPipeline, 0)
+
+	if !event.CIPassed() && !event.CIFailed() {
+		return released, cancelled, nil
+	}
+
+	updates, err := s.db.ListWaitingCIUpdates(event.Repository, event.SHA)
+
+	if err != nil {
+		return released, cancelled, err
+	}
+
+	for _, update := range updates {
+		if pipelineId != 0 && update.PipelineID != pipelineId {
+			continue
+		}
+
+		if pipelineId == 0 {
+			if _, err := s.db.GetPipelineWebhook(update.PipelineID); err == nil {
+				continue
+			}
+		}
+
+		if event.CIFailed() {
+			reason := fmt.Sprintf("cancelled: CI %s concluded with %s", event.Message, event.Conclusion)
+
+			ok, err := s.db.ReleaseCIUpdate(update.ID, "cancelled", reason)
+
+			if err != nil {
+				return released, cancelled, err
+			}
+
+			if ok {
+				cancelled = append(cancelled, SkippedPipeline{
+					PipelineID: update.PipelineID,
+					Reason:     reason,
+					UpdateID:   update.ID,
+				})
+			}
+
+			continue
+		}
+
+		pipeline, err := s.db.GetPipeline(update.PipelineID)
+
+		if err != nil {
+			slog.Error("Error getting pipeline of update waiting for CI", "update_id", update.ID, "error", err)
+			continue
+		}
+
+		// A passed CI run still needs the approvals of the pipeline, the
+		// freeze is checked once they are given.
+		held, err := s.awaitApproval(pipeline, update, database.UpdateStatusWaitingCI)
+
+		if err != nil {
+			return released, cancelled, err
+		}
+
+		if held {
+			released = append(released, TriggeredPipeline{
+				PipelineID:       update.PipelineID,
+				Name:             pipeline.Name,
+				UpdateID:         update.ID,
+				AwaitingApproval: true,
+			})
+			continue
+		}
+
+		if _, status, reason, frozen := s.freezeStatus(pipeline); frozen {
+			ok, err := s.db.ReleaseCIUpdate(update.ID, status, reason)
+
+			if err != nil {
+				return released, cancelled, err
+			}
+
+			if ok {
+				cancelled = append(cancelled, SkippedPipeline{
+					PipelineID: update.PipelineID,
+					Reason:     reason,
+					UpdateID:   update.ID,
+				})
+			}
+
+			continue
+		}
+
+		ok, err := s.db.ReleaseCIUpdate(update.ID, "pending", "in queue")
+
+		if err != nil {
+			return released, cancelled, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		s.queue.Enqueue(ciUpdateOptions(update))
+
+		released = append(released, TriggeredPipeline{
+			PipelineID: update.PipelineID,
+			UpdateID:   update.ID,
+		})
+	}
+
+	return released, cancelled, nil
+}
+
+// expireCIGates cancels the updates whose CI run did not complete before
+// their deadline. It runs for the lifetime of the server.
+func (s *Server) expireCIGates() {
+	ticker := time.NewTicker(ciGateSweepPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		updates, err := s.db.ListExpiredCIUpdates()
+
+		if err != nil {
+			continue
+		}
+
+		for _, update := range updates {
+			if _, err := s.db.ReleaseCIUpdate(update.ID, "cancelled", "cancelled: timed out waiting for CI"); err != nil {
+				slog.Error("Error cancelling update waiting for CI", "update_id", update.ID, "error", err)
+				continue
+			}
+
+			slog.Info("Cancelled update waiting for CI", "update_id", update.ID, "sha", update.SHA)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/webhooks"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// userDelivery loads the :id delivery when it was sent to a pipeline of the
+// logged user, or the user is a webhook admin, writing the error response
+// when it was not.
+func (s *Server) userDelivery(c echo.Context) (models.WebhookDelivery, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.WebhookDelivery{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.WebhookDelivery{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	delivery, err := s.db.GetWebhookDelivery(id)
+
+	if err == nil {
+		var admin bool
+		admin, err = s.db.HasPermission(loggedUserId, models.PermissionWebhookAdmin)
+
+		if err == nil && !admin {
+			_, err = s.db.GetUserPipelineById(delivery.PipelineID, loggedUserId)
+		}
+	}
+
+	if err != nil {
+		return models.WebhookDelivery{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "delivery not found",
+		})
+	}
+
+	return delivery, true, nil
+}
+
+// ListDeliveriesHandler lists the deliveries sent to the pipelines of the
+// logged user, every delivery for webhook admins.
+func (s *Server) ListDeliveriesHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	admin, err := s.db.HasPermission(loggedUserId, models.PermissionWebhookAdmin)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	page, err := strconv.Atoi(c.QueryParam("page"))
+
+	if err != nil || page <= 0 {
+		page = 1
+	}
+
+	var deliveries []models.WebhookDelivery
+
+	if admin {
+		deliveries, err = s.db.ListWebhookDeliveries(limit, (page-1)*limit)
+	} else {
+		deliveries, err = s.db.ListUserWebhookDeliveries(loggedUserId, limit, (page-1)*limit)
+	}
+
+	if err != nil {
+		slog.Error("Error getting deliveries", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting deliveries",
+		})
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func (s *Server) GetDeliveryHandler(c echo.Context) error {
+	delivery, ok, err := s.userDelivery(c)
+
+	if !ok {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverHandler replays a stored delivery through the trigger rules. The
+// replay is logged as a new delivery pointing back at the original one.
+func (s *Server) RedeliverHandler(c echo.Context) error {
+	delivery, ok, err := s.userDelivery(c)
+
+	if !ok {
+		return err
+	}
+
+	if !delivery.SignatureValid {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "delivery had an invalid signature and can not be redelivered",
+		})
+	}
+
+	provider, ok := webhooks.GetProvider(delivery.Provider)
+
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "unknown webhook provider",
+		})
+	}
+
+	header := http.Header{}
+
+	if err := json.Unmarshal(delivery.Headers, &header); err != nil {
+		slog.Error("Error reading stored delivery headers", "delivery", delivery.ID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error reading delivery headers",
+		})
+	}
+
+	redeliveryId, err := s.db.CreateWebhookDelivery(&models.WebhookDelivery{
+		DeliveryID:     uuid.NewString(),
+		Provider:       delivery.Provider,
+		EventType:      delivery.EventType,
+		Headers:        delivery.Headers,
+		Body:           delivery.Body,
+		SignatureValid: true,
+		Decision:       "received",
+		RedeliveryOf:   delivery.ID,
+		PipelineID:     delivery.PipelineID,
+	})
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error recording delivery",
+		})
+	}
+
+	result := s.routeWebhook(provider, header, []byte(delivery.Body), delivery.PipelineID)
+
+	if err := s.db.UpdateWebhookDeliveryDecision(redeliveryId, result.Decision, result.updateIds()); err != nil {
+		slog.Error("Error recording delivery decision", "delivery", redeliveryId, "error", err)
+	}
+
+	return c.JSON(result.Status, echo.Map{
+		"message":     result.Message,
+		"delivery_id": redeliveryId,
+		"pipelines":   result.Triggered,
+	})
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// postGithub sends a GitHub delivery to the shared webhook endpoint, signed
+// with secret.
+func postGithub(t *testing.T, s *Server, event, deliveryId, body, secret string) *http.Response {
+	t.Setenv("SECRET_KEY", testWebhookSecret)
+
+	c, rec := newContext(http.MethodPost, "/github-webhook", body, 0)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	c.Request().Header.Set("X-GitHub-Event", event)
+	c.Request().Header.Set("X-GitHub-Delivery", deliveryId)
+	c.Request().Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	require.NoError(t, s.GithubWebhookHandler(c))
+
+	return rec.Result()
+}
+
+func responseMessage(t *testing.T, res *http.Response) string {
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+
+	message, _ := body["message"].(string)
+
+	return message
+}
+
+func TestWebhookDeliveryRecorded(t *testing.T) {
+	db := newFakeDB()
+	s := &Server{db: db}
+
+	res := postGithub(t, s, "ping", "guid-1", `{}`, testWebhookSecret)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, db.deliveries, 1)
+	assert.Equal(t, "guid-1", db.deliveries[0].DeliveryID)
+	assert.Equal(t, "github", db.deliveries[0].Provider)
+	assert.Equal(t, "ping", db.deliveries[0].EventType)
+	assert.True(t, db.deliveries[0].SignatureValid)
+	assert.True(t, db.deliveries[0].Claimed)
+	assert.Equal(t, "ignored: ping event is not routable", db.deliveries[0].Decision)
+}
+
+func TestWebhookDuplicateDelivery(t *testing.T) {
+	db := newFakeDB()
+	s := &Server{db: db}
+
+	postGithub(t, s, "ping", "guid-1", `{}`, testWebhookSecret)
+	res := postGithub(t, s, "ping", "guid-1", `{}`, testWebhookSecret)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "duplicate delivery ignored", responseMessage(t, res))
+	assert.Len(t, db.deliveries, 1)
+}
+
+func TestWebhookInvalidSignatureDoesNotClaimDelivery(t *testing.T) {
+	db := newFakeDB()
+	s := &Server{db: db}
+
+	res := postGithub(t, s, "ping", "guid-1", `{}`, "wrong-secret")
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.Len(t, db.deliveries, 1)
+	assert.False(t, db.deliveries[0].SignatureValid)
+	assert.False(t, db.deliveries[0].Claimed)
+
+	// The provider redelivers once the secret is fixed.
+	res = postGithub(t, s, "ping", "guid-1", `{}`, testWebhookSecret)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "ignored", responseMessage(t, res))
+	require.Len(t, db.deliveries, 2)
+	assert.True(t, db.deliveries[1].Claimed)
+}
+
+func TestWebhookFailedDeliveryIsReleased(t *testing.T) {
+	db := newFakeDB()
+	s := &Server{db: db}
+
+	res := postGithub(t, s, "push", "guid-1", `{not json`, testWebhookSecret)
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.Len(t, db.deliveries, 1)
+	assert.False(t, db.deliveries[0].Claimed)
+
+	res = postGithub(t, s, "push", "guid-1", `{not json`, testWebhookSecret)
+
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	assert.Len(t, db.deliveries, 2)
+}
+
+// deliveriesFixture has a delivery sent to a pipeline of user 1, one sent to
+// a pipeline of user 2 and one sent to the shared endpoint.
+func deliveriesFixture() *fakeDB {
+	db := newFakeDB()
+	db.pipelines[10] = models.Pipeline{ID: 10, UserID: 1}
+	db.pipelines[20] = models.Pipeline{ID: 20, UserID: 2}
+
+	for _, pipelineId := range []int64{10, 20, 0} {
+		db.deliveries = append(db.deliveries, models.WebhookDelivery{
+			ID:             int64(len(db.deliveries) + 1),
+			DeliveryID:     "guid-" + strconv.FormatInt(pipelineId, 10),
+			Provider:       "github",
+			EventType:      "ping",
+			Headers:        json.RawMessage(`{"X-Github-Event":["ping"]}`),
+			Body:           `{}`,
+			SignatureValid: true,
+			Claimed:        true,
+			PipelineID:     pipelineId,
+		})
+	}
+
+	return db
+}
+
+func listDeliveries(t *testing.T, s *Server, userId int64) []models.WebhookDelivery {
+	c, rec := newContext(http.MethodGet, "/api/deliveries/list", "", userId)
+	require.NoError(t, s.ListDeliveriesHandler(c))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var deliveries []models.WebhookDelivery
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&deliveries))
+
+	return deliveries
+}
+
+func TestListDeliveriesScopedToUser(t *testing.T) {
+	db := deliveriesFixture()
+	db.permissions[3] = []string{models.PermissionWebhookAdmin}
+	s := &Server{db: db}
+
+	deliveries := listDeliveries(t, s, 1)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, int64(10), deliveries[0].PipelineID)
+
+	assert.Len(t, listDeliveries(t, s, 3), 3)
+}
+
+func TestGetDeliveryScopedToUser(t *testing.T) {
+	s := &Server{db: deliveriesFixture()}
+
+	for id, status := range map[string]int{"1": http.StatusOK, "2": http.StatusNotFound, "3": http.StatusNotFound, "9": http.StatusNotFound} {
+		c, rec := newContext(http.MethodGet, "/api/deliveries/"+id, "", 1, "id", id)
+		require.NoError(t, s.GetDeliveryHandler(c))
+		assert.Equal(t, status, rec.Code, "delivery %s", id)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	db := deliveriesFixture()
+	s := &Server{db: db}
+
+	c, rec := newContext(http.MethodPost, "/api/deliveries/redeliver/1", "", 1, "id", "1")
+	require.NoError(t, s.RedeliverHandler(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, db.deliveries, 4)
+
+	redelivery := db.deliveries[3]
+	assert.Equal(t, int64(1), redelivery.RedeliveryOf)
+	assert.Equal(t, int64(10), redelivery.PipelineID)
+	assert.NotEqual(t, db.deliveries[0].DeliveryID, redelivery.DeliveryID)
+	assert.Equal(t, "ignored: ping event is not routable", redelivery.Decision)
+
+	var body echo.Map
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, float64(4), body["delivery_id"])
+}
+
+func TestRedeliverRejected(t *testing.T) {
+	db := deliveriesFixture()
+	db.deliveries[0].SignatureValid = false
+	s := &Server{db: db}
+
+	for id, status := range map[string]int{"1": http.StatusBadRequest, "2": http.StatusNotFound, "3": http.StatusNotFound} {
+		c, rec := newContext(http.MethodPost, "/api/deliveries/redeliver/"+id, "", 1, "id", id)
+		require.NoError(t, s.RedeliverHandler(c))
+		assert.Equal(t, status, rec.Code, "delivery %s", id)
+	}
+
+	assert.Len(t, db.deliveries, 3)
+}
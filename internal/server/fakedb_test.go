@@ -0,0 +1,367 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"cmp"
+	"database/sql"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// fakeDB keeps the records the handlers under test touch in memory. The
+// methods it does not implement panic through the nil embedded Service.
+type fakeDB struct {
+	database.Service
+
+	mu          sync.Mutex
+	pipelines   map[int64]models.Pipeline
+	permissions map[int64][]string
+	deliveries  []models.WebhookDelivery
+	triggers    map[int64]models.WebhookTrigger
+	updates     []database.Update
+	links       []models.PipelineLink
+	freezes     []models.FreezeWindow
+	approvals   []models.RunApproval
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		pipelines:   make(map[int64]models.Pipeline),
+		permissions: make(map[int64][]string),
+		triggers:    make(map[int64]models.WebhookTrigger),
+	}
+}
+
+func (f *fakeDB) GetUserPipelineById(pipeline_id int64, user_id int64) (models.Pipeline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pipeline, ok := f.pipelines[pipeline_id]
+
+	if !ok || pipeline.UserID != user_id {
+		return models.Pipeline{}, sql.ErrNoRows
+	}
+
+	return pipeline, nil
+}
+
+func (f *fakeDB) GetPipeline(pipeline_id int64) (models.Pipeline, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pipeline, ok := f.pipelines[pipeline_id]
+
+	if !ok {
+		return models.Pipeline{}, sql.ErrNoRows
+	}
+
+	return pipeline, nil
+}
+
+func (f *fakeDB) GetUserByID(id int64) (models.User, error) {
+	return models.User{ID: id, Name: "user"}, nil
+}
+
+func (f *fakeDB) CreatePipelineUpdate(update *database.Update) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	record := *update
+	record.ID = int64(len(f.updates) + 1)
+	f.updates = append(f.updates, record)
+
+	return record.ID, nil
+}
+
+func (f *fakeDB) GetUpdate(id int64) (database.Update, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id <= 0 || int(id) > len(f.updates) {
+		return database.Update{}, sql.ErrNoRows
+	}
+
+	return f.updates[id-1], nil
+}
+
+func (f *fakeDB) CancelUpdate(id int64, from string, user_id int64, message string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	update := &f.updates[id-1]
+
+	if update.Status != from {
+		return false, nil
+	}
+
+	update.Status, update.Message, update.CancelledBy = "cancelled", message, user_id
+
+	return true, nil
+}
+
+func (f *fakeDB) GetCommitUpdate(pipeline_id int64, repository string, sha string) (database.Update, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := len(f.updates) - 1; i >= 0; i-- {
+		update := f.updates[i]
+
+		if update.PipelineID == pipeline_id && update.Repository == repository && update.SHA == sha && !slices.Contains([]string{"error", "cancelled", "skipped", "rejected"}, update.Status) {
+			return update, nil
+		}
+	}
+
+	return database.Update{}, sql.ErrNoRows
+}
+
+// transition sets the status of an update still in the from status.
+func (f *fakeDB) transition(id int64, from string, status string, message string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	update := &f.updates[id-1]
+
+	if update.Status != from {
+		return false
+	}
+
+	update.Status, update.Message = status, message
+
+	return true
+}
+
+func (f *fakeDB) ListWaitingCIUpdates(repository string, sha string) ([]database.Update, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	updates := make([]database.Update, 0)
+
+	for _, update := range f.updates {
+		if update.Status == database.UpdateStatusWaitingCI && update.Repository == repository && update.SHA == sha {
+			updates = append(updates, update)
+		}
+	}
+
+	return updates, nil
+}
+
+func (f *fakeDB) ReleaseCIUpdate(id int64, status string, message string) (bool, error) {
+	return f.transition(id, database.UpdateStatusWaitingCI, status, message), nil
+}
+
+func (f *fakeDB) ReleaseFrozenUpdate(id int64, status string, message string) (bool, error) {
+	return f.transition(id, database.UpdateStatusFrozen, status, message), nil
+}
+
+func (f *fakeDB) AwaitApprovalUpdate(id int64, from string, message string, deadline time.Time) (bool, error) {
+	if !f.transition(id, from, database.UpdateStatusAwaitingApproval, message) {
+		return false, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.updates[id-1].ApprovalDeadline = &deadline
+
+	return true, nil
+}
+
+func (f *fakeDB) ListRunApprovals(update_id int64) ([]models.RunApproval, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	approvals := make([]models.RunApproval, 0)
+
+	for _, approval := range f.approvals {
+		if approval.UpdateID == update_id {
+			approvals = append(approvals, approval)
+		}
+	}
+
+	return approvals, nil
+}
+
+func (f *fakeDB) ListPipelineLinks(pipeline_id int64) ([]models.PipelineLink, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	links := make([]models.PipelineLink, 0)
+
+	for _, link := range f.links {
+		if link.PipelineID == pipeline_id {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+func (f *fakeDB) ListPipelineApprovers(pipeline_id int64) ([]int64, error) {
+	return nil, nil
+}
+
+func (f *fakeDB) ListPipelineFreezeWindows(pipeline_id int64, company_id int64) ([]models.FreezeWindow, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	windows := make([]models.FreezeWindow, 0)
+
+	for _, window := range f.freezes {
+		if window.Scope == models.FreezeGlobal || (window.Scope == models.FreezePipeline && window.ScopeID == pipeline_id) {
+			windows = append(windows, window)
+		}
+	}
+
+	return windows, nil
+}
+
+func (f *fakeDB) HasPermission(user_id int64, permission string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, p := range f.permissions[user_id] {
+		if p == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (f *fakeDB) GetWebhookTrigger(id int64) (models.WebhookTrigger, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	trigger, ok := f.triggers[id]
+
+	if !ok {
+		return models.WebhookTrigger{}, sql.ErrNoRows
+	}
+
+	return trigger, nil
+}
+
+func (f *fakeDB) ListActiveWebhookTriggers(repository string) ([]models.WebhookTrigger, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	triggers := make([]models.WebhookTrigger, 0)
+
+	for _, trigger := range f.triggers {
+		if trigger.Active && trigger.Repository == repository {
+			triggers = append(triggers, trigger)
+		}
+	}
+
+	slices.SortFunc(triggers, func(a, b models.WebhookTrigger) int { return cmp.Compare(a.ID, b.ID) })
+
+	return triggers, nil
+}
+
+func (f *fakeDB) GetPipelineWebhook(pipeline_id int64) (models.PipelineWebhook, error) {
+	return models.PipelineWebhook{}, sql.ErrNoRows
+}
+
+func (f *fakeDB) CreateWebhookDelivery(delivery *models.WebhookDelivery) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for _, d := range f.deliveries {
+		if delivery.Claimed && d.Claimed && d.Provider == delivery.Provider && d.DeliveryID == delivery.DeliveryID {
+			return 0, database.ErrDuplicateDelivery
+		}
+	}
+
+	record := *delivery
+	record.ID = int64(len(f.deliveries) + 1)
+	f.deliveries = append(f.deliveries, record)
+
+	return record.ID, nil
+}
+
+func (f *fakeDB) UpdateWebhookDeliveryDecision(id int64, decision string, updateIds []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deliveries[id-1].Decision = decision
+	f.deliveries[id-1].UpdateIDs = updateIds
+
+	return nil
+}
+
+func (f *fakeDB) ReleaseWebhookDelivery(id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.deliveries[id-1].Claimed = false
+
+	return nil
+}
+
+func (f *fakeDB) GetWebhookDelivery(id int64) (models.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id <= 0 || int(id) > len(f.deliveries) {
+		return models.WebhookDelivery{}, sql.ErrNoRows
+	}
+
+	return f.deliveries[id-1], nil
+}
+
+func (f *fakeDB) ListWebhookDeliveries(limit int, offset int) ([]models.WebhookDelivery, error) {
+	return f.listDeliveries(func(models.WebhookDelivery) bool { return true }), nil
+}
+
+func (f *fakeDB) ListUserWebhookDeliveries(user_id int64, limit int, offset int) ([]models.WebhookDelivery, error) {
+	return f.listDeliveries(func(d models.WebhookDelivery) bool {
+		pipeline, ok := f.pipelines[d.PipelineID]
+		return ok && pipeline.UserID == user_id
+	}), nil
+}
+
+func (f *fakeDB) listDeliveries(keep func(models.WebhookDelivery) bool) []models.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+
+	for i := len(f.deliveries) - 1; i >= 0; i-- {
+		if keep(f.deliveries[i]) {
+			deliveries = append(deliveries, f.deliveries[i])
+		}
+	}
+
+	return deliveries
+}
+
+// newContext builds the echo context of a request, logged in as userId when
+// it is not 0. params are the path parameter names and values in pairs.
+func newContext(method, target, body string, userId int64, params ...string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+
+	if userId != 0 {
+		c.Set("user", &jwt.Token{Claims: jwt.MapClaims{"id": float64(userId)}})
+	}
+
+	var names, values []string
+
+	for i := 0; i+1 < len(params); i += 2 {
+		names = append(names, params[i])
+		values = append(values, params[i+1])
+	}
+
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+
+	return c, rec
+}
@@ -0,0 +1,407 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// freezeSweepPeriod is how often runs held by a freeze are checked for
+// release.
+const freezeSweepPeriod = time.Minute
+
+type FreezeInfo struct {
+	Scope           string     `json:"scope"`
+	ScopeID         int64      `json:"scope_id"`
+	Name            string     `json:"name"`
+	Action          string     `json:"action"`
+	StartsAt        *time.Time `json:"starts_at"`
+	EndsAt          *time.Time `json:"ends_at"`
+	Cron            string     `json:"cron"`
+	DurationMinutes *int       `json:"duration_minutes"`
+	Timezone        string     `json:"timezone"`
+}
+
+// canManageFreeze reports whether a user can change a window: global windows
+// need the freeze admin permission, company windows a user of the company and
+// pipeline windows the owner of the pipeline.
+func (s *Server) canManageFreeze(userId int64, window models.FreezeWindow) (bool, error) {
+	switch window.Scope {
+	case models.FreezeGlobal:
+		return s.db.HasPermission(userId, models.PermissionFreezeAdmin)
+	case models.FreezeCompany:
+		user, err := s.db.GetUserByID(userId)
+		return err == nil && user.CompanyID != 0 && user.CompanyID == window.ScopeID, nil
+	case models.FreezePipeline:
+		_, err := s.db.GetUserPipelineById(window.ScopeID, userId)
+		return err == nil, nil
+	}
+
+	return false, nil
+}
+
+// userFreeze loads the :id freeze window when the logged user can manage it,
+// writing the error response when they can not.
+func (s *Server) userFreeze(c echo.Context) (models.FreezeWindow, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.FreezeWindow{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.FreezeWindow{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	window, err := s.db.GetFreezeWindow(id)
+
+	allowed := false
+	if err == nil {
+		allowed, err = s.canManageFreeze(loggedUserId, window)
+	}
+
+	if err != nil || !allowed {
+		return models.FreezeWindow{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "freeze window not found",
+		})
+	}
+
+	return window, true, nil
+}
+
+// validFreeze normalizes a freeze window and checks the logged user can
+// manage its scope, writing the error response when not.
+func (s *Server) validFreeze(c echo.Context, userId int64, window models.FreezeWindow) (models.FreezeWindow, bool, error) {
+	window, err := deploy.NormalizeFreeze(window)
+
+	if err != nil {
+		return window, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	allowed, err := s.canManageFreeze(userId, window)
+
+	if err != nil {
+		return window, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	if !allowed {
+		return window, false, c.JSON(http.StatusForbidden, map[string]string{
+			"message": "user can not manage freeze windows of this scope",
+		})
+	}
+
+	return window, true, nil
+}
+
+func (s *Server) CreateFreezeHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	freezeInfo := new(FreezeInfo)
+
+	if err := c.Bind(freezeInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	window := models.FreezeWindow{
+		Scope:     freezeInfo.Scope,
+		ScopeID:   freezeInfo.ScopeID,
+		Name:      freezeInfo.Name,
+		Action:    freezeInfo.Action,
+		StartsAt:  freezeInfo.StartsAt,
+		EndsAt:    freezeInfo.EndsAt,
+		Cron:      freezeInfo.Cron,
+		Timezone:  freezeInfo.Timezone,
+		CreatedBy: loggedUserId,
+	}
+
+	if freezeInfo.DurationMinutes != nil {
+		window.DurationMinutes = *freezeInfo.DurationMinutes
+	}
+
+	window, ok, err := s.validFreeze(c, loggedUserId, window)
+
+	if !ok {
+		return err
+	}
+
+	id, err := s.db.CreateFreezeWindow(&window)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating freeze window",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":   "ok",
+		"freeze_id": strconv.FormatInt(id, 10),
+	})
+}
+
+func (s *Server) UpdateFreezeHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	window, ok, err := s.userFreeze(c)
+
+	if !ok {
+		return err
+	}
+
+	freezeInfo := new(FreezeInfo)
+
+	if err := c.Bind(freezeInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if freezeInfo.Scope != "" {
+		window.Scope = freezeInfo.Scope
+	}
+	if freezeInfo.ScopeID != 0 {
+		window.ScopeID = freezeInfo.ScopeID
+	}
+	if freezeInfo.Name != "" {
+		window.Name = freezeInfo.Name
+	}
+	if freezeInfo.Action != "" {
+		window.Action = freezeInfo.Action
+	}
+	if freezeInfo.StartsAt != nil {
+		window.StartsAt = freezeInfo.StartsAt
+	}
+	if freezeInfo.EndsAt != nil {
+		window.EndsAt = freezeInfo.EndsAt
+	}
+	if freezeInfo.Cron != "" {
+		window.Cron = freezeInfo.Cron
+	}
+	if freezeInfo.DurationMinutes != nil {
+		window.DurationMinutes = *freezeInfo.DurationMinutes
+	}
+	if freezeInfo.Timezone != "" {
+		window.Timezone = freezeInfo.Timezone
+	}
+
+	window, ok, err = s.validFreeze(c, loggedUserId, window)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.UpdateFreezeWindow(&window); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating freeze window",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) DeleteFreezeHandler(c echo.Context) error {
+	window, ok, err := s.userFreeze(c)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.DeleteFreezeWindow(window.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting freeze window",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// ListFreezesHandler returns the global windows, the windows of the company
+// of the logged user and the windows of their pipelines.
+func (s *Server) ListFreezesHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	user, err := s.db.GetUserByID(loggedUserId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	windows, err := s.db.ListUserFreezeWindows(loggedUserId, user.CompanyID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting freeze windows",
+		})
+	}
+
+	return c.JSON(http.StatusOK, windows)
+}
+
+// ListFreezeOverridesHandler returns the runs forced through a freeze window.
+func (s *Server) ListFreezeOverridesHandler(c echo.Context) error {
+	window, ok, err := s.userFreeze(c)
+
+	if !ok {
+		return err
+	}
+
+	overrides, err := s.db.ListFreezeOverrides(window.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting freeze overrides",
+		})
+	}
+
+	return c.JSON(http.StatusOK, overrides)
+}
+
+// freezeStatus returns the freeze blocking a run of pipeline now, with the
+// status and message to record the run with. Runs are not blocked when the
+// windows can not be loaded.
+func (s *Server) freezeStatus(pipeline models.Pipeline) (models.FreezeWindow, string, string, bool) {
+	var companyId int64
+	if owner, err := s.db.GetUserByID(pipeline.UserID); err == nil {
+		companyId = owner.CompanyID
+	}
+
+	windows, err := s.db.ListPipelineFreezeWindows(pipeline.ID, companyId)
+
+	if err != nil {
+		slog.Error("Error getting freeze windows", "pipeline_id", pipeline.ID, "error", err)
+		return models.FreezeWindow{}, "", "", false
+	}
+
+	window, until, frozen := deploy.ActiveFreeze(windows, time.Now())
+
+	if !frozen {
+		return window, "", "", false
+	}
+
+	status := "rejected"
+	if window.Action == models.FreezeHold {
+		status = database.UpdateStatusFrozen
+	}
+
+	return window, status, deploy.FreezeReason(window, until), true
+}
+
+// auditOverride saves a run forced through a freeze and tells the owner of
+// the pipeline.
+func (s *Server) auditOverride(pipeline models.Pipeline, window models.FreezeWindow, run database.Update, override *models.FreezeOverride) {
+	override.UpdateID = run.ID
+	override.FreezeWindowID = window.ID
+
+	if err := s.db.CreateFreezeOverride(override); err != nil {
+		slog.Error("Error saving freeze override", "update_id", run.ID, "error", err)
+	}
+
+	slog.Info("Deployment freeze overridden", "update_id", run.ID, "freeze_window_id", window.ID, "user_id", override.UserID)
+
+	user, _ := s.db.GetUserByID(override.UserID)
+	msg := fmt.Sprintf("Congelamento *%s* ignorado na execução %d da pipeline *%s* por %s: %s", window.Name, run.ID, pipeline.Name, user.Name, override.Reason)
+	s.notifyOwner(pipeline.UserID, msg, "yellow")
+}
+
+// releaseFrozen queues the runs held by a freeze once it ends, and rejects
+// them when a rejecting freeze started meanwhile. Webhook updates that wait
+// for CI are only held once CI passed, so they are queued as is. It runs for
+// the lifetime of the server.
+func (s *Server) releaseFrozen() {
+	ticker := time.NewTicker(freezeSweepPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		updates, err := s.db.ListFrozenUpdates()
+
+		if err != nil {
+			continue
+		}
+
+		for _, update := range updates {
+			s.releaseFrozenUpdate(update)
+		}
+	}
+}
+
+// releaseFrozenUpdate queues a frozen update whose freeze ended. An update of
+// a pipeline that requires approval and was not approved yet is held for
+// approval instead.
+func (s *Server) releaseFrozenUpdate(update database.Update) {
+	pipeline, err := s.db.GetPipeline(update.PipelineID)
+
+	if err != nil {
+		return
+	}
+
+	_, status, reason, frozen := s.freezeStatus(pipeline)
+
+	if frozen && status == database.UpdateStatusFrozen {
+		return
+	}
+
+	if frozen {
+		if _, err := s.db.ReleaseFrozenUpdate(update.ID, status, reason); err != nil {
+			slog.Error("Error rejecting frozen update", "update_id", update.ID, "error", err)
+		}
+		return
+	}
+
+	held, err := s.awaitApproval(pipeline, update, database.UpdateStatusFrozen)
+
+	if err != nil {
+		slog.Error("Error holding frozen update for approval", "update_id", update.ID, "error", err)
+		return
+	}
+
+	if held {
+		slog.Info("Frozen update awaiting approval", "update_id", update.ID)
+		return
+	}
+
+	released, err := s.db.ReleaseFrozenUpdate(update.ID, "pending", "in queue")
+
+	if err != nil {
+		slog.Error("Error releasing frozen update", "update_id", update.ID, "error", err)
+		return
+	}
+
+	if released {
+		slog.Info("Released frozen update", "update_id", update.ID)
+		s.queue.Enqueue(ciUpdateOptions(update))
+	}
+}
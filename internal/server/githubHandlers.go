@@ -0,0 +1,157 @@
+package server
+
+import (
+	"auto-update/utils"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type GithubIntegrationInfo struct {
+	Repository  string `json:"repository"`
+	Token       string `json:"token"`
+	Environment string `json:"environment"`
+	BaseURL     string `json:"base_url"`
+	Active      *bool  `json:"active"`
+}
+
+// userPipelineParam reads the :id pipeline param and checks that the logged
+// user owns it, writing the error response when it does not.
+func (s *Server) userPipelineParam(c echo.Context) (int64, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return 0, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return 0, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(id, loggedUserId); err != nil {
+		return 0, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	return id, true, nil
+}
+
+func (s *Server) GetGithubIntegrationHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	integration, err := s.db.GetPipelineGithub(id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "github integration not configured",
+		})
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting github integration",
+		})
+	}
+
+	return c.JSON(http.StatusOK, integration)
+}
+
+// SaveGithubIntegrationHandler configures the GitHub reporting of a pipeline.
+// The token can be left out to keep the stored one.
+func (s *Server) SaveGithubIntegrationHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	info := new(GithubIntegrationInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if strings.Count(info.Repository, "/") != 1 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "repository must be owner/name",
+		})
+	}
+
+	integration, err := s.db.GetPipelineGithub(id)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting github integration",
+		})
+	}
+
+	if info.Token != "" {
+		integration.Token, err = utils.Encrypt(info.Token)
+
+		if err != nil {
+			slog.Error("Error encrypting github token", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+		}
+	}
+
+	if integration.Token == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "token is required",
+		})
+	}
+
+	integration.PipelineID = id
+	integration.Repository = info.Repository
+	integration.Environment = info.Environment
+	integration.BaseURL = info.BaseURL
+	integration.Active = info.Active == nil || *info.Active
+
+	if integration.Environment == "" {
+		integration.Environment = "production"
+	}
+
+	if err := s.db.SavePipelineGithub(&integration); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving github integration",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) DeleteGithubIntegrationHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.DeletePipelineGithub(id); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting github integration",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type HookInfo struct {
+	PipelineID int64  `json:"pipeline_id"`
+	Phase      string `json:"phase"`
+	Position   *int   `json:"position"`
+	Kind       string `json:"kind"`
+	ServerID   int64  `json:"server_id"`
+	Command    string `json:"command"`
+	Method     string `json:"method"`
+	URL        string `json:"url"`
+	Body       string `json:"body"`
+}
+
+// userHook loads the :id hook when it belongs to a pipeline of the logged
+// user, writing the error response when it does not.
+func (s *Server) userHook(c echo.Context) (models.Hook, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.Hook{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.Hook{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	hook, err := s.db.GetHook(id)
+
+	if err == nil {
+		_, err = s.db.GetUserPipelineById(hook.PipelineID, loggedUserId)
+	}
+
+	if err != nil {
+		return models.Hook{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "hook not found",
+		})
+	}
+
+	return hook, true, nil
+}
+
+// validHook normalizes a hook, writing the error response when it is invalid
+// or runs on a server of another pipeline.
+func (s *Server) validHook(c echo.Context, hook models.Hook) (models.Hook, bool, error) {
+	hook, err := deploy.NormalizeHook(hook)
+
+	if err != nil {
+		return hook, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	if hook.Kind == models.HookCommand {
+		server, err := s.db.GetServer(hook.ServerID)
+
+		if err != nil || server.PipelineID != hook.PipelineID {
+			return hook, false, c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "server not found in pipeline",
+			})
+		}
+	}
+
+	return hook, true, nil
+}
+
+func (s *Server) CreateHookHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	hookInfo := new(HookInfo)
+
+	if err := c.Bind(hookInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(hookInfo.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	hook := models.Hook{
+		PipelineID: hookInfo.PipelineID,
+		Phase:      hookInfo.Phase,
+		Kind:       hookInfo.Kind,
+		ServerID:   hookInfo.ServerID,
+		Command:    hookInfo.Command,
+		Method:     hookInfo.Method,
+		URL:        hookInfo.URL,
+		Body:       hookInfo.Body,
+	}
+
+	hook, ok, err := s.validHook(c, hook)
+
+	if !ok {
+		return err
+	}
+
+	if hookInfo.Position != nil {
+		hook.Position = *hookInfo.Position
+	} else {
+		// New hooks run after the existing ones of their phase.
+		hooks, err := s.db.ListHooks(hook.PipelineID)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error creating hook",
+			})
+		}
+
+		for _, existing := range deploy.HooksOf(hooks, hook.Phase) {
+			if existing.Position >= hook.Position {
+				hook.Position = existing.Position + 1
+			}
+		}
+	}
+
+	id, err := s.db.CreateHook(&hook)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating hook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+		"hook_id": strconv.FormatInt(id, 10),
+	})
+}
+
+func (s *Server) UpdateHookHandler(c echo.Context) error {
+	hook, ok, err := s.userHook(c)
+
+	if !ok {
+		return err
+	}
+
+	hookInfo := new(HookInfo)
+
+	if err := c.Bind(hookInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if hookInfo.Phase != "" {
+		hook.Phase = hookInfo.Phase
+	}
+	if hookInfo.Position != nil {
+		hook.Position = *hookInfo.Position
+	}
+	if hookInfo.Kind != "" {
+		hook.Kind = hookInfo.Kind
+	}
+	if hookInfo.ServerID != 0 {
+		hook.ServerID = hookInfo.ServerID
+	}
+	if hookInfo.Command != "" {
+		hook.Command = hookInfo.Command
+	}
+	if hookInfo.Method != "" {
+		hook.Method = hookInfo.Method
+	}
+	if hookInfo.URL != "" {
+		hook.URL = hookInfo.URL
+	}
+	if hookInfo.Body != "" {
+		hook.Body = hookInfo.Body
+	}
+
+	hook, ok, err = s.validHook(c, hook)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.UpdateHook(&hook); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating hook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) DeleteHookHandler(c echo.Context) error {
+	hook, ok, err := s.userHook(c)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.DeleteHook(hook.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting hook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) ListHooksHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	pipelineId, err := strconv.ParseInt(c.Param("pipeline_id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(pipelineId, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	hooks, err := s.db.ListHooks(pipelineId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting hooks",
+		})
+	}
+
+	return c.JSON(http.StatusOK, hooks)
+}
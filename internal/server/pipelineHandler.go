@@ -1,7 +1,10 @@
 package server
 
 import (
+	"auto-update/internal/database"
 	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/internal/sshclient"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -69,8 +72,83 @@ func (s *Server) UpdatePipelineHandler(c echo.Context) error {
 	name := c.FormValue("name")
 
 	updatePipeline := &models.UpdatePipeline{
-		ID:   id,
-		Name: name,
+		ID:          id,
+		Name:        name,
+		Strategy:    c.FormValue("strategy"),
+		BatchSize:   c.FormValue("batch_size"),
+		HealthCheck: c.FormValue("health_check"),
+
+		RollbackScript: c.FormValue("rollback_script"),
+		RetryOn:        c.FormValue("retry_on"),
+	}
+
+	for name, target := range map[string]**int{
+		"bake_minutes":             &updatePipeline.BakeMinutes,
+		"max_failures":             &updatePipeline.MaxFailures,
+		"required_approvals":       &updatePipeline.RequiredApprovals,
+		"approval_timeout_minutes": &updatePipeline.ApprovalTimeoutMinutes,
+		"timeout_seconds":          &updatePipeline.TimeoutSeconds,
+		"retry_max_attempts":       &updatePipeline.RetryMaxAttempts,
+		"retry_backoff_seconds":    &updatePipeline.RetryBackoffSeconds,
+	} {
+		value, ok, err := formInt(c, name)
+
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "invalid " + name,
+			})
+		}
+
+		if ok {
+			*target = &value
+		}
+	}
+
+	if (updatePipeline.RequiredApprovals != nil && *updatePipeline.RequiredApprovals < 0) || (updatePipeline.ApprovalTimeoutMinutes != nil && *updatePipeline.ApprovalTimeoutMinutes < 0) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "approval settings cannot be negative",
+		})
+	}
+
+	if updatePipeline.TimeoutSeconds != nil && *updatePipeline.TimeoutSeconds < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "timeout cannot be negative",
+		})
+	}
+
+	if err := deploy.ValidRetry(valueOr(updatePipeline.RetryMaxAttempts, 0), valueOr(updatePipeline.RetryBackoffSeconds, 0), updatePipeline.RetryOn); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	if updatePipeline.Strategy != "" || updatePipeline.BatchSize != "" || updatePipeline.MaxFailures != nil || updatePipeline.BakeMinutes != nil {
+		pipeline, err := s.db.GetUserPipelineById(id, loggedUserId)
+
+		if err != nil {
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"message": "pipeline not found",
+			})
+		}
+
+		if updatePipeline.Strategy != "" {
+			pipeline.Strategy = updatePipeline.Strategy
+		}
+		if updatePipeline.BatchSize != "" {
+			pipeline.BatchSize = updatePipeline.BatchSize
+		}
+		if updatePipeline.MaxFailures != nil {
+			pipeline.MaxFailures = *updatePipeline.MaxFailures
+		}
+		if updatePipeline.BakeMinutes != nil {
+			pipeline.BakeMinutes = *updatePipeline.BakeMinutes
+		}
+
+		if _, err := deploy.PipelineStrategy(pipeline); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": err.Error(),
+			})
+		}
 	}
 
 	err = s.db.UpdatePipeline(updatePipeline, loggedUserId)
@@ -87,6 +165,28 @@ func (s *Server) UpdatePipelineHandler(c echo.Context) error {
 
 }
 
+// formInt reads an optional integer form value, reporting whether it was set.
+func formInt(c echo.Context, name string) (int, bool, error) {
+	value := c.FormValue(name)
+
+	if value == "" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(value)
+
+	return n, err == nil, err
+}
+
+// valueOr is the value of an optional setting, fallback when it is unset.
+func valueOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
+
 func (s *Server) DeletePipelineHandler(c echo.Context) error {
 	loggedUser, ok := c.Get("user").(*jwt.Token)
 
@@ -205,32 +305,181 @@ func (s *Server) UpdateProdPipelineHandler(c echo.Context) error {
 		})
 	}
 
-	go func() {
-		s.sshclient.UpdateProductionNew(id, loggedUserId)
-	}()
+	options := &sshclient.UpdateOptions{
+		PipelineID: id,
+		Tag:        c.FormValue("tag"),
+		Branch:     c.FormValue("branch"),
+		SHA:        c.FormValue("sha"),
+	}
+
+	if user, err := s.db.GetUserByID(loggedUserId); err == nil {
+		options.Pusher = user.Name
+	}
+
+	if c.FormValue("dry_run") == "true" {
+		return s.dryRun(c, userPipeline, options)
+	}
+
+	var override *models.FreezeOverride
+
+	if c.FormValue("override_freeze") == "true" {
+		// Approved runs are checked against freezes when released, long after
+		// the override was asked for, so it could not be applied.
+		if userPipeline.RequiredApprovals > 0 {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"message": "freezes can not be overridden on pipelines that require approval",
+			})
+		}
+
+		allowed, err := s.db.HasPermission(loggedUserId, models.PermissionFreezeOverride)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+		}
+
+		if !allowed {
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"message": "user can not override deployment freezes",
+			})
+		}
+
+		override = &models.FreezeOverride{UserID: loggedUserId, Reason: c.FormValue("override_reason")}
+	}
+
+	run, err := s.runPipeline(userPipeline, options, database.Update{RequestedBy: loggedUserId}, s.launchRun, override)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error starting pipeline run",
+		})
+	}
+
+	switch run.Status {
+	case database.UpdateStatusAwaitingApproval:
+		return c.JSON(http.StatusAccepted, map[string]string{
+			"message": "Execução aguardando aprovação",
+			"run_id":  strconv.FormatInt(run.ID, 10),
+		})
+	case database.UpdateStatusFrozen:
+		return c.JSON(http.StatusAccepted, map[string]string{
+			"message": run.Message,
+			"run_id":  strconv.FormatInt(run.ID, 10),
+		})
+	case "rejected":
+		return c.JSON(http.StatusLocked, map[string]string{
+			"message": run.Message,
+			"run_id":  strconv.FormatInt(run.ID, 10),
+		})
+	}
 
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Atualizaçãp de pipeline de produção iniciada com sucesso",
+		"run_id":  strconv.FormatInt(run.ID, 10),
 	})
 }
 
-func (s *Server) UpdateProductionById(c echo.Context) error {
-	fmt.Println("Atualizando produção")
-	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+// dryRun reports whether every server a run of the pipeline would deploy is
+// ready, without recording or starting the run. Approvals and freezes that
+// would hold the run are reported too.
+func (s *Server) dryRun(c echo.Context, pipeline models.Pipeline, options *sshclient.UpdateOptions) error {
+	reports, err := s.sshclient.DryRun(pipeline, options)
 
 	if err != nil {
-		fmt.Println("error", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{
-			"message": "invalid id",
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error checking pipeline servers",
 		})
 	}
 
-	go func() {
-		s.sshclient.UpdateProductionById(id)
-	}()
+	ready := deploy.ReadyServers(reports)
 
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Atualização de produção iniciada com sucesso",
-	})
+	response := map[string]any{
+		"dry_run":            true,
+		"ready":              len(reports) > 0 && ready == len(reports),
+		"ready_servers":      ready,
+		"total_servers":      len(reports),
+		"required_approvals": pipeline.RequiredApprovals,
+		"servers":            reports,
+	}
+
+	if _, status, reason, frozen := s.freezeStatus(pipeline); frozen {
+		response["freeze"] = map[string]string{
+			"status":  status,
+			"message": reason,
+		}
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// runPipeline records a run and starts it with launch, unless the
+// pipeline requires approval or a deployment freeze rejects or holds it. run
+// carries who or what requested it and the recorded run is returned. Runs
+// awaiting approval are checked against freezes once approved. A non nil
+// override forces the run through a freeze and is saved to audit it, it is
+// only honoured on pipelines that do not require approval.
+func (s *Server) runPipeline(pipeline models.Pipeline, options *sshclient.UpdateOptions, run database.Update, launch func(*sshclient.UpdateOptions), override *models.FreezeOverride) (database.Update, error) {
+	if pipeline.RequiredApprovals > 0 {
+		id, err := s.requestApproval(pipeline, options, run)
+		run.ID, run.Status = id, database.UpdateStatusAwaitingApproval
+		return run, err
+	}
+
+	window, status, reason, frozen := s.freezeStatus(pipeline)
+
+	if frozen && override == nil {
+		run.Status, run.Message = status, reason
+		id, err := s.createRun(options, run)
+		run.ID = id
+		return run, err
+	}
 
+	run.Status = "pending"
+	if run.Message == "" {
+		run.Message = "execução manual"
+	}
+
+	id, err := s.createRun(options, run)
+
+	if err != nil {
+		return run, err
+	}
+
+	run.ID = id
+
+	if frozen {
+		s.auditOverride(pipeline, window, run, override)
+	}
+
+	launch(options)
+
+	return run, nil
+}
+
+// createRun records run with the parameters of options, setting options.ID.
+func (s *Server) createRun(options *sshclient.UpdateOptions, run database.Update) (int64, error) {
+	run.PusherName = options.Pusher
+	run.Branch = options.Branch
+	run.PipelineID = options.PipelineID
+	run.Tag = options.Tag
+	run.SHA = options.SHA
+
+	id, err := s.db.CreatePipelineUpdate(&run)
+
+	if err != nil {
+		slog.Error("Error creating pipeline update", "error", err)
+		return 0, err
+	}
+
+	options.ID = id
+
+	return id, nil
+}
+
+// launchRun runs a recorded run in the background, enqueueing the downstream
+// pipelines it chains once it finishes.
+func (s *Server) launchRun(options *sshclient.UpdateOptions) {
+	go func() {
+		s.sshclient.RunPipeline(options)
+		s.chainRuns(options)
+	}()
 }
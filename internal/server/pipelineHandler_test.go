@@ -0,0 +1,26 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverrideFreezeRefusedWhenApprovalRequired(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[2] = models.Pipeline{ID: 2, UserID: 1, RequiredApprovals: 1}
+	db.permissions[1] = []string{models.PermissionFreezeOverride}
+	s := &Server{db: db}
+
+	c, rec := newContext(http.MethodPost, "/api/pipeline/update-prod/2", "override_freeze=true&override_reason=hotfix", 1, "id", "2")
+	c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+
+	require.NoError(t, s.UpdateProdPipelineHandler(c))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Empty(t, db.updates)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/internal/sshclient"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type PipelineLinkInfo struct {
+	DownstreamID int64  `json:"downstream_id"`
+	Condition    string `json:"condition"`
+}
+
+type PipelineLinksInfo struct {
+	Links []PipelineLinkInfo `json:"links"`
+}
+
+// GetPipelineLinksHandler returns the downstream pipelines of a pipeline.
+func (s *Server) GetPipelineLinksHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	links, err := s.db.ListPipelineLinks(id)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting pipeline links",
+		})
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+// SetPipelineLinksHandler replaces the downstream pipelines of a pipeline,
+// rejecting links that would chain a pipeline back to itself.
+func (s *Server) SetPipelineLinksHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	loggedUserId, _ := getLoggedUserIdFromContext(c)
+
+	info := new(PipelineLinksInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	links := make([]models.PipelineLink, 0, len(info.Links))
+
+	for _, link := range info.Links {
+		if _, err := s.db.GetUserPipelineById(link.DownstreamID, loggedUserId); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "downstream pipeline not found",
+			})
+		}
+
+		if link.Condition == "" {
+			link.Condition = models.LinkOnSuccess
+		}
+
+		links = append(links, models.PipelineLink{
+			PipelineID:   id,
+			DownstreamID: link.DownstreamID,
+			Condition:    link.Condition,
+		})
+	}
+
+	existing, err := s.db.ListUserPipelineLinks(loggedUserId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving pipeline links",
+		})
+	}
+
+	if err := deploy.CheckLinks(existing, id, links); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	if err := s.db.SetPipelineLinks(id, links); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving pipeline links",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// chainRuns starts the runs of the downstream pipelines a finished run
+// triggers. The commit, branch, tag and pusher of the run pass through to its
+// children, which go through approvals and deployment freezes like any other
+// run before they are queued.
+func (s *Server) chainRuns(options *sshclient.UpdateOptions) {
+	if options == nil || options.ID == 0 {
+		return
+	}
+
+	update, err := s.db.GetUpdate(options.ID)
+
+	if err != nil {
+		slog.Error("Error finding update", "update_id", options.ID, "error", err)
+		return
+	}
+
+	links, err := s.db.ListPipelineLinks(update.PipelineID)
+
+	if err != nil {
+		slog.Error("Error getting chained pipelines", "pipeline_id", update.PipelineID, "error", err)
+		return
+	}
+
+	for _, link := range links {
+		if !deploy.LinkTriggered(link, update.Status) {
+			continue
+		}
+
+		pipeline, err := s.db.GetPipeline(link.DownstreamID)
+
+		if err != nil {
+			slog.Error("Error getting chained pipeline", "parent_id", update.ID, "pipeline_id", link.DownstreamID, "error", err)
+			continue
+		}
+
+		child := &sshclient.UpdateOptions{
+			PipelineID: pipeline.ID,
+			Tag:        update.Tag,
+			Branch:     update.Branch,
+			SHA:        update.SHA,
+			Pusher:     update.PusherName,
+		}
+
+		run, err := s.runPipeline(pipeline, child, database.Update{
+			Message:    fmt.Sprintf("in queue (encadeada pela execução %d)", update.ID),
+			Repository: update.Repository,
+			ParentID:   update.ID,
+		}, s.queue.Enqueue, nil)
+
+		if err != nil {
+			slog.Error("Error creating chained update", "parent_id", update.ID, "pipeline_id", pipeline.ID, "error", err)
+			continue
+		}
+
+		slog.Info("Pipeline encadeada", "parent_id", update.ID, "update_id", run.ID, "pipeline_id", pipeline.ID, "status", run.Status)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/queue"
+	"auto-update/internal/sshclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chainFixture has a finished run of pipeline 1 chaining pipelines 2, 3 and
+// 4 on success. Pipeline 2 needs approval and pipeline 3 is frozen.
+func chainFixture() *fakeDB {
+	db := newFakeDB()
+	db.pipelines[2] = models.Pipeline{ID: 2, UserID: 1, RequiredApprovals: 1}
+	db.pipelines[3] = models.Pipeline{ID: 3, UserID: 1}
+	db.pipelines[4] = models.Pipeline{ID: 4, UserID: 1}
+
+	for _, downstream := range []int64{2, 3, 4} {
+		db.links = append(db.links, models.PipelineLink{PipelineID: 1, DownstreamID: downstream, Condition: models.LinkOnSuccess})
+	}
+
+	starts, ends := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	db.freezes = append(db.freezes, models.FreezeWindow{ID: 1, Scope: models.FreezePipeline, ScopeID: 3, Action: models.FreezeReject, StartsAt: &starts, EndsAt: &ends})
+
+	db.updates = append(db.updates, database.Update{ID: 1, PipelineID: 1, Status: "success", Tag: "v1.0.0", Branch: "main", SHA: "abc", PusherName: "dev"})
+
+	return db
+}
+
+func TestChainRunsGated(t *testing.T) {
+	db := chainFixture()
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	s.chainRuns(&sshclient.UpdateOptions{ID: 1, PipelineID: 1})
+
+	require.Len(t, db.updates, 4)
+
+	statuses := make(map[int64]string)
+
+	for _, run := range db.updates[1:] {
+		assert.Equal(t, int64(1), run.ParentID)
+		assert.Equal(t, "v1.0.0", run.Tag)
+		assert.Equal(t, "abc", run.SHA)
+		statuses[run.PipelineID] = run.Status
+	}
+
+	assert.Equal(t, map[int64]string{
+		2: database.UpdateStatusAwaitingApproval,
+		3: "rejected",
+		4: "pending",
+	}, statuses)
+
+	// Only the run that needs no approval and is not frozen is deployed.
+	assert.Equal(t, 1, s.queue.Size())
+}
+
+func TestChainRunsCondition(t *testing.T) {
+	db := chainFixture()
+	db.updates[0].Status = "error"
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	s.chainRuns(&sshclient.UpdateOptions{ID: 1, PipelineID: 1})
+
+	assert.Len(t, db.updates, 1)
+	assert.Equal(t, 0, s.queue.Size())
+}
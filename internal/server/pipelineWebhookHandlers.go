@@ -0,0 +1,204 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/webhooks"
+	"auto-update/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// defaultSecretGracePeriod is how long the previous secret keeps working
+// after a rotation, enough to update the secret on the git host.
+const defaultSecretGracePeriod = 24 * time.Hour
+
+type RegenerateWebhookInfo struct {
+	// GraceHours overrides how long the previous secret stays active.
+	GraceHours *int `json:"grace_hours"`
+	// RevokePrevious drops the previous secret right away, for leaked secrets.
+	RevokePrevious bool `json:"revoke_previous"`
+}
+
+func pipelineWebhookPath(token string) string {
+	return fmt.Sprintf("/webhooks/{provider}/%s", token)
+}
+
+// pipelineWebhookSecrets decrypts the secrets a pipeline webhook accepts, the
+// current one and the previous one while its rotation grace period lasts.
+func pipelineWebhookSecrets(webhook models.PipelineWebhook) ([]string, error) {
+	secret, err := utils.Decrypt(webhook.Secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []string{secret}
+
+	if webhook.PreviousActive(time.Now()) {
+		previous, err := utils.Decrypt(webhook.PreviousSecret)
+
+		if err != nil {
+			return nil, err
+		}
+
+		secrets = append(secrets, previous)
+	}
+
+	return secrets, nil
+}
+
+// PipelineWebhookHandler serves /webhooks/:provider/:token, the endpoint of a
+// single pipeline verified with that pipeline's own secret.
+func (s *Server) PipelineWebhookHandler(c echo.Context) error {
+	provider, ok := webhooks.GetProvider(c.Param("provider"))
+
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "unknown webhook provider",
+		})
+	}
+
+	webhook, err := s.db.GetPipelineWebhookByToken(c.Param("token"))
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "webhook not found",
+		})
+	}
+
+	secrets, err := pipelineWebhookSecrets(webhook)
+
+	if err != nil {
+		slog.Error("Error decrypting webhook secret", "pipeline_id", webhook.PipelineID, "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "internal server error",
+		})
+	}
+
+	return s.handleWebhook(c, provider, webhook.PipelineID, secrets...)
+}
+
+func (s *Server) GetPipelineWebhookHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	webhook, err := s.db.GetPipelineWebhook(id)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "pipeline has no webhook, regenerate to create one",
+		})
+	}
+
+	if err != nil {
+		slog.Error("Error getting pipeline webhook", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting webhook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"url":             pipelineWebhookPath(webhook.Token),
+		"previous_active": webhook.PreviousActive(time.Now()),
+		"webhook":         webhook,
+	})
+}
+
+// RegeneratePipelineWebhookHandler creates the webhook of a pipeline or
+// rotates its secret. The new secret is only returned by this call. The
+// previous secret stays active for the grace period unless revoked.
+func (s *Server) RegeneratePipelineWebhookHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	info := new(RegenerateWebhookInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	grace := defaultSecretGracePeriod
+
+	if info.GraceHours != nil {
+		if *info.GraceHours < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "invalid grace_hours",
+			})
+		}
+
+		grace = time.Duration(*info.GraceHours) * time.Hour
+	}
+
+	webhook, err := s.db.GetPipelineWebhook(id)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		slog.Error("Error getting pipeline webhook", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting webhook",
+		})
+	}
+
+	exists := err == nil
+
+	secret, err := webhooks.GenerateSecret(32)
+
+	if err != nil {
+		slog.Error("Error generating webhook secret", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	encryptedSecret, err := utils.Encrypt(secret)
+
+	if err != nil {
+		slog.Error("Error encrypting webhook secret", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	if !exists {
+		webhook = models.PipelineWebhook{PipelineID: id}
+
+		if webhook.Token, err = webhooks.GenerateSecret(16); err != nil {
+			slog.Error("Error generating webhook token", "error", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+		}
+	}
+
+	webhook.PreviousSecret = ""
+	webhook.PreviousExpiresAt = nil
+
+	if exists && !info.RevokePrevious && grace > 0 {
+		expiresAt := time.Now().Add(grace)
+		webhook.PreviousSecret = webhook.Secret
+		webhook.PreviousExpiresAt = &expiresAt
+	}
+
+	webhook.Secret = encryptedSecret
+
+	if err := s.db.SavePipelineWebhook(&webhook); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving webhook",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"message":             "ok",
+		"token":               webhook.Token,
+		"url":                 pipelineWebhookPath(webhook.Token),
+		"secret":              secret,
+		"previous_expires_at": webhook.PreviousExpiresAt,
+	})
+}
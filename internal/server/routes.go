@@ -1,18 +1,11 @@
 package server
 
 import (
-	"auto-update/internal/sshclient"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
@@ -26,47 +19,20 @@ type ServerInfo struct {
 	PipelineID int64  `json:"pipeline_id"`
 	Label      string `json:"label"`
 	Active     bool   `json:"active"`
-}
-
-type GithubWebhook struct {
-	Ref         string      `json:"ref"`
-	Pusher      Pusher      `json:"pusher"`
-	HeadCommit  HeadCommit  `json:"head_commit"`
-	Action      string      `json:"action"`
-	PullRequest PullRequest `json:"pull_request"`
-}
-
-type HeadCommit struct {
-	Id      string `json:"id"`
-	Message string `json:"message"`
-}
-type Pusher struct {
-	Name  string `json:"name"`
-	Email string `json:"email"`
-}
-type MergedBy struct {
-	Login string `json:"login"`
-}
-
-type Repo struct {
-	FullName string `json:"full_name"`
-}
-
-type Head struct {
-	Ref  string `json:"ref"`
-	Repo Repo   `json:"repo"`
-}
-
-type Base struct {
-	Ref string `json:"ref"`
-}
-
-type PullRequest struct {
-	Merged   bool     `json:"merged"`
-	MergedAt string   `json:"merged_at"`
-	MergedBy MergedBy `json:"merged_by"`
-	Head     Head     `json:"head"`
-	Base     Base     `json:"base"`
+	StageID    *int64 `json:"stage_id"`
+	Canary     *bool  `json:"canary"`
+	// RollbackScript runs on the server when its deploy fails.
+	RollbackScript string `json:"rollback_script"`
+	// ScriptID makes the server run a library script with ScriptParams.
+	ScriptID     *int64            `json:"script_id"`
+	ScriptParams map[string]string `json:"script_params"`
+	// TimeoutSeconds overrides the script timeout of the pipeline.
+	TimeoutSeconds *int `json:"timeout_seconds"`
+	// RetryMaxAttempts, RetryBackoffSeconds and RetryOn override the retry
+	// policy of the pipeline.
+	RetryMaxAttempts    *int    `json:"retry_max_attempts"`
+	RetryBackoffSeconds *int    `json:"retry_backoff_seconds"`
+	RetryOn             *string `json:"retry_on"`
 }
 
 func checkSecretKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
@@ -86,14 +52,6 @@ func checkSecretKeyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-func checkMAC(message []byte, messageMAC, key string) bool {
-	mac := hmac.New(sha256.New, []byte(key))
-	mac.Write(message)
-	expectedMAC := mac.Sum(nil)
-
-	return hmac.Equal([]byte(messageMAC), []byte(hex.EncodeToString(expectedMAC)))
-}
-
 func (s *Server) RegisterRoutes() http.Handler {
 	jwtSecret := os.Getenv("SECRET_JWT")
 
@@ -107,6 +65,9 @@ func (s *Server) RegisterRoutes() http.Handler {
 	e.GET("/health", s.healthHandler)
 	e.GET("/updates", s.GetUpdatesHandler)
 	e.POST("/github-webhook", s.GithubWebhookHandler)
+	e.POST("/gitlab-webhook", s.GitlabWebhookHandler)
+	e.POST("/webhooks/:provider", s.GithubWebhookHandler)
+	e.POST("/webhooks/:provider/:token", s.PipelineWebhookHandler)
 	// e.POST("/update_passwords", s.UpdatePasswords)
 
 	apiGroup := e.Group("/api")
@@ -114,6 +75,14 @@ func (s *Server) RegisterRoutes() http.Handler {
 	sessionGroup := apiGroup.Group("/session")
 	serverGroup := apiGroup.Group("/servers")
 	pipelineGroup := apiGroup.Group("/pipelines")
+	triggerGroup := apiGroup.Group("/triggers")
+	deliveryGroup := apiGroup.Group("/deliveries")
+	stageGroup := apiGroup.Group("/stages")
+	runGroup := apiGroup.Group("/runs")
+	hookGroup := apiGroup.Group("/hooks")
+	scriptGroup := apiGroup.Group("/scripts")
+	scheduleGroup := apiGroup.Group("/schedules")
+	freezeGroup := apiGroup.Group("/freezes")
 	usersGroupNoAuth := apiGroup.Group("/users")
 	usersGroupAuth := apiGroup.Group("/users")
 
@@ -122,6 +91,14 @@ func (s *Server) RegisterRoutes() http.Handler {
 	apiGroup.Use(checkSecretKeyMiddleware)
 	serverGroup.Use(echojwt.JWT([]byte(jwtSecret)))
 	pipelineGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	triggerGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	deliveryGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	stageGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	runGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	hookGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	scriptGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	scheduleGroup.Use(echojwt.JWT([]byte(jwtSecret)))
+	freezeGroup.Use(echojwt.JWT([]byte(jwtSecret)))
 	usersGroupAuth.Use(echojwt.JWT([]byte(jwtSecret)))
 
 	usersGroupNoAuth.POST("/create", s.CreateUserHandler)
@@ -138,6 +115,8 @@ func (s *Server) RegisterRoutes() http.Handler {
 	serverGroup.DELETE("/delete/:id", s.DeleteServerHandler)
 	serverGroup.GET("/list", s.ListServersHandler)
 	serverGroup.GET("/list/:id", s.ListServersHandler)
+	serverGroup.GET("/preview/:id", s.PreviewScriptHandler)
+	serverGroup.GET("/attempts/:id", s.ServerAttemptsHandler)
 
 	pipelineGroup.POST("/create", s.CreatePipelineHandler)
 	pipelineGroup.PUT("/update/:id", s.UpdatePipelineHandler)
@@ -145,6 +124,61 @@ func (s *Server) RegisterRoutes() http.Handler {
 	pipelineGroup.GET("/list", s.ListPipelinesHandler)
 	pipelineGroup.POST("/run/:id", s.UpdateProdPipelineHandler)
 	pipelineGroup.GET("/check", s.CheckServers)
+	pipelineGroup.GET("/webhook/:id", s.GetPipelineWebhookHandler)
+	pipelineGroup.POST("/webhook/:id/regenerate", s.RegeneratePipelineWebhookHandler)
+	pipelineGroup.GET("/github/:id", s.GetGithubIntegrationHandler)
+	pipelineGroup.PUT("/github/:id", s.SaveGithubIntegrationHandler)
+	pipelineGroup.DELETE("/github/:id", s.DeleteGithubIntegrationHandler)
+	pipelineGroup.GET("/variables/:id", s.GetVariablesHandler)
+	pipelineGroup.PUT("/variables/:id", s.SetVariablesHandler)
+	pipelineGroup.GET("/links/:id", s.GetPipelineLinksHandler)
+	pipelineGroup.PUT("/links/:id", s.SetPipelineLinksHandler)
+	pipelineGroup.GET("/approvers/:id", s.GetPipelineApproversHandler)
+	pipelineGroup.PUT("/approvers/:id", s.SetPipelineApproversHandler)
+
+	triggerGroup.POST("/create", s.CreateTriggerHandler)
+	triggerGroup.PUT("/update/:id", s.UpdateTriggerHandler)
+	triggerGroup.DELETE("/delete/:id", s.DeleteTriggerHandler)
+	triggerGroup.GET("/list/:pipeline_id", s.ListTriggersHandler)
+
+	deliveryGroup.GET("/list", s.ListDeliveriesHandler)
+	deliveryGroup.GET("/:id", s.GetDeliveryHandler)
+	deliveryGroup.POST("/redeliver/:id", s.RedeliverHandler)
+
+	stageGroup.POST("/create", s.CreateStageHandler)
+	stageGroup.PUT("/update/:id", s.UpdateStageHandler)
+	stageGroup.DELETE("/delete/:id", s.DeleteStageHandler)
+	stageGroup.GET("/list/:pipeline_id", s.ListStagesHandler)
+
+	runGroup.GET("/:id", s.GetRunHandler)
+	runGroup.POST("/:id/approve", s.ApproveRunHandler)
+	runGroup.POST("/:id/reject", s.RejectRunHandler)
+	runGroup.POST("/:id/cancel", s.CancelRunHandler)
+
+	hookGroup.POST("/create", s.CreateHookHandler)
+	hookGroup.PUT("/update/:id", s.UpdateHookHandler)
+	hookGroup.DELETE("/delete/:id", s.DeleteHookHandler)
+	hookGroup.GET("/list/:pipeline_id", s.ListHooksHandler)
+
+	scriptGroup.POST("/create", s.CreateScriptHandler)
+	scriptGroup.PUT("/update/:id", s.UpdateScriptHandler)
+	scriptGroup.DELETE("/delete/:id", s.DeleteScriptHandler)
+	scriptGroup.GET("/list", s.ListScriptsHandler)
+	scriptGroup.GET("/:id", s.GetScriptHandler)
+	scriptGroup.GET("/:id/servers", s.ListScriptServersHandler)
+
+	scheduleGroup.POST("/create", s.CreateScheduleHandler)
+	scheduleGroup.PUT("/update/:id", s.UpdateScheduleHandler)
+	scheduleGroup.DELETE("/delete/:id", s.DeleteScheduleHandler)
+	scheduleGroup.GET("/list/:pipeline_id", s.ListSchedulesHandler)
+	scheduleGroup.POST("/pause/:id", s.PauseScheduleHandler)
+	scheduleGroup.POST("/resume/:id", s.ResumeScheduleHandler)
+
+	freezeGroup.POST("/create", s.CreateFreezeHandler)
+	freezeGroup.PUT("/update/:id", s.UpdateFreezeHandler)
+	freezeGroup.DELETE("/delete/:id", s.DeleteFreezeHandler)
+	freezeGroup.GET("/list", s.ListFreezesHandler)
+	freezeGroup.GET("/:id/overrides", s.ListFreezeOverridesHandler)
 
 	// e.POST("/create_server", s.CreateServerHandler, checkSecretKeyMiddleware)
 	// e.PUT("/update_server/:id", s.UpdateServerHandler, checkSecretKeyMiddleware)
@@ -156,7 +190,6 @@ func (s *Server) RegisterRoutes() http.Handler {
 	// e.GET("/list_pipelines", s.ListPipelinesHandler, checkSecretKeyMiddleware)
 	// e.POST("/update_prod_pipeline/:id", s.UpdateProdPipelineHandler, checkSecretKeyMiddleware)
 	// e.GET("/check_servers", s.CheckServers, checkSecretKeyMiddleware)
-	// e.POST("/update_production/:id", s.UpdateProductionById, checkSecretKeyMiddleware)
 
 	return e
 }
@@ -189,200 +222,6 @@ func (s *Server) GetUpdatesHandler(c echo.Context) error {
 
 }
 
-func (s *Server) GithubWebhookHandler(c echo.Context) error {
-	body, err := io.ReadAll(c.Request().Body)
-	fmt.Println("body", string(body))
-
-	if err != nil {
-		slog.Error("Error reading body")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"message": "error reading body",
-		})
-	}
-
-	for name, headers := range c.Request().Header {
-		for _, h := range headers {
-			fmt.Printf("%v: %v\n", name, h)
-		}
-	}
-
-	mySecret := os.Getenv("SECRET_KEY")
-	hashSecret := strings.Split(c.Request().Header.Get("X-Hub-Signature-256"), "=")[1]
-
-	if !checkMAC(body, hashSecret, mySecret) {
-		slog.Error("Invalid secret")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"message": "invalid secret",
-		})
-	}
-
-	fmt.Println("secret valid")
-
-	webhook := new(GithubWebhook)
-	if err := json.Unmarshal(body, webhook); err != nil {
-		slog.Error("Error unmarshalling body")
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"message": "error unmarshalling body",
-		})
-	}
-
-	pushDirectlyToDevelop := webhook.Ref == "refs/heads/dev" && !strings.Contains(webhook.HeadCommit.Message, "Merge pull request #")
-	pushDirectlyToStaging := webhook.Ref == "refs/heads/staging" && !strings.Contains(webhook.HeadCommit.Message, "Merge pull request #")
-	pushDirectlyToMaster := webhook.Ref == "refs/heads/master" && !strings.Contains(webhook.HeadCommit.Message, "Merge pull request #")
-	pullRequestMerged := webhook.Action == "closed" && webhook.PullRequest.Merged && webhook.PullRequest.Base.Ref == "master"
-	pullRequestMergedToStaging := webhook.Action == "closed" && webhook.PullRequest.Merged && webhook.PullRequest.Base.Ref == "staging"
-	pullRequestMergedToDevelop := webhook.Action == "closed" && webhook.PullRequest.Merged && webhook.PullRequest.Base.Ref == "dev"
-
-	fmt.Println("Queue size:", s.queue.Size())
-
-	switch {
-	case pushDirectlyToDevelop:
-		fmt.Println("pusher name", webhook.Pusher.Name)
-		fmt.Println("pusher email", webhook.Pusher.Email)
-		fmt.Println("pusher head commit id", webhook.HeadCommit.Id)
-		fmt.Println("pusher head commit message", webhook.HeadCommit.Message)
-
-		id, err := s.db.CreateUpdate(webhook.Pusher.Name, "dev", "pending", "in queue")
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-
-		}
-
-		options := sshclient.UpdateOptions{
-			ID:         id,
-			Repository: "dev",
-		}
-
-		s.queue.Enqueue(&options)
-
-	case pushDirectlyToStaging:
-		fmt.Println("pusher name", webhook.Pusher.Name)
-		fmt.Println("pusher email", webhook.Pusher.Email)
-		fmt.Println("pusher head commit id", webhook.HeadCommit.Id)
-		fmt.Println("pusher head commit message", webhook.HeadCommit.Message)
-
-		id, err := s.db.CreateUpdate(webhook.Pusher.Name, "staging", "pending", "in queue")
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-		}
-
-		options := sshclient.UpdateOptions{
-			ID:         id,
-			Repository: "staging",
-		}
-
-		s.queue.Enqueue(&options)
-	case pushDirectlyToMaster:
-		fmt.Println("pusher name", webhook.Pusher.Name)
-		fmt.Println("pusher email", webhook.Pusher.Email)
-		fmt.Println("pusher head commit id", webhook.HeadCommit.Id)
-		fmt.Println("pusher head commit message", webhook.HeadCommit.Message)
-
-		//id, err := s.db.CreateUpdate(webhook.Pusher.Name, "master", "pending", "in queue")
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-		}
-
-	//	s.queue.Enqueue(id)
-
-	case pullRequestMerged:
-		fmt.Println("pull merged", webhook.PullRequest.Merged)
-		fmt.Println("pull merged at", webhook.PullRequest.MergedAt)
-		fmt.Println("pull merged by", webhook.PullRequest.MergedBy.Login)
-		fmt.Println("pull head ref", webhook.PullRequest.Head.Ref)
-		fmt.Println("pull head repo", webhook.PullRequest.Head.Repo.FullName)
-		fmt.Println("pull base ref", webhook.PullRequest.Base.Ref)
-
-		//id, err := s.db.CreateUpdate(webhook.PullRequest.MergedBy.Login, webhook.PullRequest.Head.Ref, "pending", "in queue")
-
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-
-		}
-
-		// s.queue.Enqueue(id)
-
-	case pullRequestMergedToStaging:
-		fmt.Println("pull merged", webhook.PullRequest.Merged)
-		fmt.Println("pull merged at", webhook.PullRequest.MergedAt)
-		fmt.Println("pull merged by", webhook.PullRequest.MergedBy.Login)
-		fmt.Println("pull head ref", webhook.PullRequest.Head.Ref)
-		fmt.Println("pull head repo", webhook.PullRequest.Head.Repo.FullName)
-		fmt.Println("pull base ref", webhook.PullRequest.Base.Ref)
-
-		id, err := s.db.CreateUpdate(webhook.PullRequest.MergedBy.Login, webhook.PullRequest.Head.Ref, "pending", "in queue")
-
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-
-		}
-
-		options := sshclient.UpdateOptions{
-			ID:         id,
-			Repository: "staging",
-		}
-
-		s.queue.Enqueue(&options)
-	case pullRequestMergedToDevelop:
-		fmt.Println("pull merged", webhook.PullRequest.Merged)
-		fmt.Println("pull merged at", webhook.PullRequest.MergedAt)
-		fmt.Println("pull merged by", webhook.PullRequest.MergedBy.Login)
-		fmt.Println("pull head ref", webhook.PullRequest.Head.Ref)
-		fmt.Println("pull head repo", webhook.PullRequest.Head.Repo.FullName)
-		fmt.Println("pull base ref", webhook.PullRequest.Base.Ref)
-
-		id, err := s.db.CreateUpdate(webhook.PullRequest.MergedBy.Login, webhook.PullRequest.Head.Ref, "pending", "in queue")
-
-		// s.hub.Broadcast <- "update"
-
-		if err != nil {
-			slog.Error("Error creating update in database")
-			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"message": "error creating update in database",
-			})
-
-		}
-
-		options := sshclient.UpdateOptions{
-			ID:         id,
-			Repository: "dev",
-		}
-
-		s.queue.Enqueue(&options)
-	}
-
-	slog.Info("Repository added in queue")
-	return c.JSON(http.StatusOK, map[string]string{
-		"message": "ok",
-	})
-
-}
-
 func (s *Server) HelloWorldHandler(c echo.Context) error {
 	resp := map[string]string{
 		"message": "Hello World",
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// userRun loads the :id run when its pipeline belongs to the logged user,
+// writing the error response when it does not.
+func (s *Server) userRun(c echo.Context) (database.Update, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return database.Update{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return database.Update{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	update, err := s.db.GetUpdate(id)
+
+	if err == nil {
+		_, err = s.db.GetUserPipelineById(update.PipelineID, loggedUserId)
+	}
+
+	if err != nil {
+		return database.Update{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "run not found",
+		})
+	}
+
+	return update, true, nil
+}
+
+// GetRunHandler returns a pipeline run with the result of each server and
+// hook, and the runs it chained. The run that chained it is its parent_id.
+func (s *Server) GetRunHandler(c echo.Context) error {
+	update, ok, err := s.userRun(c)
+
+	if !ok {
+		return err
+	}
+
+	results, err := s.db.ListServerResults(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting run results",
+		})
+	}
+
+	hooks, err := s.db.ListHookResults(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting run results",
+		})
+	}
+
+	approvals, err := s.db.ListRunApprovals(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting run results",
+		})
+	}
+
+	children, err := s.db.ListChildUpdates(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting run results",
+		})
+	}
+
+	overrides, err := s.db.ListRunFreezeOverrides(update.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting run results",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"run":              update,
+		"servers":          results,
+		"hooks":            hooks,
+		"children":         children,
+		"approvals":        approvals,
+		"freeze_overrides": overrides,
+	})
+}
+
+// CancelRunHandler cancels a run. A run that did not start yet is cancelled
+// right away and dropped from the queue. A running one stops scheduling
+// servers and interrupts its scripts, rolling back the servers it deployed
+// when rollback=true, and is marked cancelled once they stopped.
+func (s *Server) CancelRunHandler(c echo.Context) error {
+	update, ok, err := s.userRun(c)
+
+	if !ok {
+		return err
+	}
+
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	user, err := s.db.GetUserByID(loggedUserId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	switch update.Status {
+	case "running":
+		if !s.sshclient.CancelRun(update.ID, loggedUserId, user.Name, c.FormValue("rollback") == "true") {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"message": "run is not running on this server",
+			})
+		}
+
+		return c.JSON(http.StatusAccepted, map[string]string{
+			"message": "cancelling",
+			"status":  update.Status,
+		})
+
+	case "pending", database.UpdateStatusWaitingCI, database.UpdateStatusAwaitingApproval, database.UpdateStatusFrozen:
+		if update.Status == "pending" {
+			s.queue.Remove(update.ID)
+		}
+
+		cancelled, err := s.db.CancelUpdate(update.ID, update.Status, loggedUserId, "cancelled by "+user.Name)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error cancelling run",
+			})
+		}
+
+		if !cancelled {
+			return c.JSON(http.StatusConflict, map[string]string{
+				"message": "run changed status, try again",
+			})
+		}
+
+		return c.JSON(http.StatusOK, map[string]string{
+			"message": "ok",
+			"status":  "cancelled",
+		})
+	}
+
+	return c.JSON(http.StatusConflict, map[string]string{
+		"message": "run already finished",
+	})
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/queue"
+	"auto-update/internal/sshclient"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cancelRun(t *testing.T, s *Server, id int64, userId int64) (int, string) {
+	c, rec := newContext(http.MethodPost, "/api/runs/cancel", "", userId, "id", strconv.FormatInt(id, 10))
+	require.NoError(t, s.CancelRunHandler(c))
+
+	return rec.Code, responseMessage(t, rec.Result())
+}
+
+func TestCancelRunWaiting(t *testing.T) {
+	statuses := []string{"pending", database.UpdateStatusWaitingCI, database.UpdateStatusAwaitingApproval, database.UpdateStatusFrozen}
+
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1}
+
+	for _, status := range statuses {
+		db.updates = append(db.updates, database.Update{ID: int64(len(db.updates) + 1), PipelineID: 1, Status: status})
+	}
+
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	for i, status := range statuses {
+		code, _ := cancelRun(t, s, int64(i+1), 1)
+
+		assert.Equal(t, http.StatusOK, code, status)
+		assert.Equal(t, "cancelled", db.updates[i].Status, status)
+		assert.Equal(t, int64(1), db.updates[i].CancelledBy, status)
+		assert.Equal(t, "cancelled by user", db.updates[i].Message, status)
+	}
+}
+
+func TestCancelRunFinished(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1}
+
+	for _, status := range []string{"success", "error", "cancelled", "rejected"} {
+		db.updates = append(db.updates, database.Update{ID: int64(len(db.updates) + 1), PipelineID: 1, Status: status})
+	}
+
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	for _, update := range db.updates {
+		code, message := cancelRun(t, s, update.ID, 1)
+
+		assert.Equal(t, http.StatusConflict, code, update.Status)
+		assert.Equal(t, "run already finished", message)
+	}
+
+	assert.Equal(t, "success", db.updates[0].Status)
+}
+
+func TestCancelRunNotRunningHere(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1}
+	db.updates = append(db.updates, database.Update{ID: 1, PipelineID: 1, Status: "running"})
+	s := &Server{db: db, queue: queue.NewUpdateQueue(), sshclient: &sshclient.SshClientService{}}
+
+	code, message := cancelRun(t, s, 1, 1)
+
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "run is not running on this server", message)
+	assert.Equal(t, "running", db.updates[0].Status)
+}
+
+func TestCancelRunOfAnotherUser(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 2}
+	db.updates = append(db.updates, database.Update{ID: 1, PipelineID: 1, Status: "pending"})
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	code, _ := cancelRun(t, s, 1, 1)
+
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, "pending", db.updates[0].Status)
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/schedule"
+	"auto-update/internal/sshclient"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// scheduleSweepPeriod is how often schedules are checked for due runs.
+const scheduleSweepPeriod = 30 * time.Second
+
+type ScheduleInfo struct {
+	PipelineID int64  `json:"pipeline_id"`
+	Cron       string `json:"cron"`
+	Timezone   string `json:"timezone"`
+	Tag        string `json:"tag"`
+	Branch     string `json:"branch"`
+	SHA        string `json:"sha"`
+	CatchUp    string `json:"catch_up"`
+}
+
+// userSchedule loads the :id schedule when it belongs to a pipeline of the
+// logged user, writing the error response when it does not.
+func (s *Server) userSchedule(c echo.Context) (models.Schedule, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.Schedule{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.Schedule{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	sched, err := s.db.GetSchedule(id)
+
+	if err == nil {
+		_, err = s.db.GetUserPipelineById(sched.PipelineID, loggedUserId)
+	}
+
+	if err != nil {
+		return models.Schedule{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "schedule not found",
+		})
+	}
+
+	return sched, true, nil
+}
+
+// validSchedule checks the expression, timezone and catch-up policy of a
+// schedule and computes its next run from now, writing the error response
+// when it is invalid.
+func validSchedule(c echo.Context, sched models.Schedule) (models.Schedule, bool, error) {
+	if sched.Timezone == "" {
+		sched.Timezone = "UTC"
+	}
+	if sched.CatchUp == "" {
+		sched.CatchUp = schedule.CatchUpSkip
+	}
+
+	err := schedule.ValidCatchUp(sched.CatchUp)
+
+	var next time.Time
+	if err == nil {
+		next, err = schedule.NextRun(sched.Cron, sched.Timezone, time.Now())
+	}
+
+	if err != nil {
+		return sched, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	sched.NextRunAt = nil
+	if !sched.Paused {
+		sched.NextRunAt = &next
+	}
+
+	return sched, true, nil
+}
+
+func (s *Server) CreateScheduleHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	scheduleInfo := new(ScheduleInfo)
+
+	if err := c.Bind(scheduleInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(scheduleInfo.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	sched, ok, err := validSchedule(c, models.Schedule{
+		PipelineID: scheduleInfo.PipelineID,
+		Cron:       scheduleInfo.Cron,
+		Timezone:   scheduleInfo.Timezone,
+		Tag:        scheduleInfo.Tag,
+		Branch:     scheduleInfo.Branch,
+		SHA:        scheduleInfo.SHA,
+		CatchUp:    scheduleInfo.CatchUp,
+	})
+
+	if !ok {
+		return err
+	}
+
+	id, err := s.db.CreateSchedule(&sched)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating schedule",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":     "ok",
+		"schedule_id": strconv.FormatInt(id, 10),
+	})
+}
+
+// UpdateScheduleHandler changes a schedule, its next run is computed again
+// from now so edits never trigger a catch-up.
+func (s *Server) UpdateScheduleHandler(c echo.Context) error {
+	sched, ok, err := s.userSchedule(c)
+
+	if !ok {
+		return err
+	}
+
+	scheduleInfo := new(ScheduleInfo)
+
+	if err := c.Bind(scheduleInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if scheduleInfo.Cron != "" {
+		sched.Cron = scheduleInfo.Cron
+	}
+	if scheduleInfo.Timezone != "" {
+		sched.Timezone = scheduleInfo.Timezone
+	}
+	if scheduleInfo.Tag != "" {
+		sched.Tag = scheduleInfo.Tag
+	}
+	if scheduleInfo.Branch != "" {
+		sched.Branch = scheduleInfo.Branch
+	}
+	if scheduleInfo.SHA != "" {
+		sched.SHA = scheduleInfo.SHA
+	}
+	if scheduleInfo.CatchUp != "" {
+		sched.CatchUp = scheduleInfo.CatchUp
+	}
+
+	return s.saveSchedule(c, sched)
+}
+
+func (s *Server) PauseScheduleHandler(c echo.Context) error {
+	sched, ok, err := s.userSchedule(c)
+
+	if !ok {
+		return err
+	}
+
+	sched.Paused = true
+
+	return s.saveSchedule(c, sched)
+}
+
+// ResumeScheduleHandler restarts a paused schedule from now, the runs missed
+// while it was paused are not caught up.
+func (s *Server) ResumeScheduleHandler(c echo.Context) error {
+	sched, ok, err := s.userSchedule(c)
+
+	if !ok {
+		return err
+	}
+
+	sched.Paused = false
+
+	return s.saveSchedule(c, sched)
+}
+
+func (s *Server) saveSchedule(c echo.Context, sched models.Schedule) error {
+	sched, ok, err := validSchedule(c, sched)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.UpdateSchedule(&sched); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating schedule",
+		})
+	}
+
+	return c.JSON(http.StatusOK, sched)
+}
+
+func (s *Server) DeleteScheduleHandler(c echo.Context) error {
+	sched, ok, err := s.userSchedule(c)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.DeleteSchedule(sched.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting schedule",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) ListSchedulesHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	pipelineId, err := strconv.ParseInt(c.Param("pipeline_id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(pipelineId, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	schedules, err := s.db.ListSchedules(pipelineId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting schedules",
+		})
+	}
+
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// runSchedules starts the runs of due schedules. It runs for the lifetime of
+// the server.
+func (s *Server) runSchedules() {
+	ticker := time.NewTicker(scheduleSweepPeriod)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		schedules, err := s.db.ListDueSchedules()
+
+		if err != nil {
+			continue
+		}
+
+		for _, sched := range schedules {
+			s.runSchedule(sched, time.Now())
+		}
+	}
+}
+
+// runSchedule claims the due occurrences of a schedule and enqueues as many
+// runs as its catch-up policy allows.
+func (s *Server) runSchedule(sched models.Schedule, now time.Time) {
+	cron, err := schedule.Parse(sched.Cron)
+
+	var loc *time.Location
+	if err == nil {
+		loc, err = time.LoadLocation(sched.Timezone)
+	}
+
+	if err != nil {
+		slog.Error("Error reading schedule", "schedule_id", sched.ID, "error", err)
+		return
+	}
+
+	runs, following := schedule.Due(cron, sched.NextRunAt.In(loc), now.In(loc), sched.CatchUp)
+
+	var next *time.Time
+	if !following.IsZero() {
+		next = &following
+	}
+
+	claimed, err := s.db.ClaimSchedule(sched.ID, *sched.NextRunAt, next)
+
+	if err != nil || !claimed {
+		return
+	}
+
+	if runs == 0 {
+		slog.Info("Skipped missed schedule runs", "schedule_id", sched.ID, "catch_up", sched.CatchUp)
+		return
+	}
+
+	pipeline, err := s.db.GetPipeline(sched.PipelineID)
+
+	if err != nil {
+		slog.Error("Error finding scheduled pipeline", "schedule_id", sched.ID, "error", err)
+		return
+	}
+
+	for i := 0; i < runs; i++ {
+		options := &sshclient.UpdateOptions{
+			PipelineID: sched.PipelineID,
+			Tag:        sched.Tag,
+			Branch:     sched.Branch,
+			SHA:        sched.SHA,
+			Pusher:     fmt.Sprintf("agendamento %d", sched.ID),
+		}
+
+		// Queued rather than launched so the catch-up runs of a schedule
+		// run one after the other.
+		run, err := s.runPipeline(pipeline, options, database.Update{
+			Message:    "execução agendada",
+			ScheduleID: sched.ID,
+		}, s.queue.Enqueue, nil)
+
+		if err != nil {
+			slog.Error("Error starting scheduled run", "schedule_id", sched.ID, "error", err)
+			continue
+		}
+
+		slog.Info("Started scheduled run", "schedule_id", sched.ID, "update_id", run.ID, "status", run.Status)
+	}
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type ScriptInfo struct {
+	Name string `json:"name"`
+	Body string `json:"body"`
+}
+
+// userScript loads the :id library script when it belongs to the logged
+// user, writing the error response when it does not.
+func (s *Server) userScript(c echo.Context) (models.Script, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.Script{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.Script{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	script, err := s.db.GetScript(id)
+
+	if err != nil || script.UserID != loggedUserId {
+		return models.Script{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "script not found",
+		})
+	}
+
+	return script, true, nil
+}
+
+func (s *Server) CreateScriptHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	scriptInfo := new(ScriptInfo)
+
+	if err := c.Bind(scriptInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if scriptInfo.Name == "" || scriptInfo.Body == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "name and body are required",
+		})
+	}
+
+	id, err := s.db.CreateScript(&models.Script{
+		UserID: loggedUserId,
+		Name:   scriptInfo.Name,
+		Body:   scriptInfo.Body,
+	})
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating script",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":   "ok",
+		"script_id": strconv.FormatInt(id, 10),
+	})
+}
+
+// UpdateScriptHandler edits a library script, a new body becomes a new
+// version used by every server referencing the script.
+func (s *Server) UpdateScriptHandler(c echo.Context) error {
+	script, ok, err := s.userScript(c)
+
+	if !ok {
+		return err
+	}
+
+	scriptInfo := new(ScriptInfo)
+
+	if err := c.Bind(scriptInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	err = s.db.UpdateScript(&models.UpdateScript{
+		ID:   script.ID,
+		Name: scriptInfo.Name,
+		Body: scriptInfo.Body,
+	})
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating script",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// DeleteScriptHandler removes a library script no server uses anymore.
+func (s *Server) DeleteScriptHandler(c echo.Context) error {
+	script, ok, err := s.userScript(c)
+
+	if !ok {
+		return err
+	}
+
+	servers, err := s.db.ListScriptServers(script.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting script",
+		})
+	}
+
+	if len(servers) > 0 {
+		return c.JSON(http.StatusConflict, map[string]string{
+			"message": "script is used by " + strconv.Itoa(len(servers)) + " server(s)",
+		})
+	}
+
+	if err := s.db.DeleteScript(script.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting script",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) ListScriptsHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	scripts, err := s.db.ListScripts(loggedUserId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting scripts",
+		})
+	}
+
+	return c.JSON(http.StatusOK, scripts)
+}
+
+// GetScriptHandler returns a library script with its versions, newest first.
+func (s *Server) GetScriptHandler(c echo.Context) error {
+	script, ok, err := s.userScript(c)
+
+	if !ok {
+		return err
+	}
+
+	versions, err := s.db.ListScriptVersions(script.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting script versions",
+		})
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"script":   script,
+		"versions": versions,
+	})
+}
+
+// ListScriptServersHandler returns the servers that use a library script.
+func (s *Server) ListScriptServersHandler(c echo.Context) error {
+	script, ok, err := s.userScript(c)
+
+	if !ok {
+		return err
+	}
+
+	servers, err := s.db.ListScriptServers(script.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting servers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, servers)
+}
+
+// validServerScript reports whether the logged user can make a server run the
+// library script, 0 being the server's own script.
+func (s *Server) validServerScript(c echo.Context, scriptId int64) bool {
+	if scriptId == 0 {
+		return true
+	}
+
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		return false
+	}
+
+	script, err := s.db.GetScript(scriptId)
+
+	return err == nil && script.UserID == loggedUserId
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"sync"
 	"time"
 
 	"auto-update/internal/database"
@@ -21,6 +22,10 @@ type Server struct {
 	queue     *queue.UpdateQueue
 	hub       *sse.Hub
 	sshclient *sshclient.SshClientService
+
+	// dispatchMu serializes webhook dispatches, so the push and the merged
+	// pull request of the same commit never both deploy it.
+	dispatchMu sync.Mutex
 }
 
 func NewServer(queue *queue.UpdateQueue) *http.Server {
@@ -43,6 +48,13 @@ func NewServer(queue *queue.UpdateQueue) *http.Server {
 		sshclient: sshclient.NewSshClientService(),
 	}
 
+	queue.OnFinished(NewServer.chainRuns)
+
+	go NewServer.expireCIGates()
+	go NewServer.expireApprovals()
+	go NewServer.runSchedules()
+	go NewServer.releaseFrozen()
+
 	// Declare Server config
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", NewServer.port),
@@ -2,6 +2,7 @@ package server
 
 import (
 	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
 	"auto-update/utils"
 	"fmt"
 	"log/slog"
@@ -29,6 +30,38 @@ func (s *Server) CreateServerHandler(c echo.Context) error {
 	fmt.Println("serverinfo", serverinfo.PipelineID)
 	fmt.Println("serverinfo", serverinfo)
 
+	var stageId int64
+
+	if serverinfo.StageID != nil {
+		stageId = *serverinfo.StageID
+
+		if !s.validServerStage(stageId, serverinfo.PipelineID) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "stage not found in pipeline",
+			})
+		}
+	}
+
+	if serverinfo.ScriptID != nil && !s.validServerScript(c, *serverinfo.ScriptID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "script not found",
+		})
+	}
+
+	if serverinfo.TimeoutSeconds != nil && *serverinfo.TimeoutSeconds < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "timeout cannot be negative",
+		})
+	}
+
+	maxAttempts, backoffSeconds, retryOn, retry := serverRetry(serverinfo, models.UpdateServer{})
+
+	if err := deploy.ValidRetry(maxAttempts, backoffSeconds, retryOn); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
 	hashedPassword, err := utils.Encrypt(serverinfo.Password)
 
 	if err != nil {
@@ -39,7 +72,9 @@ func (s *Server) CreateServerHandler(c echo.Context) error {
 		})
 	}
 
-	newId, err := s.db.CreateServer(serverinfo.Host, hashedPassword, serverinfo.Script, serverinfo.PipelineID, serverinfo.Label)
+	canary := serverinfo.Canary != nil && *serverinfo.Canary
+
+	newId, err := s.db.CreateServer(serverinfo.Host, hashedPassword, serverinfo.Script, serverinfo.PipelineID, serverinfo.Label, stageId, canary, serverinfo.RollbackScript)
 
 	if err != nil {
 		slog.Error("Error creating server", "error", err)
@@ -48,6 +83,30 @@ func (s *Server) CreateServerHandler(c echo.Context) error {
 		})
 	}
 
+	if serverinfo.ScriptID != nil {
+		if err := s.db.SetServerScript(newId, *serverinfo.ScriptID, serverinfo.ScriptParams); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error creating server",
+			})
+		}
+	}
+
+	if serverinfo.TimeoutSeconds != nil {
+		if err := s.db.SetServerTimeout(newId, *serverinfo.TimeoutSeconds); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error creating server",
+			})
+		}
+	}
+
+	if retry {
+		if err := s.db.SetServerRetry(newId, maxAttempts, backoffSeconds, retryOn); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error creating server",
+			})
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message":   "ok",
 		"server_id": strconv.FormatInt(newId, 10),
@@ -73,6 +132,28 @@ func (s *Server) UpdateServerHandler(c echo.Context) error {
 		})
 	}
 
+	if serverinfo.TimeoutSeconds != nil && *serverinfo.TimeoutSeconds < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "timeout cannot be negative",
+		})
+	}
+
+	current, err := s.db.GetServer(id)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "server not found",
+		})
+	}
+
+	maxAttempts, backoffSeconds, retryOn, retry := serverRetry(serverinfo, *current)
+
+	if err := deploy.ValidRetry(maxAttempts, backoffSeconds, retryOn); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
 	if serverinfo.Password != "" {
 		serverinfo.Password, err = generateHashPassword(serverinfo.Password)
 
@@ -92,6 +173,20 @@ func (s *Server) UpdateServerHandler(c echo.Context) error {
 		Label:      serverinfo.Label,
 		PipelineID: serverinfo.PipelineID,
 		Active:     serverinfo.Active,
+
+		RollbackScript: serverinfo.RollbackScript,
+	}
+
+	if serverinfo.StageID != nil && !s.validServerStage(*serverinfo.StageID, current.PipelineID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "stage not found in pipeline",
+		})
+	}
+
+	if serverinfo.ScriptID != nil && !s.validServerScript(c, *serverinfo.ScriptID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "script not found",
+		})
 	}
 
 	err = s.db.UpdateServer(updateServer)
@@ -102,6 +197,46 @@ func (s *Server) UpdateServerHandler(c echo.Context) error {
 		})
 	}
 
+	if serverinfo.StageID != nil {
+		if err := s.db.SetServerStage(id, *serverinfo.StageID); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error updating server",
+			})
+		}
+	}
+
+	if serverinfo.ScriptID != nil {
+		if err := s.db.SetServerScript(id, *serverinfo.ScriptID, serverinfo.ScriptParams); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error updating server",
+			})
+		}
+	}
+
+	if serverinfo.Canary != nil {
+		if err := s.db.SetServerCanary(id, *serverinfo.Canary); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error updating server",
+			})
+		}
+	}
+
+	if serverinfo.TimeoutSeconds != nil {
+		if err := s.db.SetServerTimeout(id, *serverinfo.TimeoutSeconds); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error updating server",
+			})
+		}
+	}
+
+	if retry {
+		if err := s.db.SetServerRetry(id, maxAttempts, backoffSeconds, retryOn); err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error updating server",
+			})
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "ok",
 	})
@@ -160,3 +295,82 @@ func (s *Server) UpdatePasswords(c echo.Context) error {
 		"message": "passwords updated",
 	})
 }
+
+// validServerStage reports whether a server of the pipeline can be put in the
+// stage, 0 being the default stage every pipeline has.
+func (s *Server) validServerStage(stageId int64, pipelineId int64) bool {
+	if stageId == 0 {
+		return true
+	}
+
+	stage, err := s.db.GetStage(stageId)
+
+	return err == nil && stage.PipelineID == pipelineId
+}
+
+// serverRetry merges the retry settings of a request into the current policy
+// of a server, reporting whether the request set any of them.
+func serverRetry(serverinfo *ServerInfo, current models.UpdateServer) (int, int, string, bool) {
+	retry := serverinfo.RetryMaxAttempts != nil || serverinfo.RetryBackoffSeconds != nil || serverinfo.RetryOn != nil
+
+	return valueOr(serverinfo.RetryMaxAttempts, current.RetryMaxAttempts),
+		valueOr(serverinfo.RetryBackoffSeconds, current.RetryBackoffSeconds),
+		valueOr(serverinfo.RetryOn, current.RetryOn),
+		retry
+}
+
+// ServerAttemptsHandler lists the latest deploy attempts of a server with a
+// summary of how often it needed a retry.
+func (s *Server) ServerAttemptsHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	server, err := s.db.GetServer(id)
+
+	if err == nil {
+		_, err = s.db.GetUserPipelineById(server.PipelineID, loggedUserId)
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "server not found",
+		})
+	}
+
+	limit, ok, err := formInt(c, "limit")
+
+	if err != nil || (ok && limit <= 0) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid limit",
+		})
+	}
+
+	if !ok {
+		limit = 100
+	}
+
+	attempts, err := s.db.ListServerAttempts(id, limit)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error listing attempts",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"attempts":  attempts,
+		"flakiness": deploy.Flakiness(attempts),
+	})
+}
@@ -0,0 +1,198 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type StageInfo struct {
+	PipelineID int64  `json:"pipeline_id"`
+	Name       string `json:"name"`
+	Position   *int   `json:"position"`
+}
+
+// userStage loads the :id stage when it belongs to a pipeline of the logged
+// user, writing the error response when it does not.
+func (s *Server) userStage(c echo.Context) (models.Stage, bool, error) {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return models.Stage{}, false, c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return models.Stage{}, false, c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	stage, err := s.db.GetStage(id)
+
+	if err == nil {
+		_, err = s.db.GetUserPipelineById(stage.PipelineID, loggedUserId)
+	}
+
+	if err != nil {
+		return models.Stage{}, false, c.JSON(http.StatusNotFound, map[string]string{
+			"message": "stage not found",
+		})
+	}
+
+	return stage, true, nil
+}
+
+func (s *Server) CreateStageHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	stageInfo := new(StageInfo)
+
+	if err := c.Bind(stageInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if stageInfo.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "name is required",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(stageInfo.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	stage := &models.Stage{
+		PipelineID: stageInfo.PipelineID,
+		Name:       stageInfo.Name,
+	}
+
+	if stageInfo.Position != nil {
+		stage.Position = *stageInfo.Position
+	} else {
+		// New stages go after the existing ones.
+		stages, err := s.db.ListStages(stageInfo.PipelineID)
+
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"message": "error creating stage",
+			})
+		}
+
+		for _, existing := range stages {
+			if existing.Position >= stage.Position {
+				stage.Position = existing.Position + 1
+			}
+		}
+	}
+
+	id, err := s.db.CreateStage(stage)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating stage",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":  "ok",
+		"stage_id": strconv.FormatInt(id, 10),
+	})
+}
+
+func (s *Server) UpdateStageHandler(c echo.Context) error {
+	stage, ok, err := s.userStage(c)
+
+	if !ok {
+		return err
+	}
+
+	stageInfo := new(StageInfo)
+
+	if err := c.Bind(stageInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	err = s.db.UpdateStage(&models.UpdateStage{
+		ID:       stage.ID,
+		Name:     stageInfo.Name,
+		Position: stageInfo.Position,
+	})
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating stage",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) DeleteStageHandler(c echo.Context) error {
+	stage, ok, err := s.userStage(c)
+
+	if !ok {
+		return err
+	}
+
+	if err := s.db.DeleteStage(stage.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting stage",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) ListStagesHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	pipelineId, err := strconv.ParseInt(c.Param("pipeline_id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(pipelineId, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	stages, err := s.db.ListStages(pipelineId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting stages",
+		})
+	}
+
+	return c.JSON(http.StatusOK, stages)
+}
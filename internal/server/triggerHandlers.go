@@ -0,0 +1,255 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/webhooks"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type TriggerInfo struct {
+	PipelineID    int64    `json:"pipeline_id"`
+	Repository    string   `json:"repository"`
+	BranchPattern string   `json:"branch_pattern"`
+	EventType     string   `json:"event_type"`
+	TagPattern    string   `json:"tag_pattern"`
+	IncludePaths  []string `json:"include_paths"`
+	ExcludePaths  []string `json:"exclude_paths"`
+	Active        *bool    `json:"active"`
+	// WaitForCI makes a push wait for a successful CI run before deploying.
+	WaitForCI        *bool `json:"wait_for_ci"`
+	CITimeoutMinutes int   `json:"ci_timeout_minutes"`
+}
+
+func (s *Server) CreateTriggerHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	triggerInfo := new(TriggerInfo)
+
+	if err := c.Bind(triggerInfo); err != nil {
+		slog.Error("Error binding body", "error", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if triggerInfo.Repository == "" || !webhooks.IsValidEventType(triggerInfo.EventType) || !webhooks.ValidPattern(triggerInfo.BranchPattern) || !webhooks.ValidTagPattern(triggerInfo.TagPattern) || !webhooks.ValidPaths(triggerInfo.IncludePaths) || !webhooks.ValidPaths(triggerInfo.ExcludePaths) || triggerInfo.CITimeoutMinutes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid trigger rule",
+		})
+	}
+
+	if triggerInfo.WaitForCI != nil && *triggerInfo.WaitForCI && !webhooks.WaitsForCI(triggerInfo.EventType) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "wait_for_ci needs a push or pull_request trigger",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(triggerInfo.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	trigger := &models.WebhookTrigger{
+		PipelineID:    triggerInfo.PipelineID,
+		Repository:    triggerInfo.Repository,
+		BranchPattern: triggerInfo.BranchPattern,
+		EventType:     triggerInfo.EventType,
+		TagPattern:    triggerInfo.TagPattern,
+		IncludePaths:  triggerInfo.IncludePaths,
+		ExcludePaths:  triggerInfo.ExcludePaths,
+		Active:        triggerInfo.Active == nil || *triggerInfo.Active,
+		WaitForCI:     triggerInfo.WaitForCI != nil && *triggerInfo.WaitForCI,
+	}
+
+	trigger.CITimeoutMinutes = triggerInfo.CITimeoutMinutes
+
+	if trigger.CITimeoutMinutes == 0 {
+		trigger.CITimeoutMinutes = int(defaultCITimeout / time.Minute)
+	}
+
+	id, err := s.db.CreateWebhookTrigger(trigger)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error creating trigger",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message":    "ok",
+		"trigger_id": strconv.FormatInt(id, 10),
+	})
+}
+
+func (s *Server) UpdateTriggerHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	trigger, err := s.db.GetWebhookTrigger(id)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "trigger not found",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(trigger.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "trigger not found",
+		})
+	}
+
+	triggerInfo := new(TriggerInfo)
+
+	if err := c.Bind(triggerInfo); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	if (triggerInfo.EventType != "" && !webhooks.IsValidEventType(triggerInfo.EventType)) || !webhooks.ValidPattern(triggerInfo.BranchPattern) || !webhooks.ValidTagPattern(triggerInfo.TagPattern) || !webhooks.ValidPaths(triggerInfo.IncludePaths) || !webhooks.ValidPaths(triggerInfo.ExcludePaths) || triggerInfo.CITimeoutMinutes < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid trigger rule",
+		})
+	}
+
+	eventType, waitForCI := trigger.EventType, trigger.WaitForCI
+
+	if triggerInfo.EventType != "" {
+		eventType = triggerInfo.EventType
+	}
+
+	if triggerInfo.WaitForCI != nil {
+		waitForCI = *triggerInfo.WaitForCI
+	}
+
+	if waitForCI && !webhooks.WaitsForCI(eventType) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "wait_for_ci needs a push or pull_request trigger",
+		})
+	}
+
+	updateTrigger := &models.UpdateWebhookTrigger{
+		ID:               id,
+		Repository:       triggerInfo.Repository,
+		BranchPattern:    triggerInfo.BranchPattern,
+		EventType:        triggerInfo.EventType,
+		TagPattern:       triggerInfo.TagPattern,
+		IncludePaths:     triggerInfo.IncludePaths,
+		ExcludePaths:     triggerInfo.ExcludePaths,
+		Active:           triggerInfo.Active,
+		WaitForCI:        triggerInfo.WaitForCI,
+		CITimeoutMinutes: triggerInfo.CITimeoutMinutes,
+	}
+
+	err = s.db.UpdateWebhookTrigger(updateTrigger)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error updating trigger",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) DeleteTriggerHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	trigger, err := s.db.GetWebhookTrigger(id)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "trigger not found",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(trigger.PipelineID, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "trigger not found",
+		})
+	}
+
+	err = s.db.DeleteWebhookTrigger(id)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error deleting trigger",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+func (s *Server) ListTriggersHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	pipelineId, err := strconv.ParseInt(c.Param("pipeline_id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	if _, err := s.db.GetUserPipelineById(pipelineId, loggedUserId); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "user pipeline not found",
+		})
+	}
+
+	triggers, err := s.db.ListWebhookTriggers(pipelineId)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting triggers",
+		})
+	}
+
+	return c.JSON(http.StatusOK, triggers)
+}
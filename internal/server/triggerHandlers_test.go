@@ -0,0 +1,45 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/webhooks"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateTriggerRefusesWaitForCIOnTags(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[10] = models.Pipeline{ID: 10, UserID: 1}
+	s := &Server{db: db}
+
+	for _, eventType := range []string{webhooks.EventTag, webhooks.EventRelease} {
+		c, rec := newContext(http.MethodPost, "/api/triggers/create", `{"pipeline_id": 10, "repository": "acme/web", "event_type": "`+eventType+`", "wait_for_ci": true}`, 1)
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		require.NoError(t, s.CreateTriggerHandler(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, eventType)
+	}
+}
+
+func TestUpdateTriggerRefusesWaitForCIOnTags(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[10] = models.Pipeline{ID: 10, UserID: 1}
+	db.triggers[5] = models.WebhookTrigger{ID: 5, PipelineID: 10, Repository: "acme/web", EventType: webhooks.EventPush, WaitForCI: true}
+	db.triggers[6] = models.WebhookTrigger{ID: 6, PipelineID: 10, Repository: "acme/web", EventType: webhooks.EventRelease}
+	s := &Server{db: db}
+
+	for id, body := range map[string]string{
+		"5": `{"event_type": "release"}`,
+		"6": `{"wait_for_ci": true}`,
+	} {
+		c, rec := newContext(http.MethodPut, "/api/triggers/update/"+id, body, 1, "id", id)
+		c.Request().Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		require.NoError(t, s.UpdateTriggerHandler(c))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "trigger %s", id)
+	}
+}
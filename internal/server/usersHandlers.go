@@ -39,6 +39,16 @@ func getLoggedUserId(loggedUser *jwt.Token) (int64, error) {
 	return id, nil
 }
 
+func getLoggedUserIdFromContext(c echo.Context) (int64, error) {
+	loggedUser, ok := c.Get("user").(*jwt.Token)
+
+	if !ok {
+		return 0, errors.New("Error getting logged user context")
+	}
+
+	return getLoggedUserId(loggedUser)
+}
+
 func generateHashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	return string(bytes), err
@@ -63,14 +73,14 @@ func (s *Server) CreateUserHandler(c echo.Context) error {
 	password, err := generateHashPassword(createUser.Password)
 
 	if err != nil {
-		slog.Error("error generating password hash", err)
+		slog.Error("error generating password hash", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	id, err := s.db.CreateUser(createUser.Name, createUser.Email, password)
 	fmt.Println("bolamaaax")
 	if err != nil {
-		slog.Error("error creating user", err)
+		slog.Error("error creating user", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -97,7 +107,7 @@ func (s *Server) UpdateUserHandler(c echo.Context) error {
 	id, err := strconv.ParseInt(stringID, 10, 64)
 
 	if err != nil {
-		slog.Error("error parsing id", err)
+		slog.Error("error parsing id", "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": err.Error()})
 
 	}
@@ -110,7 +120,7 @@ func (s *Server) UpdateUserHandler(c echo.Context) error {
 	updateUserRequest := new(models.UpdateUserRequest)
 
 	if err := c.Bind(updateUserRequest); err != nil {
-		slog.Error("error updating user", err)
+		slog.Error("error updating user", "error", err)
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "invalid request fields"})
 	}
 
@@ -130,7 +140,7 @@ func (s *Server) UpdateUserHandler(c echo.Context) error {
 		password, err := generateHashPassword(updateUserRequest.Password)
 
 		if err != nil {
-			slog.Error("error generating password hash", err)
+			slog.Error("error generating password hash", "error", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
@@ -139,14 +149,14 @@ func (s *Server) UpdateUserHandler(c echo.Context) error {
 	}
 
 	if err != nil {
-		slog.Error("error generating password hash", err)
+		slog.Error("error generating password hash", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
 	err = s.db.UpdateUser(updateUser)
 
 	if err != nil {
-		slog.Error("error updating user", err)
+		slog.Error("error updating user", "error", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 
@@ -159,7 +169,7 @@ func (s *Server) DeleteUserHandler(c echo.Context) error {
 	id, err := strconv.ParseInt(userID, 10, 64)
 
 	if err != nil {
-		slog.Error("error parsing id", err)
+		slog.Error("error parsing id", "error", err)
 
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
@@ -196,7 +206,7 @@ func (s *Server) ListUsersHandler(c echo.Context) error {
 	users, err := s.db.ListUsers(pageInt, limitInt)
 
 	if err != nil {
-		slog.Error("error listing users", err)
+		slog.Error("error listing users", "error", err)
 
 		return c.JSON(http.StatusBadRequest, map[string]string{"message": "error listing users"})
 	}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/internal/sshclient"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type VariablesInfo struct {
+	// ServerID sets the overrides of a server of the pipeline instead of the
+	// pipeline variables.
+	ServerID  int64             `json:"server_id"`
+	Variables map[string]string `json:"variables"`
+}
+
+// GetVariablesHandler returns the pipeline variables and the overrides of
+// each server.
+func (s *Server) GetVariablesHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	variables, err := s.db.ListVariables(id)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting variables",
+		})
+	}
+
+	pipeline, servers := deploy.Variables(variables)
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"pipeline": pipeline,
+		"servers":  servers,
+	})
+}
+
+// SetVariablesHandler replaces the pipeline variables, or a server's
+// overrides.
+func (s *Server) SetVariablesHandler(c echo.Context) error {
+	id, ok, err := s.userPipelineParam(c)
+
+	if !ok {
+		return err
+	}
+
+	info := new(VariablesInfo)
+
+	if err := c.Bind(info); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid request",
+		})
+	}
+
+	for name := range info.Variables {
+		if !deploy.ValidVariableName(name) {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "invalid variable name: " + name,
+			})
+		}
+	}
+
+	if info.ServerID != 0 {
+		server, err := s.db.GetServer(info.ServerID)
+
+		if err != nil || server.PipelineID != id {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"message": "server not found in pipeline",
+			})
+		}
+	}
+
+	if err := s.db.SetVariables(id, info.ServerID, info.Variables); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error saving variables",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "ok",
+	})
+}
+
+// PreviewScriptHandler renders the scripts of a server as a run with the sha,
+// branch, tag, pusher and run_id query params would.
+func (s *Server) PreviewScriptHandler(c echo.Context) error {
+	loggedUserId, err := getLoggedUserIdFromContext(c)
+
+	if err != nil {
+		slog.Error("Error getting logged user id", "error", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "internal server error"})
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": "invalid id",
+		})
+	}
+
+	server, err := s.db.GetServer(id)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "server not found",
+		})
+	}
+
+	pipeline, err := s.db.GetUserPipelineById(server.PipelineID, loggedUserId)
+
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "server not found",
+		})
+	}
+
+	variables, err := s.db.ListVariables(pipeline.ID)
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting variables",
+		})
+	}
+
+	runId, _ := strconv.ParseInt(c.QueryParam("run_id"), 10, 64)
+
+	options := &sshclient.UpdateOptions{
+		ID:     runId,
+		Tag:    c.QueryParam("tag"),
+		Branch: c.QueryParam("branch"),
+		SHA:    c.QueryParam("sha"),
+		Pusher: c.QueryParam("pusher"),
+	}
+
+	servers := deploy.WithRollbackScript([]models.UpdateServer{*server}, pipeline)
+
+	scripts, err := s.db.GetScripts(deploy.ScriptIDs(servers))
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error getting scripts",
+		})
+	}
+
+	servers, err = deploy.ResolveScripts(servers, scripts)
+
+	if err == nil {
+		servers, err = deploy.RenderServers(servers, options.ScriptData(pipeline), variables)
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"script":          servers[0].Script,
+		"rollback_script": servers[0].RollbackScript,
+	})
+}
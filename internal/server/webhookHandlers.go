@@ -0,0 +1,457 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/sshclient"
+	"auto-update/internal/webhooks"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type TriggeredPipeline struct {
+	PipelineID int64  `json:"pipeline_id"`
+	Name       string `json:"name"`
+	UpdateID   int64  `json:"update_id"`
+	WaitingCI  bool   `json:"waiting_ci,omitempty"`
+	// AwaitingApproval is set when the pipeline requires approval before the
+	// update deploys.
+	AwaitingApproval bool `json:"awaiting_approval,omitempty"`
+	// Note is recorded with the delivery, such as path filters that could
+	// not be checked.
+	Note string `json:"note,omitempty"`
+}
+
+// SkippedPipeline is a pipeline a delivery matched but did not deploy. The
+// update id is set when the skip was recorded as an update, which is the case
+// for commit message directives and deployment freezes.
+type SkippedPipeline struct {
+	PipelineID int64  `json:"pipeline_id"`
+	TriggerID  int64  `json:"trigger_id"`
+	Reason     string `json:"reason"`
+	UpdateID   int64  `json:"update_id,omitempty"`
+}
+
+// dispatchWebhookEvent creates an update for every pipeline whose trigger
+// rules match the event and puts it in the update queue, unless it waits for
+// CI or, like a manual run, the pipeline requires approval or is frozen.
+// Pipelines left out by the path filters of their rules, by a directive in
+// the commit message, by a freeze or because the commit already triggered
+// them are returned with the reason.
+//
+// A delivery to a pipeline's own endpoint (pipelineId != 0) only considers
+// that pipeline's rules. Deliveries to the shared endpoints never trigger a
+// pipeline that has its own endpoint, so the global secret can not deploy it.
+func (s *Server) dispatchWebhookEvent(event webhooks.Event, pipelineId int64) ([]TriggeredPipeline, []SkippedPipeline, error) {
+	triggered := make([]TriggeredPipeline, 0)
+	skipped := make([]SkippedPipeline, 0)
+
+	triggers, err := s.db.ListActiveWebhookTriggers(event.Repository)
+
+	if err != nil {
+		slog.Error("Error getting webhook triggers", "error", err)
+		return triggered, skipped, err
+	}
+
+	if pipelineId != 0 {
+		scoped := make([]models.WebhookTrigger, 0, len(triggers))
+		for _, trigger := range triggers {
+			if trigger.PipelineID == pipelineId {
+				scoped = append(scoped, trigger)
+			}
+		}
+		triggers = scoped
+	}
+
+	matched, skippedTriggers := webhooks.MatchTriggers(triggers, event)
+
+	for _, skip := range skippedTriggers {
+		slog.Info("Skipping pipeline by path filter", "pipeline_id", skip.Trigger.PipelineID, "trigger_id", skip.Trigger.ID, "reason", skip.Reason)
+		skipped = append(skipped, SkippedPipeline{
+			PipelineID: skip.Trigger.PipelineID,
+			TriggerID:  skip.Trigger.ID,
+			Reason:     skip.Reason,
+		})
+	}
+
+	directive := webhooks.ParseDirective(event.Message)
+
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	for _, trigger := range matched {
+		note := webhooks.PathsUnchecked(trigger.IncludePaths, trigger.ExcludePaths, event)
+
+		if note != "" {
+			slog.Info("Path filters not checked", "pipeline_id", trigger.PipelineID, "trigger_id", trigger.ID, "provider", event.Provider)
+		}
+
+		pipeline, err := s.db.GetPipeline(trigger.PipelineID)
+
+		if err != nil {
+			slog.Error("Error getting triggered pipeline", "pipeline_id", trigger.PipelineID, "error", err)
+			continue
+		}
+
+		if pipelineId == 0 {
+			if _, err := s.db.GetPipelineWebhook(pipeline.ID); err == nil {
+				skipped = append(skipped, SkippedPipeline{
+					PipelineID: pipeline.ID,
+					TriggerID:  trigger.ID,
+					Reason:     "pipeline only accepts deliveries on its own webhook endpoint",
+				})
+				continue
+			}
+		}
+
+		// Merging a pull request also pushes its merge commit, a pipeline
+		// triggered by both only deploys it once.
+		if (event.Type == webhooks.EventPush || event.Type == webhooks.EventPullRequest) && event.SHA != "" {
+			previous, err := s.db.GetCommitUpdate(pipeline.ID, event.Repository, event.SHA)
+
+			if err == nil {
+				skipped = append(skipped, SkippedPipeline{
+					PipelineID: pipeline.ID,
+					TriggerID:  trigger.ID,
+					Reason:     fmt.Sprintf("commit %s already triggered update %d", event.SHA, previous.ID),
+				})
+				continue
+			}
+
+			if !errors.Is(err, sql.ErrNoRows) {
+				return triggered, skipped, err
+			}
+		}
+
+		options := &sshclient.UpdateOptions{
+			PipelineID: pipeline.ID,
+			Tag:        event.Tag,
+			Branch:     event.Branch,
+			SHA:        event.SHA,
+			Pusher:     event.Author,
+			Only:       directive.Only,
+		}
+
+		run := database.Update{
+			Message:    "in queue",
+			Directive:  directive.String(),
+			Repository: event.Repository,
+		}
+
+		if reason := directive.SkipReason(pipeline.Name); reason != "" {
+			run.Status, run.Message = "skipped", reason
+
+			id, err := s.createRun(options, run)
+
+			if err != nil {
+				return triggered, skipped, err
+			}
+
+			slog.Info("Skipping pipeline by commit directive", "pipeline_id", pipeline.ID, "directive", run.Directive)
+			skipped = append(skipped, SkippedPipeline{
+				PipelineID: pipeline.ID,
+				TriggerID:  trigger.ID,
+				Reason:     reason,
+				UpdateID:   id,
+			})
+			continue
+		}
+
+		// Updates waiting for CI go through the approval and freeze gates
+		// once CI passed, see resolveCIGates, so a freeze never releases an
+		// untested commit.
+		if trigger.WaitForCI {
+			deadline := ciDeadline(trigger.CITimeoutMinutes)
+			run.Status, run.Message, run.CIDeadline = database.UpdateStatusWaitingCI, "waiting for CI", &deadline
+
+			id, err := s.createRun(options, run)
+
+			if err != nil {
+				return triggered, skipped, err
+			}
+
+			triggered = append(triggered, TriggeredPipeline{
+				PipelineID: pipeline.ID,
+				Name:       pipeline.Name,
+				UpdateID:   id,
+				WaitingCI:  true,
+				Note:       note,
+			})
+			continue
+		}
+
+		run, err = s.runPipeline(pipeline, options, run, s.queue.Enqueue, nil)
+
+		if err != nil {
+			return triggered, skipped, err
+		}
+
+		if run.Status == database.UpdateStatusFrozen || run.Status == "rejected" {
+			slog.Info("Holding pipeline by deployment freeze", "pipeline_id", pipeline.ID, "status", run.Status)
+			skipped = append(skipped, SkippedPipeline{
+				PipelineID: pipeline.ID,
+				TriggerID:  trigger.ID,
+				Reason:     run.Message,
+				UpdateID:   run.ID,
+			})
+			continue
+		}
+
+		triggered = append(triggered, TriggeredPipeline{
+			PipelineID:       pipeline.ID,
+			Name:             pipeline.Name,
+			UpdateID:         run.ID,
+			AwaitingApproval: run.Status == database.UpdateStatusAwaitingApproval,
+			Note:             note,
+		})
+	}
+
+	return triggered, skipped, nil
+}
+
+// GithubWebhookHandler serves /github-webhook and /webhooks/:provider, picking
+// the provider from the route so every git host shares the same routing.
+func (s *Server) GithubWebhookHandler(c echo.Context) error {
+	name := c.Param("provider")
+
+	if name == "" {
+		name = webhooks.Github.Name()
+	}
+
+	provider, ok := webhooks.GetProvider(name)
+
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{
+			"message": "unknown webhook provider",
+		})
+	}
+
+	return s.handleWebhook(c, provider, 0, os.Getenv("SECRET_KEY"))
+}
+
+func (s *Server) GitlabWebhookHandler(c echo.Context) error {
+	return s.handleWebhook(c, webhooks.Gitlab, 0, os.Getenv("SECRET_KEY"))
+}
+
+// webhookResult is what routing a delivery produced, both for the response
+// and for the decision stored in the delivery log.
+type webhookResult struct {
+	Status    int
+	Message   string
+	Decision  string
+	Triggered []TriggeredPipeline
+	Skipped   []SkippedPipeline
+}
+
+func (r webhookResult) updateIds() []int64 {
+	ids := make([]int64, 0, len(r.Triggered))
+	for _, t := range r.Triggered {
+		ids = append(ids, t.UpdateID)
+	}
+
+	for _, skip := range r.Skipped {
+		if skip.UpdateID != 0 {
+			ids = append(ids, skip.UpdateID)
+		}
+	}
+
+	return ids
+}
+
+// recordedHeaders serializes the delivery headers for the delivery log,
+// leaving out the ones that carry a secret.
+func recordedHeaders(header http.Header) json.RawMessage {
+	recorded := header.Clone()
+	recorded.Del("X-Gitlab-Token")
+	recorded.Del("Authorization")
+
+	headers, err := json.Marshal(recorded)
+
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+
+	return headers
+}
+
+// handleWebhook verifies, records and routes a delivery. pipelineId is set for
+// deliveries to a pipeline's own endpoint, secrets are the values the
+// signature is accepted with.
+func (s *Server) handleWebhook(c echo.Context, provider webhooks.Provider, pipelineId int64, secrets ...string) error {
+	body, err := io.ReadAll(c.Request().Body)
+
+	if err != nil {
+		slog.Error("Error reading body")
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error reading body",
+		})
+	}
+
+	header := c.Request().Header
+	deliveryId := provider.DeliveryID(header)
+
+	if deliveryId == "" {
+		deliveryId = uuid.NewString()
+	}
+
+	signatureValid := webhooks.VerifyAny(provider, header, body, secrets...) == nil
+
+	// Only signed deliveries claim their id: an unsigned request can not
+	// block a genuine delivery, nor can one with a secret fixed later.
+	id, err := s.db.CreateWebhookDelivery(&models.WebhookDelivery{
+		DeliveryID:     deliveryId,
+		Provider:       provider.Name(),
+		EventType:      provider.EventType(header),
+		Headers:        recordedHeaders(header),
+		Body:           string(body),
+		SignatureValid: signatureValid,
+		Decision:       "received",
+		PipelineID:     pipelineId,
+		Claimed:        signatureValid,
+	})
+
+	if errors.Is(err, database.ErrDuplicateDelivery) {
+		slog.Info("Ignoring duplicate delivery", "provider", provider.Name(), "delivery_id", deliveryId)
+		return c.JSON(http.StatusOK, echo.Map{
+			"message":   "duplicate delivery ignored",
+			"pipelines": []TriggeredPipeline{},
+		})
+	}
+
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"message": "error recording delivery",
+		})
+	}
+
+	var result webhookResult
+
+	if signatureValid {
+		result = s.routeWebhook(provider, header, body, pipelineId)
+	} else {
+		slog.Error("Invalid secret", "provider", provider.Name())
+		result = webhookResult{
+			Status:    http.StatusUnauthorized,
+			Message:   "invalid secret",
+			Decision:  "rejected: invalid signature",
+			Triggered: []TriggeredPipeline{},
+		}
+	}
+
+	if err := s.db.UpdateWebhookDeliveryDecision(id, result.Decision, result.updateIds()); err != nil {
+		slog.Error("Error recording delivery decision", "delivery", id, "error", err)
+	}
+
+	// A delivery that failed to route is not a duplicate of its retry.
+	if signatureValid && result.Status >= http.StatusBadRequest {
+		if err := s.db.ReleaseWebhookDelivery(id); err != nil {
+			slog.Error("Error releasing delivery", "delivery", id, "error", err)
+		}
+	}
+
+	return c.JSON(result.Status, echo.Map{
+		"message":   result.Message,
+		"pipelines": result.Triggered,
+		"skipped":   result.Skipped,
+	})
+}
+
+// routeWebhook parses an already verified delivery and enqueues the pipelines
+// its trigger rules select. It is shared by live deliveries and redeliveries.
+func (s *Server) routeWebhook(provider webhooks.Provider, header http.Header, body []byte, pipelineId int64) webhookResult {
+	event, ok, err := provider.Parse(header, body)
+
+	if err != nil {
+		slog.Error("Error unmarshalling body", "provider", provider.Name(), "error", err)
+		return webhookResult{
+			Status:    http.StatusBadRequest,
+			Message:   "error unmarshalling body",
+			Decision:  fmt.Sprintf("rejected: invalid payload: %v", err),
+			Triggered: []TriggeredPipeline{},
+		}
+	}
+
+	if !ok {
+		slog.Info("Ignoring webhook event", "provider", provider.Name())
+		return webhookResult{
+			Status:    http.StatusOK,
+			Message:   "ignored",
+			Decision:  fmt.Sprintf("ignored: %s event is not routable", provider.EventType(header)),
+			Triggered: []TriggeredPipeline{},
+		}
+	}
+
+	if event.Type == webhooks.EventCI {
+		return s.routeCIEvent(event, pipelineId)
+	}
+
+	triggered, skipped, err := s.dispatchWebhookEvent(event, pipelineId)
+
+	if err != nil {
+		return webhookResult{
+			Status:    http.StatusInternalServerError,
+			Message:   "error creating update in database",
+			Decision:  fmt.Sprintf("error: %v", err),
+			Triggered: triggered,
+		}
+	}
+
+	slog.Info("Pipelines added in queue", "provider", provider.Name(), "repository", event.Repository, "ref", event.RefName(), "count", len(triggered))
+
+	decision := fmt.Sprintf("triggered %d pipeline(s) for %s %s", len(triggered), event.Repository, event.RefName())
+
+	if len(triggered) == 0 && len(skipped) == 0 {
+		decision = fmt.Sprintf("no trigger rule matched %s %s on %s", event.Type, event.RefName(), event.Repository)
+	}
+
+	for _, pipeline := range triggered {
+		if pipeline.Note != "" {
+			decision += fmt.Sprintf("; pipeline %d: %s", pipeline.PipelineID, pipeline.Note)
+		}
+	}
+
+	for _, skip := range skipped {
+		decision += fmt.Sprintf("; skipped pipeline %d: %s", skip.PipelineID, skip.Reason)
+	}
+
+	return webhookResult{
+		Status:    http.StatusOK,
+		Message:   "ok",
+		Decision:  decision,
+		Triggered: triggered,
+		Skipped:   skipped,
+	}
+}
+
+func (s *Server) routeCIEvent(event webhooks.Event, pipelineId int64) webhookResult {
+	released, cancelled, err := s.resolveCIGates(event, pipelineId)
+
+	if err != nil {
+		return webhookResult{
+			Status:    http.StatusInternalServerError,
+			Message:   "error resolving updates waiting for CI",
+			Decision:  fmt.Sprintf("error: %v", err),
+			Triggered: released,
+			Skipped:   cancelled,
+		}
+	}
+
+	slog.Info("CI result handled", "repository", event.Repository, "sha", event.SHA, "conclusion", event.Conclusion, "released", len(released), "cancelled", len(cancelled))
+
+	return webhookResult{
+		Status:    http.StatusOK,
+		Message:   "ok",
+		Decision:  fmt.Sprintf("CI %s on %s: released %d, cancelled %d update(s)", event.Conclusion, event.SHA, len(released), len(cancelled)),
+		Triggered: released,
+		Skipped:   cancelled,
+	}
+}
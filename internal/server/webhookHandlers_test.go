@@ -0,0 +1,173 @@
+package server
+
+import (
+	"auto-update/internal/database"
+	"auto-update/internal/database/models"
+	"auto-update/internal/queue"
+	"auto-update/internal/webhooks"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRepository = "acme/api"
+
+func pushBody(sha string) string {
+	return fmt.Sprintf(`{"ref":"refs/heads/main","repository":{"full_name":%q},"pusher":{"name":"dev"},"head_commit":{"id":%q,"message":"fix"}}`, testRepository, sha)
+}
+
+// webhookFixture has pipeline 1 deployed by pushes to main.
+func webhookFixture(waitForCI bool) *fakeDB {
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1, Name: "api"}
+	db.triggers[1] = models.WebhookTrigger{ID: 1, PipelineID: 1, Repository: testRepository, BranchPattern: "main", EventType: "push", Active: true, WaitForCI: waitForCI}
+
+	return db
+}
+
+func freezePipeline(db *fakeDB, pipelineId int64, action string) {
+	starts, ends := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	db.freezes = append(db.freezes, models.FreezeWindow{ID: int64(len(db.freezes) + 1), Scope: models.FreezePipeline, ScopeID: pipelineId, Action: action, StartsAt: &starts, EndsAt: &ends})
+}
+
+func TestWebhookWaitForCIIgnoresFreezeUntilCIPasses(t *testing.T) {
+	db := webhookFixture(true)
+	freezePipeline(db, 1, models.FreezeHold)
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	res := postGithub(t, s, "push", "guid-1", pushBody("abc"), testWebhookSecret)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, db.updates, 1)
+	assert.Equal(t, database.UpdateStatusWaitingCI, db.updates[0].Status)
+	assert.NotNil(t, db.updates[0].CIDeadline)
+	assert.Equal(t, 0, s.queue.Size())
+}
+
+func TestWebhookFrozen(t *testing.T) {
+	db := webhookFixture(false)
+	freezePipeline(db, 1, models.FreezeHold)
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	postGithub(t, s, "push", "guid-1", pushBody("abc"), testWebhookSecret)
+
+	require.Len(t, db.updates, 1)
+	assert.Equal(t, database.UpdateStatusFrozen, db.updates[0].Status)
+	assert.Equal(t, 0, s.queue.Size())
+}
+
+func TestWebhookAwaitsApproval(t *testing.T) {
+	db := webhookFixture(false)
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1, Name: "api", RequiredApprovals: 1}
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	res := postGithub(t, s, "push", "guid-1", pushBody("abc"), testWebhookSecret)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, db.updates, 1)
+	assert.Equal(t, database.UpdateStatusAwaitingApproval, db.updates[0].Status)
+	assert.NotNil(t, db.updates[0].ApprovalDeadline)
+	assert.Equal(t, "abc", db.updates[0].SHA)
+	assert.Equal(t, testRepository, db.updates[0].Repository)
+	assert.Equal(t, 0, s.queue.Size())
+}
+
+func TestWebhookQueued(t *testing.T) {
+	db := webhookFixture(false)
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	postGithub(t, s, "push", "guid-1", pushBody("abc"), testWebhookSecret)
+
+	require.Len(t, db.updates, 1)
+	assert.Equal(t, "pending", db.updates[0].Status)
+	assert.Equal(t, "in queue", db.updates[0].Message)
+	assert.Equal(t, 1, s.queue.Size())
+}
+
+// waitingCIFixture has an update of pipeline 1 waiting for CI on commit abc.
+func waitingCIFixture(pipeline models.Pipeline) *fakeDB {
+	db := newFakeDB()
+	db.pipelines[pipeline.ID] = pipeline
+	db.updates = append(db.updates, database.Update{ID: 1, PipelineID: pipeline.ID, Status: database.UpdateStatusWaitingCI, Repository: testRepository, SHA: "abc"})
+
+	return db
+}
+
+func ciPassed() webhooks.Event {
+	return webhooks.Event{Type: webhooks.EventCI, Repository: testRepository, SHA: "abc", Conclusion: "success"}
+}
+
+func TestResolveCIGatesAwaitsApproval(t *testing.T) {
+	db := waitingCIFixture(models.Pipeline{ID: 1, UserID: 1, RequiredApprovals: 2})
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	released, cancelled, err := s.resolveCIGates(ciPassed(), 0)
+
+	require.NoError(t, err)
+	assert.Empty(t, cancelled)
+	require.Len(t, released, 1)
+	assert.True(t, released[0].AwaitingApproval)
+	assert.Equal(t, database.UpdateStatusAwaitingApproval, db.updates[0].Status)
+	assert.NotNil(t, db.updates[0].ApprovalDeadline)
+	assert.Equal(t, 0, s.queue.Size())
+}
+
+func TestResolveCIGatesQueued(t *testing.T) {
+	db := waitingCIFixture(models.Pipeline{ID: 1, UserID: 1})
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	released, _, err := s.resolveCIGates(ciPassed(), 0)
+
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	assert.False(t, released[0].AwaitingApproval)
+	assert.Equal(t, "pending", db.updates[0].Status)
+	assert.Equal(t, 1, s.queue.Size())
+}
+
+func TestReleaseFrozenUpdateAwaitsApproval(t *testing.T) {
+	db := newFakeDB()
+	db.pipelines[1] = models.Pipeline{ID: 1, UserID: 1, RequiredApprovals: 1}
+	db.updates = append(db.updates,
+		database.Update{ID: 1, PipelineID: 1, Status: database.UpdateStatusFrozen},
+		database.Update{ID: 2, PipelineID: 1, Status: database.UpdateStatusFrozen},
+	)
+	db.approvals = append(db.approvals, models.RunApproval{UpdateID: 2, UserID: 3, Decision: models.ApprovalApproved})
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	s.releaseFrozenUpdate(db.updates[0])
+	s.releaseFrozenUpdate(db.updates[1])
+
+	assert.Equal(t, database.UpdateStatusAwaitingApproval, db.updates[0].Status)
+	// The approved update only waited for the freeze.
+	assert.Equal(t, "pending", db.updates[1].Status)
+	assert.Equal(t, 1, s.queue.Size())
+}
+
+func pullRequestBody(sha string) string {
+	return fmt.Sprintf(`{"action":"closed","repository":{"full_name":%q},"pull_request":{"merged":true,"merge_commit_sha":%q,"base":{"ref":"main"},"merged_by":{"login":"dev"}}}`, testRepository, sha)
+}
+
+func TestWebhookMergedPullRequestDeploysOnce(t *testing.T) {
+	db := webhookFixture(false)
+	db.triggers[2] = models.WebhookTrigger{ID: 2, PipelineID: 1, Repository: testRepository, BranchPattern: "main", EventType: "pull_request", Active: true}
+	s := &Server{db: db, queue: queue.NewUpdateQueue()}
+
+	postGithub(t, s, "push", "guid-1", pushBody("abc"), testWebhookSecret)
+	postGithub(t, s, "pull_request", "guid-2", pullRequestBody("abc"), testWebhookSecret)
+
+	require.Len(t, db.updates, 1)
+	assert.Equal(t, 1, s.queue.Size())
+	require.Len(t, db.deliveries, 2)
+	assert.Contains(t, db.deliveries[1].Decision, "skipped pipeline 1: commit abc already triggered update 1")
+
+	// A commit whose deploy failed is deployed again.
+	db.updates[0].Status = "error"
+	postGithub(t, s, "push", "guid-3", pushBody("abc"), testWebhookSecret)
+
+	assert.Len(t, db.updates, 2)
+}
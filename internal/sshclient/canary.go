@@ -0,0 +1,107 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// healthCheckInterval is how often canaries are checked while baking.
+	healthCheckInterval = 30 * time.Second
+	// healthCheckTimeout is how long a single health check may run.
+	healthCheckTimeout = time.Minute
+)
+
+// runCanaries deploys the canary servers and bakes them, running the health
+// check until the bake period ends. It reports whether the rollout can be
+// promoted to the other servers; otherwise every canary is rolled back.
+func (s *SshClientService) runCanaries(canaries []models.UpdateServer, strategy deploy.Strategy, options *UpdateOptions) ([]models.ServerResult, bool) {
+	results := s.runStage(deploy.Stage{Name: models.PhaseCanary, Servers: canaries}, options)
+
+	for i := range results {
+		results[i].Phase = models.PhaseCanary
+	}
+
+	if !hasFailures(results) {
+		err := s.bakeCanaries(canaries, strategy, options)
+
+		if err == nil {
+			return results, true
+		}
+
+		status := models.ServerError
+		if _, cancelled := cancellation(options); cancelled {
+			status = models.ServerCancelled
+		}
+
+		for i := range results {
+			results[i].Status = status
+			results[i].Output = fmt.Sprintf("%s\nhealth check: %s", results[i].Output, err.Error())
+		}
+	}
+
+	// A cancelled run only rolls back when asked to, see rollbackCancelled.
+	if _, cancelled := cancellation(options); cancelled {
+		return results, false
+	}
+
+	// Canaries whose deploy failed were already rolled back by runServer.
+	for i, server := range canaries {
+		if results[i].Rollback == nil {
+			results[i].Rollback = s.rollbackServer(server, options)
+		}
+	}
+
+	return results, false
+}
+
+// bakeCanaries waits the bake period, running the health check on every
+// canary each healthCheckInterval and once more at the end. Without a health
+// check the canaries only have to survive their deploy.
+func (s *SshClientService) bakeCanaries(canaries []models.UpdateServer, strategy deploy.Strategy, options *UpdateOptions) error {
+	deadline := time.Now().Add(strategy.BakePeriod)
+
+	slog.Info("Aguardando canários", "servers", len(canaries), "bake", strategy.BakePeriod)
+
+	for {
+		wait := max(min(healthCheckInterval, time.Until(deadline)), 0)
+
+		select {
+		case <-runContext(options).Done():
+			return runContext(options).Err()
+		case <-time.After(wait):
+		}
+
+		if strategy.HealthCheck != "" {
+			if err := s.checkCanaries(canaries, strategy.HealthCheck, options); err != nil {
+				return err
+			}
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil
+		}
+	}
+}
+
+// checkCanaries runs the health check on every canary, failing on the first
+// one that exits non-zero.
+func (s *SshClientService) checkCanaries(canaries []models.UpdateServer, check string, options *UpdateOptions) error {
+	for _, server := range canaries {
+		ctx, cancel := context.WithTimeout(runContext(options), healthCheckTimeout)
+		out, err := s.execScript(ctx, server, runEnvironment(options)+check)
+		cancel()
+
+		if err != nil {
+			slog.Error("health check falhou", "server", server.Label, "error", err)
+			return fmt.Errorf("%s: %s", server.Label, strings.TrimSpace(out))
+		}
+	}
+
+	return nil
+}
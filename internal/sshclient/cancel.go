@@ -0,0 +1,124 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"context"
+	"sync"
+)
+
+// runCancel is a run deploying in this process, whose context is cancelled
+// when a user cancels the run.
+type runCancel struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	// userID and rollback are set once the run is cancelled.
+	userID   int64
+	user     string
+	rollback bool
+}
+
+var (
+	activeRunsMu sync.Mutex
+	activeRuns   = make(map[int64]*runCancel)
+)
+
+// trackRun registers a run so CancelRun can reach it until untrackRun.
+func trackRun(id int64) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	activeRunsMu.Lock()
+	defer activeRunsMu.Unlock()
+
+	activeRuns[id] = &runCancel{ctx: ctx, cancel: cancel}
+}
+
+func untrackRun(id int64) {
+	activeRunsMu.Lock()
+	defer activeRunsMu.Unlock()
+
+	if run, ok := activeRuns[id]; ok {
+		run.cancel()
+		delete(activeRuns, id)
+	}
+}
+
+// runContext is the context the scripts of a run derive from, done once the
+// run is cancelled.
+func runContext(options *UpdateOptions) context.Context {
+	if options == nil {
+		return context.Background()
+	}
+
+	activeRunsMu.Lock()
+	defer activeRunsMu.Unlock()
+
+	if run, ok := activeRuns[options.ID]; ok {
+		return run.ctx
+	}
+
+	return context.Background()
+}
+
+// cancellation returns who cancelled a run and whether they asked for a
+// rollback, ok false while the run is not cancelled.
+func cancellation(options *UpdateOptions) (runCancel, bool) {
+	if options == nil {
+		return runCancel{}, false
+	}
+
+	activeRunsMu.Lock()
+	defer activeRunsMu.Unlock()
+
+	run, ok := activeRuns[options.ID]
+
+	if !ok || run.ctx.Err() == nil {
+		return runCancel{}, false
+	}
+
+	return *run, true
+}
+
+// CancelRun stops a run deploying in this process: servers not started yet
+// are skipped and the scripts in progress are stopped. With rollback the
+// servers the run deployed are rolled back. It reports false when the run is
+// not deploying here.
+func (s *SshClientService) CancelRun(id int64, userID int64, user string, rollback bool) bool {
+	activeRunsMu.Lock()
+	defer activeRunsMu.Unlock()
+
+	run, ok := activeRuns[id]
+
+	if !ok {
+		return false
+	}
+
+	if run.ctx.Err() == nil {
+		run.userID, run.user, run.rollback = userID, user, rollback
+		run.cancel()
+	}
+
+	return true
+}
+
+// rollbackCancelled rolls back the servers a cancelled run deployed or
+// interrupted with rollback, when the cancellation asked for it.
+func rollbackCancelled(servers []models.UpdateServer, results []models.ServerResult, options *UpdateOptions, rollback func(models.UpdateServer, *UpdateOptions) *models.ServerResult) {
+	if run, ok := cancellation(options); !ok || !run.rollback {
+		return
+	}
+
+	byID := make(map[int64]models.UpdateServer, len(servers))
+	for _, server := range servers {
+		byID[server.ID] = server
+	}
+
+	for i := range results {
+		if results[i].Rollback != nil || (results[i].Status != models.ServerSuccess && results[i].Status != models.ServerCancelled) {
+			continue
+		}
+
+		if server, ok := byID[results[i].ServerID]; ok {
+			results[i].Rollback = rollback(server, options)
+		}
+	}
+}
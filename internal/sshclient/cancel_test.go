@@ -0,0 +1,118 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCancelRun(t *testing.T) {
+	s := &SshClientService{}
+	options := &UpdateOptions{ID: 101}
+
+	assert.False(t, s.CancelRun(options.ID, 1, "ana", false), "untracked run")
+
+	trackRun(options.ID)
+	defer untrackRun(options.ID)
+
+	_, cancelled := cancellation(options)
+	assert.False(t, cancelled)
+	require.NoError(t, runContext(options).Err())
+
+	assert.True(t, s.CancelRun(options.ID, 1, "ana", true))
+	// A second cancellation keeps who cancelled first.
+	assert.True(t, s.CancelRun(options.ID, 2, "bob", false))
+
+	run, cancelled := cancellation(options)
+	require.True(t, cancelled)
+	assert.Equal(t, int64(1), run.userID)
+	assert.Equal(t, "ana", run.user)
+	assert.True(t, run.rollback)
+	assert.Error(t, runContext(options).Err())
+}
+
+func TestUntrackRun(t *testing.T) {
+	s := &SshClientService{}
+	options := &UpdateOptions{ID: 102}
+
+	trackRun(options.ID)
+	ctx := runContext(options)
+	untrackRun(options.ID)
+
+	assert.Error(t, ctx.Err())
+	assert.False(t, s.CancelRun(options.ID, 1, "ana", false))
+	assert.NoError(t, runContext(options).Err())
+}
+
+// cancelledResults are the results of a run with one server in each status
+// and a server already rolled back.
+func cancelledResults() ([]models.UpdateServer, []models.ServerResult) {
+	servers := []models.UpdateServer{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+
+	results := []models.ServerResult{
+		{ServerID: 1, Status: models.ServerSuccess},
+		{ServerID: 2, Status: models.ServerCancelled},
+		{ServerID: 3, Status: models.ServerError},
+		{ServerID: 4, Status: models.ServerSkipped},
+		{ServerID: 5, Status: models.ServerSuccess, Rollback: &models.ServerResult{ServerID: 5, Status: models.ServerSuccess}},
+	}
+
+	return servers, results
+}
+
+func rolledBack(results []models.ServerResult) []int64 {
+	var ids []int64
+
+	for _, result := range results {
+		if result.Rollback != nil {
+			ids = append(ids, result.ServerID)
+		}
+	}
+
+	return ids
+}
+
+func TestRollbackCancelled(t *testing.T) {
+	s := &SshClientService{}
+	options := &UpdateOptions{ID: 103}
+
+	trackRun(options.ID)
+	defer untrackRun(options.ID)
+
+	s.CancelRun(options.ID, 1, "ana", true)
+
+	servers, results := cancelledResults()
+	var called []int64
+
+	rollbackCancelled(servers, results, options, func(server models.UpdateServer, _ *UpdateOptions) *models.ServerResult {
+		called = append(called, server.ID)
+		return &models.ServerResult{ServerID: server.ID, Status: models.ServerSuccess}
+	})
+
+	assert.Equal(t, []int64{1, 2}, called)
+	assert.Equal(t, []int64{1, 2, 5}, rolledBack(results))
+}
+
+func TestRollbackCancelledNotAsked(t *testing.T) {
+	s := &SshClientService{}
+	rollback := func(server models.UpdateServer, _ *UpdateOptions) *models.ServerResult {
+		t.Errorf("server %d rolled back", server.ID)
+		return nil
+	}
+
+	// Not cancelled.
+	options := &UpdateOptions{ID: 104}
+	trackRun(options.ID)
+	defer untrackRun(options.ID)
+
+	servers, results := cancelledResults()
+	rollbackCancelled(servers, results, options, rollback)
+
+	// Cancelled without rollback.
+	s.CancelRun(options.ID, 1, "ana", false)
+	rollbackCancelled(servers, results, options, rollback)
+
+	assert.Equal(t, []int64{5}, rolledBack(results))
+}
@@ -0,0 +1,153 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/utils"
+	"log/slog"
+	"net"
+	"sync"
+
+	"github.com/melbahja/goph"
+	"golang.org/x/crypto/ssh"
+)
+
+// DryRun resolves the servers and rendered scripts a run of the pipeline
+// would deploy and checks every server is ready: its credentials decrypt and
+// it accepts an SSH connection with a trusted host key and authenticates.
+// Nothing runs on the servers and, unlike a run, unknown host keys are not
+// added to known_hosts.
+func (s *SshClientService) DryRun(pipeline models.Pipeline, options *UpdateOptions) ([]deploy.Readiness, error) {
+	servers, err := s.db.ListServers(pipeline.ID)
+
+	if err != nil {
+		slog.Error("error ao buscar servidores", "error", err)
+		return nil, err
+	}
+
+	variables, err := s.db.ListVariables(pipeline.ID)
+
+	if err != nil {
+		slog.Error("error ao buscar variáveis", "error", err)
+		return nil, err
+	}
+
+	servers = selectServers(servers, options)
+	servers = deploy.WithRollbackScript(servers, pipeline)
+	servers = deploy.WithTimeout(servers, pipeline)
+
+	scripts, err := s.db.GetScripts(deploy.ScriptIDs(servers))
+
+	if err != nil {
+		slog.Error("error ao buscar scripts", "error", err)
+		return nil, err
+	}
+
+	reports := make([]deploy.Readiness, len(servers))
+
+	var wg sync.WaitGroup
+
+	for i, server := range servers {
+		// A script that does not render is reported, the server is still
+		// checked so every problem shows up in one dry run.
+		resolved, err := deploy.ResolveScripts([]models.UpdateServer{server}, scripts)
+
+		if err == nil {
+			resolved, err = deploy.RenderServers(resolved, options.ScriptData(pipeline), variables)
+		}
+
+		if err == nil {
+			server = resolved[0]
+		}
+
+		reports[i] = deploy.NewReadiness(server)
+		reports[i].Check(deploy.CheckScript, err)
+
+		wg.Add(1)
+
+		go func(report *deploy.Readiness, server models.UpdateServer) {
+			defer wg.Done()
+
+			checkServer(report, server)
+		}(&reports[i], server)
+	}
+
+	wg.Wait()
+
+	return reports, nil
+}
+
+// checkServer decrypts the credentials of a server and opens an SSH
+// connection to it, closing it once authenticated.
+func checkServer(report *deploy.Readiness, server models.UpdateServer) {
+	password, err := utils.Decrypt(server.Password)
+
+	if !report.Check(deploy.CheckCredentials, err) {
+		report.Skip(deploy.CheckConnection, deploy.CheckHostKey, deploy.CheckAuthentication)
+		return
+	}
+
+	hostKey := &hostKeyCheck{}
+
+	client, err := goph.NewConn(&goph.Config{
+		User:     "root",
+		Addr:     server.Host,
+		Port:     22,
+		Auth:     goph.Password(password),
+		Timeout:  goph.DefaultTimeout,
+		Callback: hostKey.verify,
+	})
+
+	if err == nil {
+		client.Close()
+	}
+
+	// The host key is verified during the handshake, before authenticating,
+	// so a server that never presented one could not be reached.
+	switch {
+	case !hostKey.checked:
+		report.Check(deploy.CheckConnection, err)
+		report.Skip(deploy.CheckHostKey, deploy.CheckAuthentication)
+
+	case hostKey.err != nil:
+		report.Check(deploy.CheckConnection, nil)
+		report.Check(deploy.CheckHostKey, hostKey.err)
+		report.Skip(deploy.CheckAuthentication)
+
+	default:
+		report.Check(deploy.CheckConnection, nil)
+
+		if hostKey.known {
+			report.Check(deploy.CheckHostKey, nil)
+		} else {
+			report.Warn(deploy.CheckHostKey, "host key is not in known_hosts, the first run will trust it")
+		}
+
+		report.Check(deploy.CheckAuthentication, err)
+	}
+
+	slog.Info("Dry run do servidor", "server", server.Label, "ready", report.Ready)
+}
+
+// hostKeyCheck verifies a host key against known_hosts like verifyHost,
+// without trusting unknown keys.
+type hostKeyCheck struct {
+	checked bool
+	known   bool
+	err     error
+}
+
+func (h *hostKeyCheck) verify(host string, remote net.Addr, key ssh.PublicKey) error {
+	h.checked = true
+
+	found, err := goph.CheckKnownHost(host, remote, key, "")
+
+	if found && err != nil {
+		h.err = err
+		return err
+	}
+
+	h.known = found
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"fmt"
+	"strings"
+)
+
+// runEnvironment exports the run parameters in front of a server script so it
+// can deploy the exact version that triggered the run, e.g.
+// `git checkout "$DEPLOY_TAG"`.
+func runEnvironment(options *UpdateOptions) string {
+	if options == nil {
+		return ""
+	}
+
+	var env strings.Builder
+
+	if options.Tag != "" {
+		fmt.Fprintf(&env, "export DEPLOY_TAG=%s; ", shellQuote(options.Tag))
+	}
+
+	return env.String()
+}
+
+// ScriptData is what the scripts of the pipeline are rendered with for this
+// run, the server label and variables are filled per server. options may be
+// nil.
+func (options *UpdateOptions) ScriptData(pipeline models.Pipeline) deploy.ScriptData {
+	data := deploy.ScriptData{Pipeline: pipeline.Name}
+
+	if options != nil {
+		data.SHA = options.SHA
+		data.Branch = options.Branch
+		data.Tag = options.Tag
+		data.Pusher = options.Pusher
+		data.RunID = options.ID
+	}
+
+	return data
+}
+
+// describeRun is appended to notifications so they show what is deployed.
+func describeRun(options *UpdateOptions) string {
+	if options == nil {
+		return ""
+	}
+
+	var description strings.Builder
+
+	if options.Tag != "" {
+		fmt.Fprintf(&description, " (tag *%s*)", options.Tag)
+	}
+
+	if len(options.Only) > 0 {
+		fmt.Fprintf(&description, " (servidores: %s)", strings.Join(options.Only, ", "))
+	}
+
+	return description.String()
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// selectServers keeps the servers a run is limited to, matching labels case
+// insensitively.
+func selectServers(servers []models.UpdateServer, options *UpdateOptions) []models.UpdateServer {
+	if options == nil || len(options.Only) == 0 {
+		return servers
+	}
+
+	selected := make([]models.UpdateServer, 0, len(servers))
+
+	for _, server := range servers {
+		for _, label := range options.Only {
+			if strings.EqualFold(server.Label, label) {
+				selected = append(selected, server)
+				break
+			}
+		}
+	}
+
+	return selected
+}
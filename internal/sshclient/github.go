@@ -0,0 +1,138 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/github"
+	"auto-update/utils"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// deploymentReporter mirrors a run on GitHub as a deployment plus a commit
+// status when the pipeline has the integration configured. A nil reporter
+// does nothing, and GitHub errors are only logged so an unreachable API never
+// fails a deploy.
+type deploymentReporter struct {
+	client       *github.Client
+	repository   string
+	environment  string
+	ref          string
+	sha          string
+	link         string
+	context      string
+	deploymentID int64
+}
+
+// runLink points GitHub at the run in this application, APP_URL being the
+// public address of the api.
+func runLink(options *UpdateOptions) string {
+	appURL := os.Getenv("APP_URL")
+
+	if appURL == "" {
+		return ""
+	}
+
+	if options == nil || options.ID == 0 {
+		return appURL + "/updates"
+	}
+
+	return fmt.Sprintf("%s/updates?id=%d", appURL, options.ID)
+}
+
+func (s *SshClientService) newDeploymentReporter(pipeline models.Pipeline, options *UpdateOptions) *deploymentReporter {
+	integration, err := s.db.GetPipelineGithub(pipeline.ID)
+
+	if err != nil || !integration.Active {
+		return nil
+	}
+
+	reporter := &deploymentReporter{
+		repository:  integration.Repository,
+		environment: integration.Environment,
+		link:        runLink(options),
+		context:     "deploy/" + pipeline.Name,
+	}
+
+	if options != nil {
+		reporter.sha = options.SHA
+
+		switch {
+		case options.SHA != "":
+			reporter.ref = options.SHA
+		case options.Tag != "":
+			reporter.ref = options.Tag
+		default:
+			reporter.ref = options.Branch
+		}
+	}
+
+	if reporter.ref == "" {
+		slog.Info("Skipping github report, the run has no ref", "pipeline_id", pipeline.ID)
+		return nil
+	}
+
+	token, err := utils.Decrypt(integration.Token)
+
+	if err != nil {
+		slog.Error("error decrypting github token", "pipeline_id", pipeline.ID, "error", err)
+		return nil
+	}
+
+	baseURL := integration.BaseURL
+	if baseURL == "" {
+		baseURL = os.Getenv("GITHUB_API_URL")
+	}
+
+	clientOptions := append([]github.Option{github.WithBaseURL(baseURL)}, s.githubOptions...)
+	reporter.client = github.NewClient(token, clientOptions...)
+
+	return reporter
+}
+
+// start creates the deployment and marks it and the commit as pending.
+func (r *deploymentReporter) start(description string) {
+	if r == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	deployment, err := r.client.CreateDeployment(ctx, r.repository, r.ref, r.environment, description)
+
+	if err != nil {
+		slog.Error("error creating github deployment", "repository", r.repository, "error", err)
+	} else {
+		r.deploymentID = deployment.ID
+	}
+
+	r.reportWithContext(ctx, github.StatePending, description)
+}
+
+func (r *deploymentReporter) report(state string, description string) {
+	if r == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r.reportWithContext(ctx, state, description)
+}
+
+func (r *deploymentReporter) reportWithContext(ctx context.Context, state string, description string) {
+	if r.deploymentID != 0 {
+		if err := r.client.CreateDeploymentStatus(ctx, r.repository, r.deploymentID, state, r.link, description); err != nil {
+			slog.Error("error creating github deployment status", "repository", r.repository, "state", state, "error", err)
+		}
+	}
+
+	if r.sha != "" {
+		if err := r.client.CreateCommitStatus(ctx, r.repository, r.sha, state, r.link, description, r.context); err != nil {
+			slog.Error("error creating github commit status", "repository", r.repository, "state", state, "error", err)
+		}
+	}
+}
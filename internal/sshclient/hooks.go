@@ -0,0 +1,169 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// hookTimeout is how long a hook may run.
+const hookTimeout = 2 * time.Minute
+
+// hookOutputLimit caps how much of an HTTP hook response is recorded.
+const hookOutputLimit = 4096
+
+var hookClient = &http.Client{Timeout: hookTimeout}
+
+// runHooks runs the hooks of a phase in order and records their results.
+// Pre hooks stop at the first failure, which is returned so the run aborts
+// before touching any server; post hooks all run and only report failures.
+func (s *SshClientService) runHooks(hooks []models.Hook, phase string, options *UpdateOptions) ([]models.HookResult, error) {
+	hooks = deploy.HooksOf(hooks, phase)
+	results := make([]models.HookResult, 0, len(hooks))
+
+	var failure error
+
+	for _, hook := range hooks {
+		result := s.runHook(hook, options)
+		results = append(results, result)
+
+		if result.Failed() && failure == nil {
+			failure = fmt.Errorf("hook %s %d falhou: %s", phase, hook.ID, strings.TrimSpace(result.Output))
+
+			if phase == models.HookPre {
+				break
+			}
+		}
+	}
+
+	s.saveHookResults(options, results)
+
+	return results, failure
+}
+
+func (s *SshClientService) runHook(hook models.Hook, options *UpdateOptions) models.HookResult {
+	ctx, cancel := context.WithTimeout(runContext(options), hookTimeout)
+	defer cancel()
+
+	result := models.HookResult{
+		HookID:    hook.ID,
+		Phase:     hook.Phase,
+		Kind:      hook.Kind,
+		Status:    models.ServerSuccess,
+		StartedAt: time.Now(),
+	}
+
+	slog.Info("Executando hook", "hook_id", hook.ID, "phase", hook.Phase, "kind", hook.Kind)
+
+	var err error
+
+	switch hook.Kind {
+	case models.HookCommand:
+		result.Output, err = s.runCommandHook(ctx, hook, options)
+	case models.HookHTTP:
+		result.Output, err = runHTTPHook(ctx, hook)
+	default:
+		err = fmt.Errorf("tipo de hook desconhecido: %s", hook.Kind)
+	}
+
+	if err != nil {
+		slog.Error("error no hook", "hook_id", hook.ID, "error", err)
+		result.Status = models.ServerError
+
+		if result.Output == "" {
+			result.Output = err.Error()
+		}
+	}
+
+	result.FinishedAt = time.Now()
+
+	return result
+}
+
+// runCommandHook runs the hook command on its server, which must still be
+// part of the pipeline.
+func (s *SshClientService) runCommandHook(ctx context.Context, hook models.Hook, options *UpdateOptions) (string, error) {
+	server, err := s.db.GetServer(hook.ServerID)
+
+	if err != nil || server.PipelineID != hook.PipelineID {
+		return "", fmt.Errorf("servidor %d não encontrado na pipeline", hook.ServerID)
+	}
+
+	return s.execScript(ctx, *server, runEnvironment(options)+hook.Command)
+}
+
+// runHTTPHook sends the hook request, any status other than 2xx failing it.
+func runHTTPHook(ctx context.Context, hook models.Hook) (string, error) {
+	var body io.Reader
+	if hook.Body != "" {
+		body = strings.NewReader(hook.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, hook.Method, hook.URL, body)
+
+	if err != nil {
+		return "", err
+	}
+
+	if hook.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := hookClient.Do(req)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	out, _ := io.ReadAll(io.LimitReader(resp.Body, hookOutputLimit))
+	output := fmt.Sprintf("%s\n%s", resp.Status, out)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return output, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return output, nil
+}
+
+// saveHookResults records the hook results of a run that has an update
+// record.
+func (s *SshClientService) saveHookResults(options *UpdateOptions, results []models.HookResult) {
+	if options == nil || options.ID == 0 {
+		return
+	}
+
+	for i := range results {
+		results[i].UpdateID = options.ID
+
+		if err := s.db.CreateHookResult(&results[i]); err != nil {
+			slog.Error("error ao salvar resultado do hook", "update_id", options.ID, "hook_id", results[i].HookID, "error", err)
+		}
+	}
+}
+
+// hookSummary lists the hooks of a phase that failed.
+func hookSummary(results []models.HookResult) string {
+	var msg strings.Builder
+
+	for _, result := range results {
+		if !result.Failed() {
+			continue
+		}
+
+		if msg.Len() == 0 {
+			msg.WriteString("\n\nHooks com erro:\n")
+		}
+
+		msg.WriteString(fmt.Sprintf("```[%s] hook %d - %s```\n", result.Phase, result.HookID, strings.TrimSpace(result.Output)))
+	}
+
+	return msg.String()
+}
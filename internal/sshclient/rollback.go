@@ -0,0 +1,79 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// scriptFailed reports whether a script ran on the server and exited non-zero
+// or timed out, as opposed to never reaching the server.
+func scriptFailed(err error) bool {
+	var exitErr *ssh.ExitError
+
+	return errors.As(err, &exitErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// rollbackServer runs the rollback script of a server, returning nil when it
+// has none.
+func (s *SshClientService) rollbackServer(server models.UpdateServer, options *UpdateOptions) *models.ServerResult {
+	if strings.TrimSpace(server.RollbackScript) == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deploy.ServerTimeout(server))
+	defer cancel()
+
+	result := &models.ServerResult{
+		ServerID:  server.ID,
+		Label:     server.Label,
+		StageID:   server.StageID,
+		Phase:     models.PhaseRollback,
+		StartedAt: time.Now(),
+	}
+
+	slog.Info("Executando rollback", "server", server.Label)
+
+	out, err := s.execScript(ctx, server, runEnvironment(options)+server.RollbackScript)
+
+	result.Status = scriptStatus(err)
+	result.Output = out
+	result.FinishedAt = time.Now()
+
+	if err != nil {
+		slog.Error("error no rollback do servidor", "server", server.Label, "error", err)
+	}
+
+	return result
+}
+
+// rollbackSummary tells whether the rollback of each failed server
+// succeeded.
+func rollbackSummary(results []models.ServerResult) string {
+	var msg strings.Builder
+
+	for _, result := range results {
+		if result.Rollback == nil {
+			continue
+		}
+
+		if msg.Len() == 0 {
+			msg.WriteString("\n\nRollback:\n")
+		}
+
+		if result.Rollback.Failed() {
+			msg.WriteString(fmt.Sprintf("```*%s* - falhou: %s```\n", result.Label, result.Rollback.Output))
+		} else {
+			msg.WriteString(fmt.Sprintf("```*%s* - sucesso```\n", result.Label))
+		}
+	}
+
+	return msg.String()
+}
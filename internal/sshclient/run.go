@@ -0,0 +1,447 @@
+package sshclient
+
+import (
+	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/melbahja/goph"
+	"golang.org/x/crypto/ssh"
+)
+
+// killGrace is how long a timed out script has to exit after SIGTERM before
+// it is killed.
+const killGrace = 10 * time.Second
+
+// errConnection wraps the errors of a server that could not be reached or
+// lost its connection before its script finished.
+var errConnection = errors.New("connection")
+
+// runStage deploys the servers of a stage in parallel and waits for all of
+// them.
+func (s *SshClientService) runStage(stage deploy.Stage, options *UpdateOptions) []models.ServerResult {
+	results := make([]models.ServerResult, len(stage.Servers))
+
+	var wg sync.WaitGroup
+
+	for i, server := range stage.Servers {
+		wg.Add(1)
+
+		go func(i int, server models.UpdateServer) {
+			defer wg.Done()
+
+			result := s.runServer(runContext(options), server, options)
+			result.StageID = stage.ID
+			result.Stage = stage.Name
+			results[i] = result
+		}(i, server)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// runServer deploys a server, attempting it again while its retry policy
+// allows, and rolls the server back when its script failed. Each attempt has
+// the timeout of the server; the earlier attempts are kept in Attempts.
+func (s *SshClientService) runServer(ctx context.Context, server models.UpdateServer, options *UpdateOptions) models.ServerResult {
+	policy := deploy.ServerRetry(server)
+
+	var attempts []models.ServerResult
+
+	for attempt := 1; ; attempt++ {
+		result, err := s.attemptServer(ctx, server, options, attempt)
+
+		if err != nil && policy.Retries(result.FailureClass, attempt) {
+			delay := policy.Delay(attempt)
+
+			slog.Info("Tentando novamente", "server", server.Label, "attempt", attempt, "failure", result.FailureClass, "backoff", delay)
+
+			if sleepContext(ctx, delay) {
+				result.Phase = models.PhaseRetry
+				attempts = append(attempts, result)
+				continue
+			}
+		}
+
+		result.Attempts = attempts
+
+		if err != nil && scriptFailed(err) {
+			result.Rollback = s.rollbackServer(server, options)
+		}
+
+		return result
+	}
+}
+
+// attemptServer connects to a server and runs its script once.
+func (s *SshClientService) attemptServer(ctx context.Context, server models.UpdateServer, options *UpdateOptions, attempt int) (models.ServerResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, deploy.ServerTimeout(server))
+	defer cancel()
+
+	result := models.ServerResult{
+		ServerID:  server.ID,
+		Label:     server.Label,
+		StageID:   server.StageID,
+		Attempt:   attempt,
+		StartedAt: time.Now(),
+	}
+
+	slog.Info("Atualizando repositório no servidor de produção", "server", server.Label, "attempt", attempt)
+
+	out, err := s.execScript(ctx, server, runEnvironment(options)+server.Script)
+
+	result.Status = scriptStatus(err)
+	result.Output = out
+	result.FailureClass = failureClass(err)
+
+	slog.Info("Atualização finalizada", "info", server.Label, "status", result.Status)
+
+	result.FinishedAt = time.Now()
+
+	return result, err
+}
+
+// sleepContext waits d, reporting false when ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// execScript connects to a server and runs a script on it, returning its
+// output. When ctx is done first the script is stopped with stopScript and
+// the output so far is returned with ctx.Err().
+func (s *SshClientService) execScript(ctx context.Context, server models.UpdateServer, script string) (string, error) {
+	decryptedPassword, err := utils.Decrypt(server.Password)
+
+	if err != nil {
+		slog.Error("error ao decriptar password com o servidor", "error", err)
+		return err.Error(), err
+	}
+
+	config := &goph.Config{
+		User:     "root",
+		Addr:     server.Host,
+		Port:     22,
+		Auth:     goph.Password(decryptedPassword),
+		Timeout:  goph.DefaultTimeout,
+		Callback: verifyHost,
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		config.Timeout = min(config.Timeout, time.Until(deadline))
+	}
+
+	client, err := goph.NewConn(config)
+
+	if err != nil {
+		slog.Error("error ao conectar com o servidor", "host", server.Host, "error", err)
+		return err.Error(), fmt.Errorf("%w: %w", errConnection, err)
+	}
+
+	defer client.Close()
+
+	session, err := client.NewSession()
+
+	if err != nil {
+		slog.Error("error ao abrir sessão com o servidor", "host", server.Host, "error", err)
+		return err.Error(), fmt.Errorf("%w: %w", errConnection, err)
+	}
+
+	defer session.Close()
+
+	var output lockedBuffer
+	session.Stdout = &output
+	session.Stderr = &output
+
+	if err := session.Start(script); err != nil {
+		slog.Error("error ao executar comando no servidor", "host", server.Host, "error", err)
+		return err.Error(), fmt.Errorf("%w: %w", errConnection, err)
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- session.Wait()
+	}()
+
+	select {
+	case <-ctx.Done():
+		slog.Info("Timeout reached for server", "info", server.Label)
+		stopScript(session, done)
+		return output.String() + "\ntimeout", ctx.Err()
+
+	case err := <-done:
+		message := output.String()
+
+		fmt.Println(message)
+
+		if err != nil {
+			slog.Error("error ao executar comando no servidor", "host", server.Host, "error", err)
+		}
+
+		// Without an exit status the connection dropped before the script
+		// finished.
+		var exitErr *ssh.ExitError
+
+		if err != nil && !errors.As(err, &exitErr) {
+			err = fmt.Errorf("%w: %w", errConnection, err)
+		}
+
+		return message, err
+	}
+}
+
+// scriptStatus is the result status of a script that ended with err.
+func scriptStatus(err error) string {
+	switch {
+	case err == nil:
+		return models.ServerSuccess
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.ServerTimedOut
+	case errors.Is(err, context.Canceled):
+		return models.ServerCancelled
+	}
+
+	return models.ServerError
+}
+
+// failureClass is the deploy failure class of a script error, empty for
+// failures that are never retried such as a cancelled run.
+func failureClass(err error) string {
+	var exitErr *ssh.ExitError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &exitErr):
+		return deploy.FailureExit
+	case errors.Is(err, context.DeadlineExceeded):
+		return deploy.FailureTimeout
+	case errors.Is(err, errConnection):
+		return deploy.FailureConnection
+	}
+
+	return ""
+}
+
+// stopScript sends SIGTERM to a remote script, SIGKILL when it did not exit
+// within killGrace, and closes its session.
+func stopScript(session *ssh.Session, done <-chan error) {
+	if err := session.Signal(ssh.SIGTERM); err != nil {
+		slog.Error("error ao sinalizar comando no servidor", "error", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(killGrace):
+		_ = session.Signal(ssh.SIGKILL)
+	}
+
+	_ = session.Close()
+}
+
+// lockedBuffer collects the stdout and stderr of a session, which are written
+// from different goroutines.
+type lockedBuffer struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.String()
+}
+
+func skippedResults(stage deploy.Stage) []models.ServerResult {
+	results := make([]models.ServerResult, 0, len(stage.Servers))
+
+	for _, server := range stage.Servers {
+		results = append(results, models.ServerResult{
+			ServerID: server.ID,
+			Label:    server.Label,
+			StageID:  stage.ID,
+			Stage:    stage.Name,
+			Status:   models.ServerSkipped,
+		})
+	}
+
+	return results
+}
+
+// batchResults numbers the results of a rolling batch.
+func batchResults(results []models.ServerResult, batch int) []models.ServerResult {
+	for i := range results {
+		results[i].Batch = batch
+	}
+
+	return results
+}
+
+// rolloutSummary lists, for each stage of a rolling run, the batches that
+// completed without failures out of the total.
+func rolloutSummary(plan []deploy.Stage, results []models.ServerResult) string {
+	var msg strings.Builder
+
+	msg.WriteString("\n\nLotes concluídos:\n")
+
+	for _, stage := range plan {
+		total := 0
+		failed := make(map[int]bool)
+		ran := make(map[int]bool)
+
+		for _, result := range results {
+			if result.StageID != stage.ID || result.Stage != stage.Name {
+				continue
+			}
+
+			total = max(total, result.Batch)
+
+			if result.Status != models.ServerSkipped {
+				ran[result.Batch] = true
+			}
+			if result.Failed() {
+				failed[result.Batch] = true
+			}
+		}
+
+		completed := make([]string, 0)
+		for batch := 1; batch <= total; batch++ {
+			if ran[batch] && !failed[batch] {
+				completed = append(completed, fmt.Sprint(batch))
+			}
+		}
+
+		if len(completed) == 0 {
+			completed = append(completed, "nenhum")
+		}
+
+		msg.WriteString(fmt.Sprintf("```%s: %s de %d```\n", stage.Name, strings.Join(completed, ", "), total))
+	}
+
+	return msg.String()
+}
+
+func countFailures(results []models.ServerResult) int {
+	count := 0
+
+	for _, result := range results {
+		if result.Failed() {
+			count++
+		}
+	}
+
+	return count
+}
+
+func hasFailures(results []models.ServerResult) bool {
+	return countFailures(results) > 0
+}
+
+// saveServerResults records the results of a run that has an update record.
+func (s *SshClientService) saveServerResults(options *UpdateOptions, results []models.ServerResult) {
+	if options == nil || options.ID == 0 {
+		return
+	}
+
+	for i := range results {
+		results[i].UpdateID = options.ID
+
+		for j := range results[i].Attempts {
+			attempt := &results[i].Attempts[j]
+			attempt.UpdateID = options.ID
+			attempt.Stage = results[i].Stage
+			attempt.Batch = results[i].Batch
+
+			if err := s.db.CreateServerResult(attempt); err != nil {
+				slog.Error("error ao salvar tentativa do servidor", "update_id", options.ID, "server", attempt.Label, "attempt", attempt.Attempt, "error", err)
+			}
+		}
+
+		if err := s.db.CreateServerResult(&results[i]); err != nil {
+			slog.Error("error ao salvar resultado do servidor", "update_id", options.ID, "server", results[i].Label, "error", err)
+		}
+
+		if rollback := results[i].Rollback; rollback != nil {
+			rollback.UpdateID = options.ID
+			rollback.Stage = results[i].Stage
+			rollback.Batch = results[i].Batch
+
+			if err := s.db.CreateServerResult(rollback); err != nil {
+				slog.Error("error ao salvar rollback do servidor", "update_id", options.ID, "server", rollback.Label, "error", err)
+			}
+		}
+	}
+}
+
+// runSummary is the final notification of a run, listing the errors of each
+// stage and the stages that were skipped.
+func runSummary(pipeline models.Pipeline, options *UpdateOptions, plan []deploy.Stage, results []models.ServerResult) (string, string) {
+	var msg strings.Builder
+	color := "green"
+
+	msg.WriteString(fmt.Sprintf("Atualização realizada com sucesso na pipeline: *%s*%s", pipeline.Name, describeRun(options)))
+
+	if !hasFailures(results) {
+		return msg.String(), color
+	}
+
+	color = "red"
+	msg.WriteString("\n\nErros encontrados nos servidores:\n")
+
+	for _, result := range results {
+		if result.Failed() {
+			if len(plan) > 1 {
+				msg.WriteString(fmt.Sprintf("```[%s] *%s* - %s```\n", result.Stage, result.Label, result.Output))
+			} else {
+				msg.WriteString(fmt.Sprintf("```*%s* - %s```\n", result.Label, result.Output))
+			}
+		}
+	}
+
+	skipped := make([]string, 0)
+	ran := make(map[string]bool)
+
+	for _, result := range results {
+		if result.Status != models.ServerSkipped {
+			ran[result.Stage] = true
+		}
+	}
+
+	for _, stage := range plan {
+		if len(stage.Servers) > 0 && !ran[stage.Name] {
+			skipped = append(skipped, stage.Name)
+		}
+	}
+
+	if len(skipped) > 0 {
+		msg.WriteString(fmt.Sprintf("\nEtapas não executadas: %s\n", strings.Join(skipped, ", ")))
+	}
+
+	return msg.String(), color
+}
@@ -3,17 +3,15 @@ package sshclient
 import (
 	"auto-update/internal/database"
 	"auto-update/internal/database/models"
+	"auto-update/internal/deploy"
+	"auto-update/internal/github"
 	notification "auto-update/internal/notifications"
 	"auto-update/internal/sse"
-	"auto-update/utils"
-	"context"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/melbahja/goph"
 	"golang.org/x/crypto/ssh"
@@ -21,12 +19,14 @@ import (
 
 type SshClient interface {
 	UpdateRepository(options *UpdateOptions) error
-	UpdateProductionNew(pipeline_id int64, userId int64) error
-	UpdateProductionById(id int64) error
+	RunPipeline(options *UpdateOptions) error
+	UpdateProductionNew(pipeline_id int64, userId int64, options *UpdateOptions) error
 }
 
 type SshClientService struct {
 	db database.Service
+	// githubOptions configure the client used to report runs to GitHub.
+	githubOptions []github.Option
 }
 
 type ServerInfo struct {
@@ -38,11 +38,13 @@ type ServerInfo struct {
 type UpdateOptions struct {
 	ID         int64
 	Repository string
-}
-
-type ErrorMessage struct {
-	Label  string
-	Reason string
+	PipelineID int64
+	Tag        string
+	Branch     string
+	SHA        string
+	Pusher     string
+	// Only limits the run to the servers with these labels.
+	Only []string
 }
 
 func NewSshClientService() *SshClientService {
@@ -123,7 +125,7 @@ func (s *SshClientService) UpdateRepository(options *UpdateOptions) error {
 		sse.GetHub().Broadcast <- "error ao executar comando de Atualizar"
 		if err != nil {
 			fmt.Println("error ao atualizar status do update", err)
-			slog.Error("error ao atualizar status do update", err)
+			slog.Error("error ao atualizar status do update", "error", err)
 		}
 
 		fmt.Println("error ao executar comando de Atualizar", err)
@@ -140,200 +142,285 @@ func (s *SshClientService) UpdateRepository(options *UpdateOptions) error {
 	return nil
 }
 
-func (s *SshClientService) UpdateProductionNew(pipeline_id int64, userId int64) error {
-	slog.Info("Atualizando repositório no servidor de produção")
-	db := database.GetService()
+// RunPipeline runs a pipeline enqueued by a webhook trigger and keeps the
+// update record in sync with the outcome. Updates that are no longer pending,
+// like one cancelled while queued, are skipped.
+func (s *SshClientService) RunPipeline(options *UpdateOptions) error {
+	slog.Info("Atualizando pipeline do update", "update_id", options.ID, "pipeline_id", options.PipelineID)
 
-	pipeline, err := db.GetUserPipelineById(pipeline_id, userId)
+	pipeline, err := s.db.GetPipeline(options.PipelineID)
 
 	if err != nil {
 		slog.Error("error finding pipeline", "error", err)
+
+		if err := s.db.UpdateStatusAndMessage(options.ID, "error", "pipeline não encontrada"); err != nil {
+			slog.Error("error ao atualizar status do update", "error", err)
+		}
 		return err
 	}
 
-	servers, err := db.ListServers(pipeline_id)
+	// Registered before the run shows as running so it can always be
+	// cancelled from then on.
+	trackRun(options.ID)
+	defer untrackRun(options.ID)
 
-	notificationService := notification.NewNotificationService()
+	started, err := s.db.StartUpdate(options.ID, fmt.Sprintf("Atualizando pipeline %s", pipeline.Name))
 
 	if err != nil {
-		slog.Error("error ao buscar servidores", "error", err)
-		return err
+		slog.Error("error ao atualizar status do update", "error", err)
+	} else if !started {
+		slog.Info("Update não está mais pendente", "update_id", options.ID)
+		return nil
 	}
 
-	err = notificationService.SendAllNotifications(fmt.Sprintf("Atualização iniciada na pipeline: *%s*", pipeline.Name), userId, "yellow")
+	results, err := s.deployPipeline(pipeline, pipeline.UserID, options)
+
+	if run, cancelled := cancellation(options); cancelled {
+		if _, err := s.db.CancelUpdate(options.ID, "running", run.userID, "cancelled by "+run.user); err != nil {
+			slog.Error("error ao atualizar status do update", "error", err)
+		}
+		return nil
+	}
 
 	if err != nil {
-		slog.Error("error ao enviar notificação", "error", err)
+		if err := s.db.UpdateStatusAndMessage(options.ID, "error", err.Error()); err != nil {
+			slog.Error("error ao atualizar status do update", "error", err)
+		}
 		return err
 	}
 
-	errors := make([]ErrorMessage, 0)
+	status := "success"
+	message := fmt.Sprintf("Pipeline %s atualizada com sucesso", pipeline.Name)
 
-	var wg sync.WaitGroup
+	if hasFailures(results) {
+		labels := make([]string, 0)
+		for _, result := range results {
+			if result.Failed() {
+				labels = append(labels, result.Label)
+			}
+		}
 
-	for _, server := range servers {
-		wg.Add(1)
-		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Second)
+		status = "error"
+		message = fmt.Sprintf("Erros nos servidores: %s", strings.Join(labels, ", "))
+	}
 
-		go func(ctx context.Context, server models.UpdateServer) {
+	if err := s.db.UpdateStatusAndMessage(options.ID, status, message); err != nil {
+		slog.Error("error ao atualizar status do update", "error", err)
+	}
 
-			defer wg.Done()
-			defer cancel()
+	return nil
+}
 
-			done := make(chan bool)
+func (s *SshClientService) UpdateProductionNew(pipeline_id int64, userId int64, options *UpdateOptions) error {
+	slog.Info("Atualizando repositório no servidor de produção")
 
-			go func() {
-				slog.Info("Atualizando repositório no servidor de produção", server.Label)
+	pipeline, err := s.db.GetUserPipelineById(pipeline_id, userId)
 
-				decryptedPassword, err := utils.Decrypt(server.Password)
+	if err != nil {
+		slog.Error("error finding pipeline", "error", err)
+		return err
+	}
 
-				if err != nil {
-					slog.Error("error ao decriptar password com o servidor", "error", err)
+	_, err = s.deployPipeline(pipeline, userId, options)
 
-					errors = append(errors, ErrorMessage{Label: server.Label, Reason: err.Error()})
-					done <- true
-					return
-				}
+	return err
+}
 
-				auth := goph.Password(decryptedPassword)
+// deployPipeline runs the active servers of the pipeline stage by stage and
+// notifies the user, returning the result of every server. A stage only
+// starts when every server of the previous one succeeded, the servers of the
+// stages left are reported as skipped. With a rolling strategy each stage is
+// deployed batch by batch and the rollout stops once more servers failed than
+// the pipeline allows. With a canary strategy the canary servers go first and
+// the rest only follow once they baked healthy. options may be nil for runs
+// without parameters. Pre hooks run before any server and abort the run when
+// one fails, post hooks run once the servers are done.
+func (s *SshClientService) deployPipeline(pipeline models.Pipeline, userId int64, options *UpdateOptions) ([]models.ServerResult, error) {
+	servers, err := s.db.ListServers(pipeline.ID)
 
-				client, err := goph.NewConn(&goph.Config{
-					User:     "root",
-					Addr:     server.Host,
-					Port:     22,
-					Auth:     auth,
-					Callback: verifyHost,
-				})
+	notificationService := notification.NewNotificationService()
 
-				if err != nil {
-					fmt.Println("error ao conectar com o servidor:"+server.Host, err)
-					slog.Error("error ao conectar com o servidor", "error", err)
+	if err != nil {
+		slog.Error("error ao buscar servidores", "error", err)
+		return nil, err
+	}
 
-					errors = append(errors, ErrorMessage{Label: server.Label, Reason: err.Error()})
-					done <- true
-					return
-				}
+	stages, err := s.db.ListStages(pipeline.ID)
 
-				defer client.Close()
+	if err != nil {
+		slog.Error("error ao buscar etapas", "error", err)
+		return nil, err
+	}
 
-				// run script
+	hooks, err := s.db.ListHooks(pipeline.ID)
 
-				out, err := client.Run(server.Script)
-				//out, err := client.Run("ls -a")
+	if err != nil {
+		slog.Error("error ao buscar hooks", "error", err)
+		return nil, err
+	}
 
-				message := string(out)
+	variables, err := s.db.ListVariables(pipeline.ID)
 
-				fmt.Println(message)
+	if err != nil {
+		slog.Error("error ao buscar variáveis", "error", err)
+		return nil, err
+	}
 
-				if err != nil {
-					slog.Error("error ao executar comando de Atualizar o servidor:"+server.Host, "error", err)
+	servers = selectServers(servers, options)
+	servers = deploy.WithRollbackScript(servers, pipeline)
+	servers = deploy.WithTimeout(servers, pipeline)
+	servers = deploy.WithRetry(servers, pipeline)
 
-					errors = append(errors, ErrorMessage{Label: server.Label, Reason: message})
-				}
+	strategy, err := deploy.PipelineStrategy(pipeline)
 
-				done <- true
-			}()
+	if err != nil {
+		slog.Error("error na estratégia da pipeline", "pipeline", pipeline.Name, "error", err)
+		return nil, err
+	}
 
-			select {
-			case <-ctx.Done():
-				slog.Info("Timeout reached for server", "info", server.Label)
-			case <-done:
-				slog.Info("Atualização realizada com sucesso:", "info", server.Label)
-			}
+	reporter := s.newDeploymentReporter(pipeline, options)
+	reporter.start(fmt.Sprintf("Deploy da pipeline %s", pipeline.Name))
 
-		}(ctx, server)
+	if len(servers) == 0 && options != nil && len(options.Only) > 0 {
+		err := fmt.Errorf("nenhum servidor ativo para: %s", strings.Join(options.Only, ", "))
+		reporter.report(github.StateError, err.Error())
+		return nil, err
 	}
 
-	wg.Wait()
-
-	var msg strings.Builder
-	color := "green"
-	msg.WriteString(fmt.Sprintf("Atualização realizada com sucesso na pipeline: *%s*", pipeline.Name))
+	// A script that does not resolve or render aborts the run before any
+	// server is touched.
+	scripts, err := s.db.GetScripts(deploy.ScriptIDs(servers))
 
-	if len(errors) > 0 {
-		msg.WriteString("\n\nErros encontrados nos servidores:\n")
-		for _, e := range errors {
-			msg.WriteString(fmt.Sprintf("```*%s* - %s```\n", e.Label, e.Reason))
-		}
-		color = "red"
+	if err == nil {
+		servers, err = deploy.ResolveScripts(servers, scripts)
 	}
 
-	fmt.Println(msg.String())
-	err = notificationService.SendAllNotifications(msg.String(), userId, color)
+	if err == nil {
+		servers, err = deploy.RenderServers(servers, options.ScriptData(pipeline), variables)
+	}
 
 	if err != nil {
-		slog.Error("error ao enviar notificação", "error", err)
-		return err
+		slog.Error("error ao renderizar scripts", "pipeline", pipeline.Name, "error", err)
+		reporter.report(github.StateError, err.Error())
+		return nil, err
 	}
-	return nil
-}
 
-func (s *SshClientService) UpdateProductionById(id int64) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Second)
-	defer cancel()
+	err = notificationService.SendAllNotifications(fmt.Sprintf("Atualização iniciada na pipeline: *%s*%s", pipeline.Name, describeRun(options)), userId, "yellow")
 
-	slog.Info("Atualizando repositório no servidor de produção")
+	if err != nil {
+		slog.Error("error ao enviar notificação", "error", err)
+		reporter.report(github.StateError, "erro ao enviar notificação")
+		return nil, err
+	}
 
-	server, err := database.GetService().GetServer(id)
+	if _, err := s.runHooks(hooks, models.HookPre, options); err != nil {
+		reporter.report(github.StateFailure, err.Error())
 
-	if err != nil {
+		msg := fmt.Sprintf("Atualização abortada na pipeline: *%s*%s\n\n```%s```", pipeline.Name, describeRun(options), err.Error())
+		if err := notificationService.SendAllNotifications(msg, userId, "red"); err != nil {
+			slog.Error("error ao enviar notificação", "error", err)
+		}
 
-		slog.Error("error ao buscar servidores", "error", err)
-		return err
+		return nil, err
 	}
 
-	done := make(chan bool)
+	reporter.report(github.StateInProgress, fmt.Sprintf("Atualizando %d servidor(es)", len(servers)))
 
-	slog.Info("Atualizando repositório no servidor de produção", "info", server.Label)
-	go func() {
+	results := make([]models.ServerResult, 0, len(servers))
+	deployed := servers
+	aborted := false
 
-		decryptedPassword, err := utils.Decrypt(server.Password)
-		if err != nil {
-			slog.Error("error ao decriptar o password:"+server.Host, "error", err)
-			done <- true
-			return
+	if strategy.Name == deploy.StrategyCanary {
+		canaries, rest := deploy.SplitCanaries(servers)
+
+		if len(canaries) == 0 {
+			err := fmt.Errorf("nenhum servidor canário na pipeline %s", pipeline.Name)
+			reporter.report(github.StateError, err.Error())
+			return nil, err
 		}
 
-		auth := goph.Password(decryptedPassword)
+		canaryResults, promoted := s.runCanaries(canaries, strategy, options)
+		results = append(results, canaryResults...)
+		servers = rest
+		aborted = !promoted
 
-		client, err := goph.NewConn(&goph.Config{
-			User:     "root",
-			Addr:     server.Host,
-			Port:     22,
-			Auth:     auth,
-			Callback: verifyHost,
-		})
+		if promoted {
+			reporter.report(github.StateInProgress, fmt.Sprintf("Canário aprovado, atualizando %d servidor(es)", len(rest)))
+		}
+	}
 
-		if err != nil {
-			slog.Error("error ao conectar com o servidor:"+server.Host, "error", err)
-			done <- true
-			return
+	plan := deploy.PlanStages(stages, servers)
+
+	for _, stage := range plan {
+		if aborted {
+			results = append(results, skippedResults(stage)...)
+			continue
 		}
 
-		defer client.Close()
+		slog.Info("Iniciando etapa", "pipeline", pipeline.Name, "stage", stage.Name, "servers", len(stage.Servers))
 
-		// run script
+		stageFailed := false
 
-		out, err := client.Run(server.Script)
-		//out, err := client.Run("ls -a")
+		for i, batch := range strategy.Batches(stage.Servers) {
+			batchStage := deploy.Stage{ID: stage.ID, Name: stage.Name, Servers: batch}
 
-		message := string(out)
+			if _, cancelled := cancellation(options); cancelled {
+				aborted = true
+			}
 
-		fmt.Println(message)
+			if aborted {
+				results = append(results, batchResults(skippedResults(batchStage), i+1)...)
+				continue
+			}
 
-		if err != nil {
-			slog.Error("error ao executar comando de Atualizar o servidor:"+server.Host, "error", err)
+			batchResult := batchResults(s.runStage(batchStage, options), i+1)
+			results = append(results, batchResult...)
+
+			stageFailed = stageFailed || hasFailures(batchResult)
+			aborted = strategy.ShouldAbort(countFailures(results))
 		}
 
-		done <- true
-	}()
+		// Even within the threshold, a stage with failures does not let the
+		// next one start.
+		aborted = aborted || stageFailed
+	}
+
+	rollbackCancelled(deployed, results, options, s.rollbackServer)
+	s.saveServerResults(options, results)
+
+	var postHooks []models.HookResult
+	var postErr error
 
-	select {
-	case <-ctx.Done():
-		fmt.Println("Timeout reached for server", server.Label)
-	case <-done:
-		fmt.Println("Atualização realizada com sucesso:", server.Label)
+	// A cancelled run stops before its post hooks.
+	if _, cancelled := cancellation(options); !cancelled {
+		postHooks, postErr = s.runHooks(hooks, models.HookPost, options)
 	}
 
-	return nil
+	if failures := countFailures(results); failures > 0 {
+		reporter.report(github.StateFailure, fmt.Sprintf("%d de %d servidor(es) falharam", failures, len(results)))
+	} else {
+		reporter.report(github.StateSuccess, fmt.Sprintf("Pipeline %s atualizada com sucesso", pipeline.Name))
+	}
+
+	msg, color := runSummary(pipeline, options, plan, results)
+
+	if strategy.Name == deploy.StrategyRolling {
+		msg += rolloutSummary(plan, results)
+	}
+
+	msg += rollbackSummary(results)
+	msg += hookSummary(postHooks)
+
+	if postErr != nil {
+		color = "red"
+	}
+
+	fmt.Println(msg)
+	err = notificationService.SendAllNotifications(msg, userId, color)
+
+	if err != nil {
+		slog.Error("error ao enviar notificação", "error", err)
+	}
+
+	return results, nil
 }
@@ -0,0 +1,136 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type BitbucketWebhook struct {
+	Actor       BitbucketActor       `json:"actor"`
+	Repository  Repo                 `json:"repository"`
+	Push        BitbucketPush        `json:"push"`
+	PullRequest BitbucketPullRequest `json:"pullrequest"`
+}
+
+type BitbucketActor struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+type BitbucketPush struct {
+	Changes []BitbucketChange `json:"changes"`
+}
+
+type BitbucketChange struct {
+	New *BitbucketRef `json:"new"`
+}
+
+type BitbucketRef struct {
+	Type   string          `json:"type"`
+	Name   string          `json:"name"`
+	Target BitbucketCommit `json:"target"`
+}
+
+type BitbucketCommit struct {
+	Hash    string `json:"hash"`
+	Message string `json:"message"`
+}
+
+type BitbucketPullRequest struct {
+	Title       string          `json:"title"`
+	State       string          `json:"state"`
+	MergeCommit BitbucketCommit `json:"merge_commit"`
+	Destination struct {
+		Branch struct {
+			Name string `json:"name"`
+		} `json:"branch"`
+	} `json:"destination"`
+}
+
+type BitbucketProvider struct{}
+
+func (BitbucketProvider) Name() string {
+	return "bitbucket"
+}
+
+func (BitbucketProvider) DeliveryID(header http.Header) string {
+	return header.Get("X-Request-UUID")
+}
+
+func (BitbucketProvider) EventType(header http.Header) string {
+	return header.Get("X-Event-Key")
+}
+
+func (BitbucketProvider) Verify(header http.Header, body []byte, secret string) error {
+	signature := strings.TrimPrefix(header.Get("X-Hub-Signature"), "sha256=")
+
+	if !checkMAC(body, signature, secret) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Parse handles repo:push and pullrequest:fulfilled. A push may carry several
+// ref changes, only the first one that still exists after the push is used.
+func (BitbucketProvider) Parse(header http.Header, body []byte) (Event, bool, error) {
+	webhook := new(BitbucketWebhook)
+
+	if err := json.Unmarshal(body, webhook); err != nil {
+		return Event{}, false, err
+	}
+
+	switch Bitbucket.EventType(header) {
+	case "repo:push":
+		for _, change := range webhook.Push.Changes {
+			if change.New == nil {
+				continue
+			}
+
+			event := Event{
+				Provider:   "bitbucket",
+				Repository: webhook.Repository.FullName,
+				SHA:        change.New.Target.Hash,
+				Author:     webhook.Actor.Nickname,
+				Message:    change.New.Target.Message,
+			}
+
+			switch change.New.Type {
+			case "branch":
+				event.Type = EventPush
+				event.Ref = "refs/heads/" + change.New.Name
+				event.Branch = change.New.Name
+			case "tag", "annotated_tag":
+				event.Type = EventTag
+				event.Ref = "refs/tags/" + change.New.Name
+				event.Tag = change.New.Name
+			default:
+				continue
+			}
+
+			return event, true, nil
+		}
+
+	case "pullrequest:fulfilled":
+		if webhook.PullRequest.State != "MERGED" {
+			return Event{}, false, nil
+		}
+
+		branch := webhook.PullRequest.Destination.Branch.Name
+
+		return Event{
+			Provider:   "bitbucket",
+			Type:       EventPullRequest,
+			Repository: webhook.Repository.FullName,
+			Ref:        "refs/heads/" + branch,
+			Branch:     branch,
+			SHA:        webhook.PullRequest.MergeCommit.Hash,
+			Author:     webhook.Actor.Nickname,
+			Message:    webhook.PullRequest.Title,
+			Merged:     true,
+		}, true, nil
+	}
+
+	return Event{}, false, nil
+}
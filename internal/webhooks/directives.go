@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	skipDirective     = regexp.MustCompile(`(?i)\[\s*skip\s+deploy\s*\]`)
+	onlyDirective     = regexp.MustCompile(`(?i)\[\s*deploy\s+only\s*:\s*([^\]]*)\]`)
+	pipelineDirective = regexp.MustCompile(`(?i)\[\s*deploy\s+pipeline\s*=\s*([^\]\s]+)\s*\]`)
+)
+
+// Directive is what a commit message asks of the deployment it triggers:
+//
+//	[skip deploy]               no pipeline is deployed
+//	[deploy only: api,worker]   only the servers with these labels are deployed
+//	[deploy pipeline=prod-eu]   only the matched pipeline with this name is deployed
+type Directive struct {
+	Skip     bool     `json:"skip"`
+	Only     []string `json:"only"`
+	Pipeline string   `json:"pipeline"`
+}
+
+// ParseDirective reads the deploy directives of a commit message. Directives
+// can be combined, [skip deploy] wins over the others.
+func ParseDirective(message string) Directive {
+	var directive Directive
+
+	directive.Skip = skipDirective.MatchString(message)
+
+	if match := onlyDirective.FindStringSubmatch(message); match != nil {
+		for _, label := range strings.Split(match[1], ",") {
+			if label = strings.TrimSpace(label); label != "" {
+				directive.Only = append(directive.Only, label)
+			}
+		}
+	}
+
+	if match := pipelineDirective.FindStringSubmatch(message); match != nil {
+		directive.Pipeline = match[1]
+	}
+
+	return directive
+}
+
+func (d Directive) IsEmpty() bool {
+	return !d.Skip && len(d.Only) == 0 && d.Pipeline == ""
+}
+
+// String is the canonical form stored with the update record.
+func (d Directive) String() string {
+	parts := make([]string, 0, 3)
+
+	if d.Skip {
+		parts = append(parts, "[skip deploy]")
+	}
+
+	if len(d.Only) > 0 {
+		parts = append(parts, fmt.Sprintf("[deploy only: %s]", strings.Join(d.Only, ",")))
+	}
+
+	if d.Pipeline != "" {
+		parts = append(parts, fmt.Sprintf("[deploy pipeline=%s]", d.Pipeline))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// SkipReason tells why the directive keeps a pipeline from deploying, or
+// returns an empty string when the pipeline should deploy.
+func (d Directive) SkipReason(pipelineName string) string {
+	if d.Skip {
+		return "skipped by [skip deploy] in the commit message"
+	}
+
+	if d.Pipeline != "" && !strings.EqualFold(d.Pipeline, pipelineName) {
+		return fmt.Sprintf("commit message only deploys pipeline %s", d.Pipeline)
+	}
+
+	return ""
+}
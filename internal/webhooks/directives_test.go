@@ -0,0 +1,29 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDirective(t *testing.T) {
+	directive := ParseDirective("Fix typo in docs [skip deploy]")
+	assert.True(t, directive.Skip)
+	assert.Equal(t, "[skip deploy]", directive.String())
+	assert.NotEmpty(t, directive.SkipReason("prod"))
+
+	directive = ParseDirective("Speed up jobs\n\n[Deploy only: api, worker ]")
+	assert.False(t, directive.Skip)
+	assert.Equal(t, []string{"api", "worker"}, directive.Only)
+	assert.Equal(t, "", directive.SkipReason("prod"))
+
+	directive = ParseDirective("Hotfix [deploy pipeline=prod-eu] [deploy only: api]")
+	assert.Equal(t, "prod-eu", directive.Pipeline)
+	assert.Equal(t, "[deploy only: api] [deploy pipeline=prod-eu]", directive.String())
+	assert.Equal(t, "", directive.SkipReason("Prod-EU"))
+	assert.Equal(t, "commit message only deploys pipeline prod-eu", directive.SkipReason("prod-us"))
+
+	directive = ParseDirective("Regular commit, skip deploy is not a directive")
+	assert.True(t, directive.IsEmpty())
+	assert.Equal(t, "", directive.String())
+}
@@ -0,0 +1,77 @@
+package webhooks
+
+const (
+	EventPush        = "push"
+	EventPullRequest = "pull_request"
+	EventTag         = "tag"
+	EventRelease     = "release"
+	// EventCI is a completed CI run. It never matches a trigger rule, it
+	// releases or cancels the updates waiting for CI on its commit.
+	EventCI = "ci"
+)
+
+// Event is the normalized form of an inbound webhook delivery that the
+// trigger rules are evaluated against. Files lists the paths changed by a
+// push and is nil when the payload does not carry them, in which case path
+// filters are not applied and the delivery records it, see PathsUnchecked.
+type Event struct {
+	Provider   string
+	Type       string
+	Repository string
+	Ref        string
+	Branch     string
+	Tag        string
+	SHA        string
+	Author     string
+	Message    string
+	Merged     bool
+	Files      []string
+	Conclusion string
+}
+
+// CIPassed reports whether a CI event concluded successfully.
+func (e Event) CIPassed() bool {
+	return e.Conclusion == "success"
+}
+
+// CIFailed reports whether a CI event concluded in a way that should cancel
+// the deploy. Neutral and skipped runs leave the update waiting.
+func (e Event) CIFailed() bool {
+	switch e.Conclusion {
+	case "failure", "cancelled", "timed_out", "action_required", "startup_failure":
+		return true
+	}
+
+	return false
+}
+
+// IsTag reports whether the event is about a tag rather than a branch.
+func (e Event) IsTag() bool {
+	return e.Type == EventTag || e.Type == EventRelease
+}
+
+// RefName is the branch the event happened on, or the tag name for tag and
+// release events.
+func (e Event) RefName() string {
+	if e.IsTag() {
+		return e.Tag
+	}
+
+	return e.Branch
+}
+
+// WaitsForCI reports whether rules for the event type can wait for CI. CI
+// runs are matched on the commit SHA, which tag and release events do not
+// carry.
+func WaitsForCI(eventType string) bool {
+	return eventType == EventPush || eventType == EventPullRequest
+}
+
+func IsValidEventType(eventType string) bool {
+	switch eventType {
+	case EventPush, EventPullRequest, EventTag, EventRelease:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type GiteaWebhook struct {
+	Ref         string           `json:"ref"`
+	After       string           `json:"after"`
+	Action      string           `json:"action"`
+	Pusher      GiteaUser        `json:"pusher"`
+	HeadCommit  GiteaCommit      `json:"head_commit"`
+	Commits     []GiteaCommit    `json:"commits"`
+	Repository  Repo             `json:"repository"`
+	PullRequest GiteaPullRequest `json:"pull_request"`
+}
+
+type GiteaUser struct {
+	Login    string `json:"login"`
+	Username string `json:"username"`
+}
+
+type GiteaCommit struct {
+	Id       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+type GiteaPullRequest struct {
+	Title          string    `json:"title"`
+	Merged         bool      `json:"merged"`
+	MergedBy       GiteaUser `json:"merged_by"`
+	MergeCommitSha string    `json:"merge_commit_sha"`
+	Base           Base      `json:"base"`
+}
+
+// GiteaProvider also serves Forgejo, which sends the same payloads and keeps
+// the X-Gitea-* headers next to its own X-Forgejo-* ones.
+type GiteaProvider struct{}
+
+func (GiteaProvider) Name() string {
+	return "gitea"
+}
+
+func (GiteaProvider) DeliveryID(header http.Header) string {
+	if delivery := header.Get("X-Gitea-Delivery"); delivery != "" {
+		return delivery
+	}
+
+	return header.Get("X-Forgejo-Delivery")
+}
+
+func (GiteaProvider) EventType(header http.Header) string {
+	if eventType := header.Get("X-Gitea-Event"); eventType != "" {
+		return eventType
+	}
+
+	return header.Get("X-Forgejo-Event")
+}
+
+func (GiteaProvider) Verify(header http.Header, body []byte, secret string) error {
+	signature := header.Get("X-Gitea-Signature")
+
+	if signature == "" {
+		signature = header.Get("X-Forgejo-Signature")
+	}
+
+	if !checkMAC(body, signature, secret) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Parse handles branch and tag pushes and merged pull requests.
+func (GiteaProvider) Parse(header http.Header, body []byte) (Event, bool, error) {
+	webhook := new(GiteaWebhook)
+
+	if err := json.Unmarshal(body, webhook); err != nil {
+		return Event{}, false, err
+	}
+
+	switch Gitea.EventType(header) {
+	case "push":
+		if webhook.After == nullSha {
+			return Event{}, false, nil
+		}
+
+		event := Event{
+			Provider:   "gitea",
+			Repository: webhook.Repository.FullName,
+			Ref:        webhook.Ref,
+			SHA:        webhook.After,
+			Author:     webhook.Pusher.Login,
+			Message:    webhook.HeadCommit.Message,
+		}
+
+		switch {
+		case strings.HasPrefix(webhook.Ref, "refs/heads/"):
+			event.Type = EventPush
+			event.Branch = strings.TrimPrefix(webhook.Ref, "refs/heads/")
+			event.Files = webhook.changedFiles()
+		case strings.HasPrefix(webhook.Ref, "refs/tags/"):
+			event.Type = EventTag
+			event.Tag = strings.TrimPrefix(webhook.Ref, "refs/tags/")
+		default:
+			return Event{}, false, nil
+		}
+
+		return event, true, nil
+
+	case "pull_request":
+		if webhook.Action != "closed" || !webhook.PullRequest.Merged {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "gitea",
+			Type:       EventPullRequest,
+			Repository: webhook.Repository.FullName,
+			Ref:        "refs/heads/" + webhook.PullRequest.Base.Ref,
+			Branch:     webhook.PullRequest.Base.Ref,
+			SHA:        webhook.PullRequest.MergeCommitSha,
+			Author:     webhook.PullRequest.MergedBy.Login,
+			Message:    webhook.PullRequest.Title,
+			Merged:     true,
+		}, true, nil
+	}
+
+	return Event{}, false, nil
+}
+
+func (w *GiteaWebhook) changedFiles() []string {
+	if len(w.Commits) == 0 {
+		return nil
+	}
+
+	var files changedFiles
+	for _, commit := range w.Commits {
+		files.add(commit.Added, commit.Modified, commit.Removed)
+	}
+
+	return files.files
+}
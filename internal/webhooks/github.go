@@ -0,0 +1,235 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// commitSHA matches a full git commit SHA.
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+type GithubWebhook struct {
+	Ref         string       `json:"ref"`
+	RefType     string       `json:"ref_type"`
+	Pusher      Pusher       `json:"pusher"`
+	Sender      Sender       `json:"sender"`
+	HeadCommit  HeadCommit   `json:"head_commit"`
+	Commits     []HeadCommit `json:"commits"`
+	Action      string       `json:"action"`
+	PullRequest PullRequest  `json:"pull_request"`
+	Release     Release      `json:"release"`
+	WorkflowRun CIRun        `json:"workflow_run"`
+	CheckSuite  CIRun        `json:"check_suite"`
+	Repository  Repo         `json:"repository"`
+}
+
+type Sender struct {
+	Login string `json:"login"`
+}
+
+type Release struct {
+	TagName         string `json:"tag_name"`
+	TargetCommitish string `json:"target_commitish"`
+	Name            string `json:"name"`
+	Prerelease      bool   `json:"prerelease"`
+	Author          Sender `json:"author"`
+}
+
+// CIRun holds the fields shared by workflow_run and check_suite payloads.
+type CIRun struct {
+	Name       string `json:"name"`
+	HeadSha    string `json:"head_sha"`
+	HeadBranch string `json:"head_branch"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+}
+
+type HeadCommit struct {
+	Id       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+type Pusher struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type MergedBy struct {
+	Login string `json:"login"`
+}
+
+type Repo struct {
+	FullName string `json:"full_name"`
+}
+
+type Head struct {
+	Ref  string `json:"ref"`
+	Repo Repo   `json:"repo"`
+}
+
+type Base struct {
+	Ref string `json:"ref"`
+}
+
+type PullRequest struct {
+	Merged         bool     `json:"merged"`
+	MergedAt       string   `json:"merged_at"`
+	MergedBy       MergedBy `json:"merged_by"`
+	MergeCommitSha string   `json:"merge_commit_sha"`
+	Title          string   `json:"title"`
+	Head           Head     `json:"head"`
+	Base           Base     `json:"base"`
+}
+
+type GithubProvider struct{}
+
+func (GithubProvider) Name() string {
+	return "github"
+}
+
+func (GithubProvider) DeliveryID(header http.Header) string {
+	return header.Get("X-GitHub-Delivery")
+}
+
+func (GithubProvider) EventType(header http.Header) string {
+	return header.Get("X-GitHub-Event")
+}
+
+func (GithubProvider) Verify(header http.Header, body []byte, secret string) error {
+	signature := strings.TrimPrefix(header.Get("X-Hub-Signature-256"), "sha256=")
+
+	if !checkMAC(body, signature, secret) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Parse handles branch pushes, merged pull requests, tag creation, published
+// releases and completed CI runs. Tag pushes are ignored in favor of the create event so
+// a new tag is only routed once.
+func (GithubProvider) Parse(header http.Header, body []byte) (Event, bool, error) {
+	webhook := new(GithubWebhook)
+
+	if err := json.Unmarshal(body, webhook); err != nil {
+		return Event{}, false, err
+	}
+
+	switch Github.EventType(header) {
+	case "push":
+		if !strings.HasPrefix(webhook.Ref, "refs/heads/") || webhook.HeadCommit.Id == "" {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "github",
+			Type:       EventPush,
+			Repository: webhook.Repository.FullName,
+			Ref:        webhook.Ref,
+			Branch:     strings.TrimPrefix(webhook.Ref, "refs/heads/"),
+			SHA:        webhook.HeadCommit.Id,
+			Author:     webhook.Pusher.Name,
+			Message:    webhook.HeadCommit.Message,
+			Files:      webhook.changedFiles(),
+		}, true, nil
+
+	case "pull_request":
+		if webhook.Action != "closed" || !webhook.PullRequest.Merged {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "github",
+			Type:       EventPullRequest,
+			Repository: webhook.Repository.FullName,
+			Ref:        "refs/heads/" + webhook.PullRequest.Base.Ref,
+			Branch:     webhook.PullRequest.Base.Ref,
+			SHA:        webhook.PullRequest.MergeCommitSha,
+			Author:     webhook.PullRequest.MergedBy.Login,
+			Message:    webhook.PullRequest.Title,
+			Merged:     true,
+		}, true, nil
+
+	case "create":
+		if webhook.RefType != "tag" {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "github",
+			Type:       EventTag,
+			Repository: webhook.Repository.FullName,
+			Ref:        "refs/tags/" + webhook.Ref,
+			Tag:        webhook.Ref,
+			Author:     webhook.Sender.Login,
+		}, true, nil
+
+	case "release":
+		if webhook.Action != "published" {
+			return Event{}, false, nil
+		}
+
+		event := Event{
+			Provider:   "github",
+			Type:       EventRelease,
+			Repository: webhook.Repository.FullName,
+			Ref:        "refs/tags/" + webhook.Release.TagName,
+			Tag:        webhook.Release.TagName,
+			Author:     webhook.Release.Author.Login,
+			Message:    webhook.Release.Name,
+		}
+
+		// target_commitish is the branch the tag was created from, or the
+		// commit itself.
+		if commitSHA.MatchString(webhook.Release.TargetCommitish) {
+			event.SHA = webhook.Release.TargetCommitish
+		} else {
+			event.Branch = webhook.Release.TargetCommitish
+		}
+
+		return event, true, nil
+
+	case "workflow_run", "check_suite":
+		run := webhook.WorkflowRun
+		if Github.EventType(header) == "check_suite" {
+			run = webhook.CheckSuite
+		}
+
+		if webhook.Action != "completed" || run.HeadSha == "" {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "github",
+			Type:       EventCI,
+			Repository: webhook.Repository.FullName,
+			Branch:     run.HeadBranch,
+			SHA:        run.HeadSha,
+			Author:     webhook.Sender.Login,
+			Message:    run.Name,
+			Conclusion: run.Conclusion,
+		}, true, nil
+	}
+
+	return Event{}, false, nil
+}
+
+// changedFiles lists the files touched by a push. GitHub only includes the
+// first 20 commits of a push, so very large pushes may miss some paths.
+func (w *GithubWebhook) changedFiles() []string {
+	if len(w.Commits) == 0 {
+		return nil
+	}
+
+	var files changedFiles
+	for _, commit := range w.Commits {
+		files.add(commit.Added, commit.Modified, commit.Removed)
+	}
+
+	return files.files
+}
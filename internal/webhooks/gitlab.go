@@ -0,0 +1,185 @@
+package webhooks
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	GitlabPushHook         = "Push Hook"
+	GitlabTagPushHook      = "Tag Push Hook"
+	GitlabMergeRequestHook = "Merge Request Hook"
+)
+
+type GitlabWebhook struct {
+	ObjectKind       string                 `json:"object_kind"`
+	Ref              string                 `json:"ref"`
+	After            string                 `json:"after"`
+	CheckoutSha      string                 `json:"checkout_sha"`
+	UserName         string                 `json:"user_name"`
+	UserUsername     string                 `json:"user_username"`
+	User             GitlabUser             `json:"user"`
+	Project          GitlabProject          `json:"project"`
+	Commits          []GitlabCommit         `json:"commits"`
+	ObjectAttributes GitlabObjectAttributes `json:"object_attributes"`
+}
+
+type GitlabUser struct {
+	Name     string `json:"name"`
+	Username string `json:"username"`
+}
+
+type GitlabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type GitlabCommit struct {
+	Id       string   `json:"id"`
+	Message  string   `json:"message"`
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+type GitlabObjectAttributes struct {
+	Action         string       `json:"action"`
+	State          string       `json:"state"`
+	Title          string       `json:"title"`
+	SourceBranch   string       `json:"source_branch"`
+	TargetBranch   string       `json:"target_branch"`
+	MergeCommitSha string       `json:"merge_commit_sha"`
+	LastCommit     GitlabCommit `json:"last_commit"`
+}
+
+type GitlabProvider struct{}
+
+func (GitlabProvider) Name() string {
+	return "gitlab"
+}
+
+func (GitlabProvider) DeliveryID(header http.Header) string {
+	return header.Get("X-Gitlab-Event-UUID")
+}
+
+func (GitlabProvider) EventType(header http.Header) string {
+	return header.Get("X-Gitlab-Event")
+}
+
+// Verify compares the X-Gitlab-Token header, GitLab does not sign the body.
+func (GitlabProvider) Verify(header http.Header, body []byte, secret string) error {
+	token := header.Get("X-Gitlab-Token")
+
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (GitlabProvider) Parse(header http.Header, body []byte) (Event, bool, error) {
+	return ParseGitlab(Gitlab.EventType(header), body)
+}
+
+// ParseGitlab normalizes a GitLab Push Hook, Tag Push Hook or merged Merge
+// Request Hook payload. The boolean is false for deliveries that should not
+// trigger anything, such as branch deletions or merge requests being opened.
+func ParseGitlab(eventType string, body []byte) (Event, bool, error) {
+	webhook := new(GitlabWebhook)
+
+	if err := json.Unmarshal(body, webhook); err != nil {
+		return Event{}, false, err
+	}
+
+	switch eventType {
+	case GitlabPushHook:
+		if !strings.HasPrefix(webhook.Ref, "refs/heads/") || webhook.After == nullSha {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "gitlab",
+			Type:       EventPush,
+			Repository: webhook.Project.PathWithNamespace,
+			Ref:        webhook.Ref,
+			Branch:     strings.TrimPrefix(webhook.Ref, "refs/heads/"),
+			SHA:        webhook.After,
+			Author:     webhook.UserUsername,
+			Message:    webhook.headCommit().Message,
+			Files:      webhook.changedFiles(),
+		}, true, nil
+
+	case GitlabTagPushHook:
+		if !strings.HasPrefix(webhook.Ref, "refs/tags/") || webhook.After == nullSha {
+			return Event{}, false, nil
+		}
+
+		return Event{
+			Provider:   "gitlab",
+			Type:       EventTag,
+			Repository: webhook.Project.PathWithNamespace,
+			Ref:        webhook.Ref,
+			Tag:        strings.TrimPrefix(webhook.Ref, "refs/tags/"),
+			SHA:        webhook.CheckoutSha,
+			Author:     webhook.UserUsername,
+		}, true, nil
+
+	case GitlabMergeRequestHook:
+		attributes := webhook.ObjectAttributes
+
+		if attributes.Action != "merge" {
+			return Event{}, false, nil
+		}
+
+		sha := attributes.MergeCommitSha
+		if sha == "" {
+			sha = attributes.LastCommit.Id
+		}
+
+		return Event{
+			Provider:   "gitlab",
+			Type:       EventPullRequest,
+			Repository: webhook.Project.PathWithNamespace,
+			Ref:        "refs/heads/" + attributes.TargetBranch,
+			Branch:     attributes.TargetBranch,
+			SHA:        sha,
+			Author:     webhook.User.Username,
+			Message:    attributes.Title,
+			Merged:     true,
+		}, true, nil
+	}
+
+	return Event{}, false, nil
+}
+
+// headCommit is the commit the push moved the branch to. GitLab lists the
+// commits oldest first, so the last one is used when no id matches.
+func (w *GitlabWebhook) headCommit() GitlabCommit {
+	for _, commit := range w.Commits {
+		if commit.Id == w.After {
+			return commit
+		}
+	}
+
+	if len(w.Commits) > 0 {
+		return w.Commits[len(w.Commits)-1]
+	}
+
+	return GitlabCommit{}
+}
+
+// changedFiles lists the files touched by a push, nil when the payload has no
+// commits to read them from.
+func (w *GitlabWebhook) changedFiles() []string {
+	if len(w.Commits) == 0 {
+		return nil
+	}
+
+	var files changedFiles
+	for _, commit := range w.Commits {
+		files.add(commit.Added, commit.Modified, commit.Removed)
+	}
+
+	return files.files
+}
@@ -0,0 +1,69 @@
+package webhooks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture %s: %v", name, err)
+	}
+
+	return body
+}
+
+func TestParseGitlabPush(t *testing.T) {
+	event, ok, err := ParseGitlab(GitlabPushHook, readFixture(t, "gitlab/push.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPush, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "release/2.3", event.Branch)
+	assert.Equal(t, "da1560886d4f094c3e6c9ef40349f7d38b5d27d7", event.SHA)
+	assert.Equal(t, "jsmith", event.Author)
+	assert.Equal(t, "fixed readme", event.Message)
+	assert.Equal(t, []string{"CHANGELOG", "app/controller/application.rb", "README.md"}, event.Files)
+}
+
+func TestParseGitlabPushBranchDeleted(t *testing.T) {
+	_, ok, err := ParseGitlab(GitlabPushHook, readFixture(t, "gitlab/push_branch_deleted.json"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestParseGitlabTagPush(t *testing.T) {
+	event, ok, err := ParseGitlab(GitlabTagPushHook, readFixture(t, "gitlab/tag_push.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventTag, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "v2.3.0", event.Tag)
+	assert.Equal(t, "v2.3.0", event.RefName())
+	assert.Equal(t, "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7", event.SHA)
+}
+
+func TestParseGitlabMergeRequest(t *testing.T) {
+	event, ok, err := ParseGitlab(GitlabMergeRequestHook, readFixture(t, "gitlab/merge_request_merged.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPullRequest, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "staging", event.Branch)
+	assert.Equal(t, "7e2a2f8b5a0d1c1b1d4b9d2e3f4a5b6c7d8e9f01", event.SHA)
+	assert.Equal(t, "root", event.Author)
+
+	_, ok, err = ParseGitlab(GitlabMergeRequestHook, readFixture(t, "gitlab/merge_request_opened.json"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestParseGitlabUnknownEvent(t *testing.T) {
+	_, ok, err := ParseGitlab("Note Hook", readFixture(t, "gitlab/push.json"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,58 @@
+package webhooks
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether name matches the glob pattern. Patterns are matched
+// segment by segment on "/", so "release/*" matches "release/1.0" but not
+// "release/1.0/hotfix". A "**" segment matches any number of segments and
+// an empty pattern matches everything.
+func Match(pattern string, name string) bool {
+	if pattern == "" {
+		return true
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(rest, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false
+		}
+
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+
+	return len(name) == 0
+}
+
+// ValidPattern reports whether every segment of the pattern is a well formed
+// glob, so bad rules are rejected when saved instead of never matching.
+func ValidPattern(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
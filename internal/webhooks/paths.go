@@ -0,0 +1,94 @@
+package webhooks
+
+import "fmt"
+
+// MatchPaths decides whether the changed files of a push are relevant to a
+// rule. Files matching an exclude glob are ignored first, then at least one
+// remaining file has to match an include glob, or any file when the rule has
+// no include globs. A push without changed files is not filtered, see
+// PathsUnchecked. It returns the reason when the push should be skipped.
+func MatchPaths(include []string, exclude []string, files []string) (bool, string) {
+	if len(files) == 0 || (len(include) == 0 && len(exclude) == 0) {
+		return true, ""
+	}
+
+	relevant := 0
+
+	for _, file := range files {
+		if matchAny(exclude, file) {
+			continue
+		}
+
+		relevant++
+
+		if len(include) == 0 || matchAny(include, file) {
+			return true, ""
+		}
+	}
+
+	if relevant == 0 {
+		return false, fmt.Sprintf("all %d changed file(s) match the exclude paths", len(files))
+	}
+
+	return false, fmt.Sprintf("none of the %d changed file(s) match the include paths", relevant)
+}
+
+// PathsUnchecked explains why the path filters of a rule could not be applied
+// to an event without changed files, either because its provider does not
+// list them, such as Bitbucket pushes, or because the push changed none. It
+// is empty when the rule has no path filters or they were applied.
+func PathsUnchecked(include []string, exclude []string, event Event) string {
+	if len(event.Files) > 0 || (len(include) == 0 && len(exclude) == 0) {
+		return ""
+	}
+
+	if event.Files == nil {
+		return fmt.Sprintf("path filters not checked, the %s %s event does not list changed files", event.Provider, event.Type)
+	}
+
+	return "path filters not checked, no changed files to check"
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidPaths reports whether every glob of a path filter is well formed.
+func ValidPaths(patterns []string) bool {
+	for _, pattern := range patterns {
+		if pattern == "" || !ValidPattern(pattern) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// changedFiles collects the files touched by the commits of a push, in order
+// and without duplicates.
+type changedFiles struct {
+	files []string
+	seen  map[string]bool
+}
+
+func (c *changedFiles) add(lists ...[]string) {
+	if c.seen == nil {
+		c.seen = make(map[string]bool)
+		c.files = make([]string, 0)
+	}
+
+	for _, list := range lists {
+		for _, file := range list {
+			if !c.seen[file] {
+				c.seen[file] = true
+				c.files = append(c.files, file)
+			}
+		}
+	}
+}
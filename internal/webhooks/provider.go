@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// nullSha is the "after" sha git hosts send when a ref is deleted.
+const nullSha = "0000000000000000000000000000000000000000"
+
+// Provider verifies and normalizes the webhook deliveries of one git host.
+type Provider interface {
+	Name() string
+	// DeliveryID is the id the git host gives the delivery, reused on retries.
+	DeliveryID(header http.Header) string
+	EventType(header http.Header) string
+	// Verify checks that the delivery was signed with secret.
+	Verify(header http.Header, body []byte, secret string) error
+	// Parse returns the normalized event and false when the delivery is
+	// valid but is not something that can trigger a pipeline.
+	Parse(header http.Header, body []byte) (Event, bool, error)
+}
+
+var (
+	Github    Provider = GithubProvider{}
+	Gitlab    Provider = GitlabProvider{}
+	Gitea     Provider = GiteaProvider{}
+	Bitbucket Provider = BitbucketProvider{}
+)
+
+var providers = map[string]Provider{
+	Github.Name():    Github,
+	Gitlab.Name():    Gitlab,
+	Gitea.Name():     Gitea,
+	"forgejo":        Gitea,
+	Bitbucket.Name(): Bitbucket,
+}
+
+func GetProvider(name string) (Provider, bool) {
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// checkMAC compares a hex encoded HMAC-SHA256 signature of message.
+func checkMAC(message []byte, messageMAC string, key string) bool {
+	if key == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(message)
+	expectedMAC := mac.Sum(nil)
+
+	return hmac.Equal([]byte(messageMAC), []byte(hex.EncodeToString(expectedMAC)))
+}
@@ -0,0 +1,206 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestGetProvider(t *testing.T) {
+	for _, name := range []string{"github", "gitlab", "gitea", "forgejo", "bitbucket"} {
+		_, ok := GetProvider(name)
+		assert.True(t, ok, name)
+	}
+
+	_, ok := GetProvider("svn")
+	assert.False(t, ok)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"ref":"refs/heads/dev"}`)
+	secret := "s3cr3t"
+
+	cases := []struct {
+		provider Provider
+		header   http.Header
+	}{
+		{Github, http.Header{"X-Hub-Signature-256": {"sha256=" + sign(body, secret)}}},
+		{Gitlab, http.Header{"X-Gitlab-Token": {secret}}},
+		{Gitea, http.Header{"X-Gitea-Signature": {sign(body, secret)}}},
+		{Gitea, http.Header{"X-Forgejo-Signature": {sign(body, secret)}}},
+		{Bitbucket, http.Header{"X-Hub-Signature": {"sha256=" + sign(body, secret)}}},
+	}
+
+	for _, c := range cases {
+		assert.NoError(t, c.provider.Verify(c.header, body, secret), c.provider.Name())
+		assert.ErrorIs(t, c.provider.Verify(c.header, body, "other"), ErrInvalidSignature, c.provider.Name())
+		assert.ErrorIs(t, c.provider.Verify(http.Header{}, body, ""), ErrInvalidSignature, c.provider.Name())
+	}
+}
+
+func TestGithubParse(t *testing.T) {
+	header := http.Header{"X-Github-Event": {"push"}}
+
+	event, ok, err := Github.Parse(header, readFixture(t, "github/push.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Event{
+		Provider:   "github",
+		Type:       EventPush,
+		Repository: "acme/web",
+		Ref:        "refs/heads/dev",
+		Branch:     "dev",
+		SHA:        "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+		Author:     "octocat",
+		Message:    "Update README.md",
+		Files:      []string{"README.md"},
+	}, event)
+
+	header = http.Header{"X-Github-Event": {"pull_request"}}
+
+	event, ok, err = Github.Parse(header, readFixture(t, "github/pull_request_merged.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPullRequest, event.Type)
+	assert.Equal(t, "staging", event.Branch)
+	assert.Equal(t, "c5b97d5ae6c19d5c5df71a34c7fbeeda2479ccbc", event.SHA)
+	assert.Equal(t, "hubot", event.Author)
+	assert.True(t, event.Merged)
+
+	header = http.Header{"X-Github-Event": {"create"}}
+
+	event, ok, err = Github.Parse(header, readFixture(t, "github/create_tag.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventTag, event.Type)
+	assert.Equal(t, "v2.4.0", event.Tag)
+	assert.Equal(t, "octocat", event.Author)
+
+	header = http.Header{"X-Github-Event": {"release"}}
+
+	event, ok, err = Github.Parse(header, readFixture(t, "github/release_published.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventRelease, event.Type)
+	assert.Equal(t, "v2.4.0", event.Tag)
+	assert.Equal(t, "v2.4.0", event.RefName())
+	assert.Equal(t, "master", event.Branch)
+	assert.Empty(t, event.SHA)
+
+	body := strings.Replace(string(readFixture(t, "github/release_published.json")), `"target_commitish": "master"`, `"target_commitish": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c"`, 1)
+
+	event, ok, err = Github.Parse(header, []byte(body))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, event.Branch)
+	assert.Equal(t, "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", event.SHA)
+}
+
+func TestWaitsForCI(t *testing.T) {
+	assert.True(t, WaitsForCI(EventPush))
+	assert.True(t, WaitsForCI(EventPullRequest))
+	assert.False(t, WaitsForCI(EventTag))
+	assert.False(t, WaitsForCI(EventRelease))
+}
+
+func TestGithubParseCI(t *testing.T) {
+	header := http.Header{"X-Github-Event": {"workflow_run"}}
+
+	event, ok, err := Github.Parse(header, readFixture(t, "github/workflow_run_completed.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventCI, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c", event.SHA)
+	assert.Equal(t, "Build", event.Message)
+	assert.True(t, event.CIPassed())
+	assert.False(t, event.CIFailed())
+
+	header = http.Header{"X-Github-Event": {"check_suite"}}
+
+	event, ok, err = Github.Parse(header, readFixture(t, "github/check_suite_completed.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "staging", event.Branch)
+	assert.Equal(t, "ec26c3e57ca3a959ca5aad62de7213c562f8c821", event.SHA)
+	assert.True(t, event.CIFailed())
+
+	assert.False(t, Event{Conclusion: "neutral"}.CIFailed())
+}
+
+func TestGiteaParse(t *testing.T) {
+	header := http.Header{"X-Forgejo-Event": {"push"}}
+
+	event, ok, err := Gitea.Parse(header, readFixture(t, "gitea/push.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPush, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "master", event.Branch)
+	assert.Equal(t, "bffeb74224043ba2feb48d137756c8a9331c449a", event.SHA)
+	assert.Equal(t, "gitea", event.Author)
+	assert.Equal(t, []string{"api/handler.go", "docs/index.md"}, event.Files)
+
+	header = http.Header{"X-Gitea-Event": {"pull_request"}}
+
+	event, ok, err = Gitea.Parse(header, readFixture(t, "gitea/pull_request_merged.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPullRequest, event.Type)
+	assert.Equal(t, "staging", event.Branch)
+	assert.Equal(t, "2b5b3e5a1b1b4b6f4d0e1f9b6c8a2f3e4d5c6b7a", event.SHA)
+	assert.True(t, event.Merged)
+}
+
+func TestBitbucketParse(t *testing.T) {
+	header := http.Header{"X-Event-Key": {"repo:push"}}
+
+	event, ok, err := Bitbucket.Parse(header, readFixture(t, "bitbucket/push.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPush, event.Type)
+	assert.Equal(t, "acme/web", event.Repository)
+	assert.Equal(t, "release/3.0", event.Branch)
+	assert.Equal(t, "709d658dc5b6d6afcd46049c2f332ee3f515a67d", event.SHA)
+	assert.Equal(t, "emma", event.Author)
+
+	header = http.Header{"X-Event-Key": {"pullrequest:fulfilled"}}
+
+	event, ok, err = Bitbucket.Parse(header, readFixture(t, "bitbucket/pullrequest_fulfilled.json"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, EventPullRequest, event.Type)
+	assert.Equal(t, "master", event.Branch)
+	assert.Equal(t, "f3a1c0de9b7e", event.SHA)
+	assert.True(t, event.Merged)
+
+	header = http.Header{"X-Event-Key": {"pullrequest:created"}}
+
+	_, ok, err = Bitbucket.Parse(header, readFixture(t, "bitbucket/pullrequest_fulfilled.json"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerifyAny(t *testing.T) {
+	body := readFixture(t, "github/push.json")
+	header := http.Header{"X-Hub-Signature-256": {"sha256=" + sign(body, "old-secret")}}
+
+	assert.NoError(t, VerifyAny(Github, header, body, "new-secret", "old-secret"))
+	assert.ErrorIs(t, VerifyAny(Github, header, body, "new-secret"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyAny(Github, header, body), ErrInvalidSignature)
+
+	secret, err := GenerateSecret(32)
+	assert.NoError(t, err)
+	assert.Len(t, secret, 64)
+}
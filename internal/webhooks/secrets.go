@@ -0,0 +1,31 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// VerifyAny accepts a delivery signed with any of the secrets, which lets a
+// rotated secret and its predecessor both be active for a while.
+func VerifyAny(provider Provider, header http.Header, body []byte, secrets ...string) error {
+	for _, secret := range secrets {
+		if provider.Verify(header, body, secret) == nil {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+// GenerateSecret returns a random hex string of n bytes, used for webhook
+// URL tokens and signing secrets.
+func GenerateSecret(n int) (string, error) {
+	buf := make([]byte, n)
+
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
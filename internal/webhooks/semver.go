@@ -0,0 +1,62 @@
+package webhooks
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var semverRegexp = regexp.MustCompile(`^v?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+var tagPatternRegexp = regexp.MustCompile(`^v?(\d+|[*x])(?:\.(\d+|[*x]))?(?:\.(\d+|[*x]))?(?:-([0-9A-Za-z.*?-]+))?$`)
+
+// IsSemver reports whether tag is a semantic version, with or without a
+// leading "v".
+func IsSemver(tag string) bool {
+	return semverRegexp.MatchString(tag)
+}
+
+// ValidTagPattern reports whether pattern can be used by MatchTagPattern.
+func ValidTagPattern(pattern string) bool {
+	return pattern == "" || tagPatternRegexp.MatchString(pattern)
+}
+
+// MatchTagPattern reports whether tag is a semantic version selected by
+// pattern. Patterns look like versions where any component may be "*" or "x"
+// and missing components match anything, so "v1.x" matches "v1.4.2" and "2"
+// matches "v2.0.1". Pre-releases only match when the pattern has a
+// pre-release part, e.g. "v2.x-rc*". An empty pattern matches every release.
+func MatchTagPattern(pattern string, tag string) bool {
+	version := semverRegexp.FindStringSubmatch(tag)
+
+	if version == nil {
+		return false
+	}
+
+	if pattern == "" || pattern == "*" {
+		return version[4] == ""
+	}
+
+	wanted := tagPatternRegexp.FindStringSubmatch(pattern)
+
+	if wanted == nil {
+		return false
+	}
+
+	for i := 1; i <= 3; i++ {
+		if wanted[i] == "" || wanted[i] == "*" || wanted[i] == "x" {
+			continue
+		}
+
+		if strings.TrimLeft(wanted[i], "0") != strings.TrimLeft(version[i], "0") {
+			return false
+		}
+	}
+
+	if wanted[4] == "" {
+		return version[4] == ""
+	}
+
+	ok, err := path.Match(wanted[4], version[4])
+	return err == nil && ok
+}
@@ -0,0 +1,50 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSemver(t *testing.T) {
+	assert.True(t, IsSemver("v1.2.3"))
+	assert.True(t, IsSemver("1.2.3-rc.1"))
+	assert.True(t, IsSemver("v10.0.0+build.5"))
+	assert.False(t, IsSemver("v1.2"))
+	assert.False(t, IsSemver("release-2024"))
+}
+
+func TestMatchTagPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		tag     string
+		match   bool
+	}{
+		{"", "v1.2.3", true},
+		{"*", "v1.2.3", true},
+		{"", "v1.2.3-rc.1", false},
+		{"", "nightly", false},
+		{"v1.x", "v1.4.2", true},
+		{"v1.x", "v2.0.0", false},
+		{"1.*", "v1.0.9", true},
+		{"2", "v2.0.1", true},
+		{"v2.3.x", "2.3.7", true},
+		{"v2.3.x", "2.4.0", false},
+		{"v2.3.1", "v2.3.1", true},
+		{"v2.x-rc*", "v2.1.0-rc.2", true},
+		{"v2.x-rc*", "v2.1.0", false},
+		{"v2.x-*", "v2.1.0-beta", true},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.match, MatchTagPattern(c.pattern, c.tag), "%s ~ %s", c.pattern, c.tag)
+	}
+}
+
+func TestValidTagPattern(t *testing.T) {
+	assert.True(t, ValidTagPattern(""))
+	assert.True(t, ValidTagPattern("v1.x"))
+	assert.True(t, ValidTagPattern("v2.x-rc*"))
+	assert.False(t, ValidTagPattern("release/*"))
+	assert.False(t, ValidTagPattern("v1..2"))
+}
@@ -0,0 +1,57 @@
+package webhooks
+
+import "auto-update/internal/database/models"
+
+// SkippedTrigger is a rule that matched the event's ref but was not fired,
+// with the reason recorded in the delivery log.
+type SkippedTrigger struct {
+	Trigger models.WebhookTrigger
+	Reason  string
+}
+
+// MatchTriggers returns the active rules that fire for the event, keeping at
+// most one rule per pipeline so a pipeline is never enqueued twice for the
+// same delivery. Tag and release events are filtered by the rule's semver
+// tag pattern instead of the branch pattern. Rules whose path filters reject
+// the changed files are returned as skipped, unless another rule fired the
+// same pipeline.
+func MatchTriggers(triggers []models.WebhookTrigger, event Event) ([]models.WebhookTrigger, []SkippedTrigger) {
+	matched := make([]models.WebhookTrigger, 0)
+	seen := make(map[int64]bool)
+	skipped := make([]SkippedTrigger, 0)
+
+	for _, trigger := range triggers {
+		if !trigger.Active || seen[trigger.PipelineID] {
+			continue
+		}
+
+		if trigger.Repository != event.Repository || trigger.EventType != event.Type {
+			continue
+		}
+
+		if event.IsTag() {
+			if !MatchTagPattern(trigger.TagPattern, event.Tag) {
+				continue
+			}
+		} else if !Match(trigger.BranchPattern, event.Branch) {
+			continue
+		}
+
+		if ok, reason := MatchPaths(trigger.IncludePaths, trigger.ExcludePaths, event.Files); !ok {
+			skipped = append(skipped, SkippedTrigger{Trigger: trigger, Reason: reason})
+			continue
+		}
+
+		seen[trigger.PipelineID] = true
+		matched = append(matched, trigger)
+	}
+
+	notFired := make([]SkippedTrigger, 0, len(skipped))
+	for _, skip := range skipped {
+		if !seen[skip.Trigger.PipelineID] {
+			notFired = append(notFired, skip)
+		}
+	}
+
+	return matched, notFired
+}
@@ -0,0 +1,130 @@
+package webhooks
+
+import (
+	"auto-update/internal/database/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("dev", "dev"))
+	assert.True(t, Match("release/*", "release/1.2"))
+	assert.False(t, Match("release/*", "release/1.2/hotfix"))
+	assert.True(t, Match("release/**", "release/1.2/hotfix"))
+	assert.True(t, Match("**/*.md", "docs/guide/intro.md"))
+	assert.True(t, Match("**/*.md", "README.md"))
+	assert.True(t, Match("", "anything"))
+	assert.False(t, Match("staging", "dev"))
+}
+
+func TestMatchTriggers(t *testing.T) {
+	triggers := []models.WebhookTrigger{
+		{ID: 1, PipelineID: 10, Repository: "acme/web", BranchPattern: "dev", EventType: EventPush, Active: true},
+		{ID: 2, PipelineID: 20, Repository: "acme/web", BranchPattern: "release/*", EventType: EventPush, Active: true},
+		{ID: 3, PipelineID: 20, Repository: "acme/web", BranchPattern: "release/**", EventType: EventPush, Active: true},
+		{ID: 4, PipelineID: 30, Repository: "acme/web", BranchPattern: "release/*", EventType: EventPullRequest, Active: true},
+		{ID: 5, PipelineID: 40, Repository: "acme/api", BranchPattern: "release/*", EventType: EventPush, Active: true},
+		{ID: 6, PipelineID: 50, Repository: "acme/web", BranchPattern: "release/*", EventType: EventPush, Active: false},
+	}
+
+	event := Event{Type: EventPush, Repository: "acme/web", Branch: "release/1.4"}
+
+	matched, _ := MatchTriggers(triggers, event)
+
+	assert.Len(t, matched, 1)
+	assert.Equal(t, int64(2), matched[0].ID)
+
+	event = Event{Type: EventPullRequest, Repository: "acme/web", Branch: "release/1.4"}
+
+	matched, _ = MatchTriggers(triggers, event)
+
+	assert.Len(t, matched, 1)
+	assert.Equal(t, int64(30), matched[0].PipelineID)
+
+	event = Event{Type: EventPush, Repository: "acme/web", Branch: "master"}
+
+	matched, _ = MatchTriggers(triggers, event)
+
+	assert.Empty(t, matched)
+}
+
+func TestMatchTriggersTags(t *testing.T) {
+	triggers := []models.WebhookTrigger{
+		{ID: 1, PipelineID: 10, Repository: "acme/web", EventType: EventRelease, TagPattern: "v2.x", Active: true},
+		{ID: 2, PipelineID: 20, Repository: "acme/web", EventType: EventTag, TagPattern: "v2.x-rc*", Active: true},
+	}
+
+	matched, _ := MatchTriggers(triggers, Event{Type: EventRelease, Repository: "acme/web", Branch: "master", Tag: "v2.4.0"})
+
+	assert.Len(t, matched, 1)
+	assert.Equal(t, int64(10), matched[0].PipelineID)
+
+	matched, _ = MatchTriggers(triggers, Event{Type: EventRelease, Repository: "acme/web", Tag: "v3.0.0"})
+	assert.Empty(t, matched)
+
+	matched, _ = MatchTriggers(triggers, Event{Type: EventTag, Repository: "acme/web", Tag: "v2.4.0"})
+	assert.Empty(t, matched)
+
+	matched, _ = MatchTriggers(triggers, Event{Type: EventTag, Repository: "acme/web", Tag: "v2.5.0-rc.1"})
+	assert.Len(t, matched, 1)
+}
+
+func TestMatchPaths(t *testing.T) {
+	ok, _ := MatchPaths(nil, nil, []string{"README.md"})
+	assert.True(t, ok)
+
+	ok, _ = MatchPaths([]string{"api/**"}, nil, nil)
+	assert.True(t, ok)
+
+	ok, _ = MatchPaths(nil, []string{"docs/**"}, []string{})
+	assert.True(t, ok)
+
+	ok, reason := MatchPaths(nil, []string{"**/*.md", "docs/**"}, []string{"README.md", "docs/guide/intro.html"})
+	assert.False(t, ok)
+	assert.Equal(t, "all 2 changed file(s) match the exclude paths", reason)
+
+	ok, _ = MatchPaths(nil, []string{"**/*.md"}, []string{"README.md", "api/main.go"})
+	assert.True(t, ok)
+
+	ok, reason = MatchPaths([]string{"api/**"}, []string{"**/*.md"}, []string{"api/README.md", "worker/main.go"})
+	assert.False(t, ok)
+	assert.Equal(t, "none of the 1 changed file(s) match the include paths", reason)
+
+	ok, _ = MatchPaths([]string{"api/**"}, nil, []string{"worker/main.go", "api/handler/user.go"})
+	assert.True(t, ok)
+}
+
+func TestMatchTriggersPaths(t *testing.T) {
+	triggers := []models.WebhookTrigger{
+		{ID: 1, PipelineID: 10, Repository: "acme/mono", BranchPattern: "dev", EventType: EventPush, ExcludePaths: []string{"docs/**", "**/*.md"}, Active: true},
+		{ID: 2, PipelineID: 20, Repository: "acme/mono", BranchPattern: "dev", EventType: EventPush, IncludePaths: []string{"worker/**"}, Active: true},
+		{ID: 3, PipelineID: 20, Repository: "acme/mono", BranchPattern: "dev", EventType: EventPush, IncludePaths: []string{"shared/**"}, Active: true},
+	}
+
+	matched, skipped := MatchTriggers(triggers, Event{Type: EventPush, Repository: "acme/mono", Branch: "dev", Files: []string{"docs/index.md"}})
+
+	assert.Empty(t, matched)
+	assert.Len(t, skipped, 3)
+	assert.Equal(t, int64(1), skipped[0].Trigger.ID)
+
+	matched, skipped = MatchTriggers(triggers, Event{Type: EventPush, Repository: "acme/mono", Branch: "dev", Files: []string{"shared/config.go"}})
+
+	assert.Len(t, matched, 2)
+	assert.Equal(t, int64(3), matched[1].ID)
+	assert.Empty(t, skipped)
+}
+
+func TestPathsUnchecked(t *testing.T) {
+	bitbucket := Event{Provider: "bitbucket", Type: EventPush}
+
+	assert.Equal(t, "path filters not checked, the bitbucket push event does not list changed files", PathsUnchecked([]string{"api/**"}, nil, bitbucket))
+	assert.Equal(t, "path filters not checked, the bitbucket push event does not list changed files", PathsUnchecked(nil, []string{"docs/**"}, bitbucket))
+	assert.Empty(t, PathsUnchecked(nil, nil, bitbucket))
+
+	bitbucket.Files = []string{"api/main.go"}
+	assert.Empty(t, PathsUnchecked([]string{"api/**"}, nil, bitbucket))
+
+	empty := Event{Provider: "github", Type: EventPush, Files: []string{}}
+	assert.Equal(t, "path filters not checked, no changed files to check", PathsUnchecked(nil, []string{"docs/**"}, empty))
+}